@@ -0,0 +1,5 @@
+// Package userv1 holds the protobuf definition of the gRPC user API and the
+// code generated from it.
+package userv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative user.proto
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"user-profile-api/config"
+)
+
+// runConfigCommand implements the "config" subcommand
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: server config print [-config path]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML configuration file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	out, err := cfg.YAML()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to render configuration: %v\n", err)
+		return 1
+	}
+
+	os.Stdout.Write(out)
+	return 0
+}
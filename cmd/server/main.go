@@ -2,34 +2,62 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"user-profile-api/config"
+	"user-profile-api/internal/birthday"
+	"user-profile-api/internal/database"
+	"user-profile-api/internal/eventstream"
+	"user-profile-api/internal/graphqlapi"
+	"user-profile-api/internal/grpcserver"
 	"user-profile-api/internal/handler"
+	"user-profile-api/internal/health"
 	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/outbox"
 	"user-profile-api/internal/repository"
 	"user-profile-api/internal/routes"
 	"user-profile-api/internal/service"
+	"user-profile-api/internal/tracing"
+	"user-profile-api/internal/webhook"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to the YAML configuration file")
+	flag.Parse()
+
 	// Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		fmt.Printf("Failed to load configuration: %v\n", err)
 		os.Exit(1)
 	}
 
 	// Initialize logger
-	log, err := logger.New(cfg.LogLevel)
+	log, logLevel, err := logger.New(logger.Config{
+		Level:              cfg.Log.Level,
+		Encoding:           cfg.Log.Encoding,
+		SamplingInitial:    cfg.Log.SamplingInitial,
+		SamplingThereafter: cfg.Log.SamplingThereafter,
+		FilePath:           cfg.Log.File.Path,
+		FileMaxSizeMB:      cfg.Log.File.MaxSizeMB,
+		FileMaxAgeDays:     cfg.Log.File.MaxAgeDays,
+		FileMaxBackups:     cfg.Log.File.MaxBackups,
+	})
 	if err != nil {
 		fmt.Printf("Failed to initialize logger: %v\n", err)
 		os.Exit(1)
@@ -37,49 +65,222 @@ func main() {
 	defer log.Sync()
 
 	log.Info("starting user profile API",
-		zap.String("port", cfg.Port),
-		zap.String("log_level", cfg.LogLevel),
+		zap.String("port", cfg.Server.Port),
+		zap.String("environment", cfg.Server.Environment),
+		zap.String("log_level", cfg.Log.Level),
+		zap.String("tracing_exporter", cfg.Tracing.Exporter),
 	)
 
-	// Connect to database
-	poolConfig, err := pgxpool.ParseConfig(cfg.DatabaseURL)
+	// Initialize tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:  cfg.Server.ServiceName,
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
 	if err != nil {
-		log.Fatal("failed to parse database URL", zap.Error(err))
+		log.Fatal("failed to initialize tracing", zap.Error(err))
 	}
 
-	dbPool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	// Connect to database, stopping the retry loop if we are asked to exit
+	startupCtx, stopStartup := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	dbPool, err := database.Connect(startupCtx, cfg.Database, cfg.Server.ServiceName, log)
+	stopStartup()
 	if err != nil {
 		log.Fatal("failed to connect to database", zap.Error(err))
 	}
 	defer dbPool.Close()
+	log.Info("successfully connected to database")
 
-	// Verify database connection
-	if err := dbPool.Ping(context.Background()); err != nil {
-		log.Fatal("failed to ping database", zap.Error(err))
+	// Open read replicas and start monitoring their lag
+	replicaPools, err := database.OpenReplicas(context.Background(), cfg.Database, cfg.Server.ServiceName)
+	if err != nil {
+		log.Fatal("failed to open read replicas", zap.Error(err))
+	}
+	replicas := repository.NewReplicaSet(replicaPools, cfg.Database.ReplicaMaxLag, cfg.Database.ReplicaCheckPeriod, log)
+	defer replicas.Close()
+
+	// Background workers run until shutdown
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	replicas.Start(bgCtx)
+	if len(replicaPools) > 0 {
+		log.Info("read replicas configured", zap.Int("count", len(replicaPools)))
 	}
-	log.Info("successfully connected to database")
 
 	// Initialize layers
-	repo := repository.NewPostgresRepository(dbPool, log)
-	userService := service.NewUserService(repo, log)
+	configStore := config.NewStore(*configPath, cfg, log)
+	var repo repository.Repository = repository.NewPostgresRepository(dbPool, log,
+		repository.WithQueryComments(cfg.Database.QueryComments),
+		repository.WithQueryTimeout(cfg.Database.QueryTimeout),
+		repository.WithReplicas(replicas),
+	)
+	if cfg.Cache.Enabled {
+		cached := repository.NewCachedRepository(repo, repository.CacheOptions{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+		}, log)
+		if cfg.Cache.Broadcast {
+			cached.EnableBroadcast(bgCtx, dbPool)
+		}
+		repo = cached
+	}
+	userService := service.NewUserService(repo, log,
+		service.WithPageSizes(cfg.Limits.DefaultPageSize, cfg.Limits.MaxPageSize),
+		service.WithEvents(cfg.Outbox.Enabled),
+		service.WithLeapDayPolicy(models.LeapDayPolicy(cfg.Birthdays.LeapDay)),
+		service.WithCalendarLimit(cfg.Birthdays.CalendarLimit),
+	)
+
+	// Relay recorded events to the configured sinks and webhooks, and stream
+	// them to /users/events
+	var webhookHandler *handler.WebhookHandler
+	var eventHandler *handler.EventHandler
+	var broker *eventstream.Broker
+	if cfg.Outbox.Enabled {
+		sink, err := outbox.NewSink(cfg.Outbox)
+		if err != nil {
+			log.Fatal("failed to initialize outbox sinks", zap.Error(err))
+		}
+		if cfg.Webhooks.Enabled {
+			dispatcher := webhook.NewDispatcher(dbPool, webhook.DispatcherOptions{
+				BatchSize:    cfg.Webhooks.BatchSize,
+				PollInterval: cfg.Webhooks.PollInterval,
+				Timeout:      cfg.Webhooks.Timeout,
+				MaxAttempts:  cfg.Webhooks.MaxAttempts,
+				DisableAfter: cfg.Webhooks.DisableAfter,
+				Retention:    cfg.Webhooks.Retention,
+			}, log)
+			go dispatcher.Run(bgCtx)
+			sink = outbox.Fanout(sink, dispatcher)
+			webhookHandler = handler.NewWebhookHandler(webhook.NewService(dbPool, log), log)
+		}
+		relay := outbox.NewRelay(dbPool, sink, outbox.Options{
+			BatchSize:    cfg.Outbox.BatchSize,
+			PollInterval: cfg.Outbox.PollInterval,
+			Retention:    cfg.Outbox.Retention,
+		}, log)
+		go relay.Run(bgCtx)
+
+		broker = eventstream.NewBroker(cfg.Stream.BufferSize, log)
+		broker.Start(bgCtx, dbPool)
+		eventHandler = handler.NewEventHandler(broker, cfg.Stream.Heartbeat, log)
+		log.Info("outbox relay started", zap.Strings("sinks", cfg.Outbox.Sinks))
+	}
+
+	// Announce birthdays from a single instance
+	if s := cfg.Birthdays.Scheduler; s.Enabled {
+		notifiers, err := birthday.NewNotifiers(s, log)
+		if err != nil {
+			log.Fatal("failed to initialize birthday notifiers", zap.Error(err))
+		}
+		sendAt, _ := time.Parse("15:04", s.SendAt)
+		location, _ := time.LoadLocation(s.Timezone)
+		scheduler := birthday.NewScheduler(dbPool, notifiers, birthday.Options{
+			Interval: s.Interval,
+			SendAt:   time.Duration(sendAt.Hour())*time.Hour + time.Duration(sendAt.Minute())*time.Minute,
+			Location: location,
+			LeapDay:  models.LeapDayPolicy(cfg.Birthdays.LeapDay),
+			Clock:    service.SystemClock,
+		}, log)
+		go scheduler.Run(bgCtx)
+		log.Info("birthday scheduler started", zap.Strings("notifiers", s.Notifiers), zap.String("send_at", s.SendAt))
+	}
 	userHandler := handler.NewUserHandler(userService, log)
-	healthHandler := handler.NewHealthHandler()
+	var graphqlHandler *handler.GraphQLHandler
+	if cfg.GraphQL.Enabled {
+		executor, err := graphqlapi.New(userService, graphqlapi.Options{
+			MaxDepth:        cfg.GraphQL.MaxDepth,
+			MaxComplexity:   cfg.GraphQL.MaxComplexity,
+			DefaultPageSize: cfg.Limits.DefaultPageSize,
+		}, log)
+		if err != nil {
+			log.Fatal("failed to initialize GraphQL", zap.Error(err))
+		}
+		var pinWindow time.Duration
+		if len(cfg.Database.ReplicaURLs) > 0 {
+			pinWindow = cfg.Database.PrimaryPinWindow
+		}
+		graphqlHandler = handler.NewGraphQLHandler(executor, cfg.Server.Environment == config.EnvironmentDevelopment, pinWindow, log)
+	}
+	checker := health.NewChecker(cfg.Health.CheckTimeout, cfg.Health.CacheTTL)
+	checker.Register("postgres", dbPool.Ping)
+	healthHandler := handler.NewHealthHandler(checker)
+	adminHandler := handler.NewAdminHandler(logLevel, configStore, log)
+
+	// Apply hot-reloadable settings to running components
+	configStore.Subscribe(func(old, new *config.Config) {
+		if old.Log.Level != new.Log.Level {
+			level, _ := zapcore.ParseLevel(new.Log.Level)
+			logLevel.SetLevel(level)
+			log.Info("log level reloaded", zap.Stringer("log_level", level))
+		}
+		userService.SetPageSizes(new.Limits.DefaultPageSize, new.Limits.MaxPageSize)
+	})
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:      "User Profile API",
 		ErrorHandler: nil,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+		BodyLimit:    cfg.Limits.BodyLimitBytes,
 	})
 
-	routes.Setup(app, userHandler, healthHandler, log)
+	routes.Setup(app, configStore, userHandler, healthHandler, adminHandler, webhookHandler, eventHandler, graphqlHandler, log)
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	// Reload configuration on SIGHUP and whenever the config file changes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			_ = configStore.Reload("SIGHUP")
+		}
+	}()
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if err := configStore.Watch(watchCtx); err != nil {
+		log.Warn("config file watching disabled", zap.Error(err))
+	}
+
+	// Serve the gRPC API on its own port
+	var grpcServer *grpcserver.Server
+	if cfg.GRPC.Enabled {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPC.Port))
+		if err != nil {
+			log.Fatal("failed to listen for gRPC", zap.Error(err))
+		}
+		grpcServer = grpcserver.New(userService, grpcserver.Options{
+			AuthToken:  cfg.GRPC.AuthToken,
+			Reflection: cfg.GRPC.Reflection,
+		}, log)
+		go func() {
+			log.Info("gRPC server starting", zap.String("address", lis.Addr().String()))
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatal("failed to serve gRPC", zap.Error(err))
+			}
+		}()
+	}
+
+	checker.MarkStarted()
+
 	go func() {
-		addr := fmt.Sprintf(":%s", cfg.Port)
-		log.Info("server starting", zap.String("address", addr))
-		if err := app.Listen(addr); err != nil {
+		addr := fmt.Sprintf(":%s", cfg.Server.Port)
+		log.Info("server starting", zap.String("address", addr), zap.Bool("tls", cfg.Server.TLS.Enabled))
+
+		listen := func() error { return app.Listen(addr) }
+		if cfg.Server.TLS.Enabled {
+			listen = func() error { return app.ListenTLS(addr, cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile) }
+		}
+		if err := listen(); err != nil {
 			log.Fatal("failed to start server", zap.Error(err))
 		}
 	}()
@@ -87,12 +288,32 @@ func main() {
 	<-quit
 	log.Info("shutting down server gracefully...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Fail readiness first so load balancers stop routing new traffic
+	checker.MarkShuttingDown()
+	if grpcServer != nil {
+		grpcServer.SetServing(false)
+	}
+	log.Info("draining traffic", zap.Duration("delay", cfg.Server.ShutdownDrainDelay))
+	time.Sleep(cfg.Server.ShutdownDrainDelay)
+
+	// Event streams never finish on their own
+	if broker != nil {
+		broker.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := app.ShutdownWithContext(ctx); err != nil {
 		log.Error("server shutdown error", zap.Error(err))
 	}
+	if grpcServer != nil {
+		grpcServer.Shutdown(ctx)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error("tracing shutdown error", zap.Error(err))
+	}
 
 	log.Info("server stopped")
 }
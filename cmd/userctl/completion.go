@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func completionCommand() command {
+	return command{
+		name:    "completion",
+		args:    "bash | zsh",
+		summary: "Print a shell completion script",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) != 1 {
+					return usagef("expected a shell")
+				}
+
+				switch args[0] {
+				case "bash":
+				case "zsh":
+					fmt.Fprintln(c.stdout, "autoload -U +X bashcompinit && bashcompinit")
+				default:
+					return usagef("unsupported shell %q; use bash or zsh", args[0])
+				}
+				fmt.Fprint(c.stdout, bashCompletion())
+				return nil
+			}
+		},
+	}
+}
+
+// bashCompletion returns a bash completion script listing the commands and
+// the flags of each
+func bashCompletion() string {
+	var names []string
+	var cases strings.Builder
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+
+		fs := (&cli{}).flagSet(cmd.name)
+		cmd.setup(fs)
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		sort.Strings(flags)
+		fmt.Fprintf(&cases, "    %s) flags=%q ;;\n", cmd.name, strings.Join(flags, " "))
+	}
+
+	return fmt.Sprintf(`_userctl() {
+  local cur prev flags
+  cur="${COMP_WORDS[COMP_CWORD]}"
+  prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+  if [ "$COMP_CWORD" -eq 1 ]; then
+    COMPREPLY=($(compgen -W %q -- "$cur"))
+    return
+  fi
+
+  case "$prev" in
+    -o) COMPREPLY=($(compgen -W "table json yaml" -- "$cur")); return ;;
+    -format) COMPREPLY=($(compgen -W "json csv" -- "$cur")); return ;;
+    -profile) COMPREPLY=($(compgen -W "$(userctl profile list -names 2>/dev/null)" -- "$cur")); return ;;
+    -config|-file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+  esac
+
+  case "${COMP_WORDS[1]}" in
+%s  esac
+
+  if [[ "$cur" == -* ]]; then
+    COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+  elif [ "${COMP_WORDS[1]}" = profile ] && [ "$COMP_CWORD" -eq 2 ]; then
+    COMPREPLY=($(compgen -W "list use set delete" -- "$cur"))
+  elif [ "${COMP_WORDS[1]}" = profile ] && [ "$COMP_CWORD" -eq 3 ]; then
+    COMPREPLY=($(compgen -W "$(userctl profile list -names 2>/dev/null)" -- "$cur"))
+  elif [ "${COMP_WORDS[1]}" = completion ]; then
+    COMPREPLY=($(compgen -W "bash zsh" -- "$cur"))
+  elif [ "${COMP_WORDS[1]}" = import ]; then
+    COMPREPLY=($(compgen -f -- "$cur"))
+  fi
+}
+complete -F _userctl userctl
+`, strings.Join(names, " "), cases.String())
+}
@@ -0,0 +1,224 @@
+// Command userctl manages users through the User Profile API.
+//
+//	userctl -profile staging list -all
+//	userctl get 42 -o yaml
+//	userctl export -file users.csv
+//
+// Run "userctl help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"user-profile-api/pkg/client"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is a userctl subcommand. setup registers the command's flags and
+// returns the function that runs it with the remaining arguments.
+type command struct {
+	name    string
+	args    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(ctx context.Context, c *cli, args []string) error
+}
+
+// commands lists the subcommands in the order shown by help
+func commands() []command {
+	return []command{
+		getCommand(),
+		listCommand(),
+		searchCommand(),
+		createCommand(),
+		updateCommand(),
+		deleteCommand(),
+		importCommand(),
+		exportCommand(),
+		profileCommand(),
+		completionCommand(),
+	}
+}
+
+// usageError reports a malformed command line; userctl exits with 2
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// cli holds the global flags and I/O shared by every command
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	profile    string
+	server     string
+	token      string
+	output     string
+	timeout    time.Duration
+}
+
+// run executes the command line and returns the exit code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		c.usage()
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	var cmd *command
+	for _, candidate := range commands() {
+		if candidate.name == args[0] {
+			cmd = &candidate
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "userctl: unknown command %q\n\n", args[0])
+		c.usage()
+		return 2
+	}
+
+	fs := c.flagSet(cmd.name)
+	runCmd := cmd.setup(fs)
+	positional, err := parseInterspersed(fs, args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err == nil {
+		// Check -o before making any requests
+		_, err = c.format()
+	}
+	if err == nil {
+		err = runCmd(ctx, c, positional)
+	}
+
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "userctl %s: %v\nusage: userctl %s [flags] %s\n", cmd.name, err, cmd.name, cmd.args)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "userctl %s: %v\n", cmd.name, err)
+		return 1
+	}
+}
+
+// usage prints the list of commands
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "usage: userctl <command> [flags] [arguments]")
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(c.stderr, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(c.stderr)
+	fmt.Fprintln(c.stderr, `Run "userctl <command> -h" for the flags of a command.`)
+}
+
+// flagSet creates a flag set for a command with the global flags registered
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("userctl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	fs.StringVar(&c.configPath, "config", os.Getenv("USERCTL_CONFIG"), "path to the profiles file (default "+defaultConfigPath()+")")
+	fs.StringVar(&c.profile, "profile", os.Getenv("USERCTL_PROFILE"), "profile to use instead of the current one")
+	fs.StringVar(&c.server, "server", os.Getenv("USERCTL_SERVER"), "API base URL, overriding the profile")
+	fs.StringVar(&c.token, "token", os.Getenv("USERCTL_TOKEN"), "bearer token, overriding the profile")
+	fs.StringVar(&c.output, "o", "", "output format: table, json or yaml (default from the profile, else table)")
+	fs.DurationVar(&c.timeout, "timeout", 30*time.Second, "timeout for each HTTP request")
+	return fs
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// the positional arguments, which are returned
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		// "--" ends the flags; everything after it is positional
+		if args[0] == "--" {
+			return append(positional, args[1:]...), nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// client creates an API client from the selected profile and flags
+func (c *cli) client() (*client.Client, error) {
+	p, err := c.resolveProfile()
+	if err != nil {
+		return nil, err
+	}
+	if p.Server == "" {
+		return nil, usagef("no server configured; pass -server, set USERCTL_SERVER or add a profile with \"userctl profile set\"")
+	}
+
+	opts := []client.Option{
+		client.WithHTTPClient(&http.Client{Timeout: c.timeout}),
+		client.WithUserAgent("userctl"),
+	}
+	if p.Token != "" {
+		opts = append(opts, client.WithAuth(client.BearerToken(p.Token)))
+	}
+	return client.New(p.Server, opts...)
+}
+
+// format returns the selected output format
+func (c *cli) format() (string, error) {
+	format := c.output
+	if format == "" {
+		p, err := c.resolveProfile()
+		if err != nil {
+			return "", err
+		}
+		format = p.Output
+	}
+	if format == "" {
+		format = formatTable
+	}
+
+	format = strings.ToLower(format)
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return format, nil
+	}
+	return "", usagef("unknown output format %q; use table, json or yaml", format)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI serves the parts of the users API that userctl calls
+type fakeAPI struct {
+	mu      sync.Mutex
+	users   []map[string]any
+	patches []map[string]any
+	auth    []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/users":
+		body["id"] = len(f.users) + 1
+		f.users = append(f.users, body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(body)
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/users":
+		// Pages of at most two users, so userctl has to follow them
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		page := map[string]any{"data": f.users[min(offset, len(f.users)):min(offset+2, len(f.users))]}
+		if offset+2 < len(f.users) {
+			page["next_offset"] = offset + 2
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodPatch:
+		f.patches = append(f.patches, body)
+		json.NewEncoder(w).Encode(map[string]any{"id": 1, "name": "patched"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"user not found","code":"not_found"}`))
+	}
+}
+
+// runCLI runs userctl with args and returns its exit code and output
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestImportExportRoundTrip(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("USERCTL_CONFIG", configPath)
+
+	if code, _, stderr := runCLI(t, "", "profile", "set", "test", "-server", server.URL, "-token", "secret"); code != 0 {
+		t.Fatalf("profile set exit code = %d: %s", code, stderr)
+	}
+
+	csv := "dob,name,team\n1990-05-15,Alice,a\n1985-01-01,Bob,b\n2000-12-31,Carol,c\n"
+	if code, _, stderr := runCLI(t, csv, "import", "-format", "csv", "-"); code != 0 {
+		t.Fatalf("import exit code = %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runCLI(t, "", "export")
+	if code != 0 {
+		t.Fatalf("export exit code = %d: %s", code, stderr)
+	}
+	var exported []map[string]any
+	if err := json.Unmarshal([]byte(stdout), &exported); err != nil {
+		t.Fatal(err)
+	}
+	if len(exported) != 3 || exported[2]["name"] != "Carol" || exported[2]["dob"] != "2000-12-31" {
+		t.Errorf("export = %v; want the three imported users", exported)
+	}
+
+	for _, header := range api.auth {
+		if header != "Bearer secret" {
+			t.Fatalf("Authorization = %q; want the profile's token", header)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, "", "search", "-name", "o", "-born-before", "1999-01-01", "-o", "json")
+	if code != 0 || !strings.Contains(stdout, "Bob") || strings.Contains(stdout, "Carol") {
+		t.Errorf("search = %d, %s; want only Bob", code, stdout)
+	}
+}
+
+func TestUpdateSendsOnlyGivenFields(t *testing.T) {
+	api := &fakeAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	t.Setenv("USERCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	if code, _, stderr := runCLI(t, "", "update", "1", "-name", "Alicia", "-server", server.URL); code != 0 {
+		t.Fatalf("update exit code = %d: %s", code, stderr)
+	}
+	if len(api.patches) != 1 || len(api.patches[0]) != 1 || api.patches[0]["name"] != "Alicia" {
+		t.Errorf("patches = %v; want only the name", api.patches)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	server := httptest.NewServer(&fakeAPI{})
+	defer server.Close()
+	t.Setenv("USERCTL_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"frobnicate"}, 2},
+		{[]string{"get", "abc", "-server", server.URL}, 2},
+		{[]string{"get", "1"}, 2},
+		{[]string{"get", "7", "-server", server.URL}, 1},
+		{[]string{"delete", "7", "-server", server.URL}, 1},
+		{[]string{"delete", "7", "-yes", "-server", server.URL}, 1},
+		{[]string{"completion", "bash"}, 0},
+	}
+	for _, tt := range tests {
+		if code, _, stderr := runCLI(t, "", tt.args...); code != tt.want {
+			t.Errorf("userctl %v exit code = %d; want %d (%s)", tt.args, code, tt.want, stderr)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"user-profile-api/pkg/client"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with -o
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// printUser prints a single user
+func (c *cli) printUser(user *client.User) error {
+	return c.print(user, []client.User{*user})
+}
+
+// printUsers prints a list of users
+func (c *cli) printUsers(users []client.User) error {
+	if users == nil {
+		users = []client.User{}
+	}
+	return c.print(users, users)
+}
+
+// print writes v as JSON or YAML, or users as a table
+func (c *cli) print(v any, users []client.User) error {
+	format, err := c.format()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatJSON:
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case formatYAML:
+		enc := yaml.NewEncoder(c.stdout)
+		enc.SetIndent(2)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDOB\tAGE")
+	for _, user := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", user.ID, user.Name, user.DOB, user.Age)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profile holds the settings for one environment
+type profile struct {
+	Server string `yaml:"server"`
+	Token  string `yaml:"token,omitempty"`
+	Output string `yaml:"output,omitempty"`
+}
+
+// profileFile is the userctl configuration file:
+//
+//	current: staging
+//	profiles:
+//	  staging:
+//	    server: https://users.staging.example.com
+//	    token: ...
+//	  production:
+//	    server: https://users.example.com
+//	    output: json
+type profileFile struct {
+	Current  string             `yaml:"current,omitempty"`
+	Profiles map[string]profile `yaml:"profiles"`
+}
+
+// defaultConfigPath returns the profiles file used when -config and
+// USERCTL_CONFIG are unset
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "userctl.yaml"
+	}
+	return filepath.Join(dir, "userctl", "config.yaml")
+}
+
+// configPathOrDefault returns the profiles file path
+func (c *cli) configPathOrDefault() string {
+	if c.configPath != "" {
+		return c.configPath
+	}
+	return defaultConfigPath()
+}
+
+// loadProfiles reads the profiles file; a missing file has no profiles
+func (c *cli) loadProfiles() (*profileFile, error) {
+	file := &profileFile{Profiles: map[string]profile{}}
+
+	data, err := os.ReadFile(c.configPathOrDefault())
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles: %w", err)
+	}
+	if err := yaml.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles %s: %w", c.configPathOrDefault(), err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]profile{}
+	}
+	return file, nil
+}
+
+// saveProfiles writes the profiles file. It holds tokens, so only the owner
+// may read it.
+func (c *cli) saveProfiles(file *profileFile) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode profiles: %w", err)
+	}
+
+	path := c.configPathOrDefault()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write profiles: %w", err)
+	}
+	return nil
+}
+
+// resolveProfile returns the selected profile with the -server and -token
+// flags (or their environment variables) applied on top
+func (c *cli) resolveProfile() (profile, error) {
+	file, err := c.loadProfiles()
+	if err != nil {
+		return profile{}, err
+	}
+
+	var p profile
+	name := c.profile
+	if name == "" {
+		name = file.Current
+	}
+	if name != "" {
+		var ok bool
+		if p, ok = file.Profiles[name]; !ok && c.profile != "" {
+			return profile{}, usagef("unknown profile %q", name)
+		}
+	}
+
+	if c.server != "" {
+		p.Server = c.server
+	}
+	if c.token != "" {
+		p.Token = c.token
+	}
+	return p, nil
+}
+
+func profileCommand() command {
+	return command{
+		name:    "profile",
+		args:    "list | use NAME | set NAME | delete NAME",
+		summary: "Manage the profiles for each environment",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			names := fs.Bool("names", false, "list: print only the profile names")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) == 0 {
+					return usagef("missing profile subcommand")
+				}
+				file, err := c.loadProfiles()
+				if err != nil {
+					return err
+				}
+
+				switch sub := args[0]; {
+				case sub == "list" && len(args) == 1:
+					return c.listProfiles(file, *names)
+
+				case sub == "use" && len(args) == 2:
+					if _, ok := file.Profiles[args[1]]; !ok {
+						return usagef("unknown profile %q", args[1])
+					}
+					file.Current = args[1]
+
+				case sub == "set" && len(args) == 2:
+					// The global -server, -token and -o flags hold the new values
+					p := file.Profiles[args[1]]
+					if c.server != "" {
+						p.Server = c.server
+					}
+					if c.token != "" {
+						p.Token = c.token
+					}
+					if c.output != "" {
+						p.Output = c.output
+					}
+					if p.Server == "" {
+						return usagef("profile %q needs a server; pass -server", args[1])
+					}
+					file.Profiles[args[1]] = p
+					if file.Current == "" {
+						file.Current = args[1]
+					}
+
+				case sub == "delete" && len(args) == 2:
+					if _, ok := file.Profiles[args[1]]; !ok {
+						return usagef("unknown profile %q", args[1])
+					}
+					delete(file.Profiles, args[1])
+					if file.Current == args[1] {
+						file.Current = ""
+					}
+
+				default:
+					return usagef("invalid profile command %q", strings.Join(args, " "))
+				}
+				return c.saveProfiles(file)
+			}
+		},
+	}
+}
+
+// listProfiles prints the profiles, marking the current one
+func (c *cli) listProfiles(file *profileFile, namesOnly bool) error {
+	names := make([]string, 0, len(file.Profiles))
+	for name := range file.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if namesOnly {
+		for _, name := range names {
+			fmt.Fprintln(c.stdout, name)
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tSERVER")
+	for _, name := range names {
+		current := ""
+		if name == file.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", current, name, file.Profiles[name].Server)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"user-profile-api/pkg/client"
+)
+
+// File formats for import and export
+const (
+	fileJSON = "json"
+	fileCSV  = "csv"
+)
+
+// fileFormat returns the format named by the -format flag, falling back to
+// the file extension and then JSON
+func fileFormat(format, path string) (string, error) {
+	if format == "" {
+		format = fileJSON
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = fileCSV
+		}
+	}
+
+	format = strings.ToLower(format)
+	if format != fileJSON && format != fileCSV {
+		return "", usagef("unknown file format %q; use json or csv", format)
+	}
+	return format, nil
+}
+
+func importCommand() command {
+	return command{
+		name:    "import",
+		args:    "FILE",
+		summary: "Create users from a JSON or CSV file (- for stdin)",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			format := fs.String("format", "", "file format: json or csv (default from the file extension, else json)")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) != 1 {
+					return usagef("expected one file")
+				}
+				format, err := fileFormat(*format, args[0])
+				if err != nil {
+					return err
+				}
+
+				in := c.stdin
+				if args[0] != "-" {
+					f, err := os.Open(args[0])
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					in = f
+				}
+
+				var inputs []client.UserInput
+				if format == fileCSV {
+					inputs, err = readCSV(in)
+				} else {
+					inputs, err = readJSON(in)
+				}
+				if err != nil {
+					return err
+				}
+
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				// Keep going past bad records so one run reports all of them
+				var failed int
+				for i, input := range inputs {
+					user, err := api.Create(ctx, input)
+					if err != nil {
+						if ctx.Err() != nil {
+							return err
+						}
+						failed++
+						fmt.Fprintf(c.stderr, "record %d (%s): %v\n", i+1, input.Name, err)
+						continue
+					}
+					fmt.Fprintf(c.stderr, "created user %d (%s)\n", user.ID, user.Name)
+				}
+
+				if failed > 0 {
+					return fmt.Errorf("%d of %d users failed to import", failed, len(inputs))
+				}
+				fmt.Fprintf(c.stderr, "imported %d users\n", len(inputs))
+				return nil
+			}
+		},
+	}
+}
+
+// readJSON reads a JSON array of users; other fields, such as the id and
+// age in an export, are ignored
+func readJSON(r io.Reader) ([]client.UserInput, error) {
+	var inputs []client.UserInput
+	if err := json.NewDecoder(r).Decode(&inputs); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return inputs, nil
+}
+
+// readCSV reads a CSV file whose header names the name and dob columns;
+// other columns are ignored
+func readCSV(r io.Reader) ([]client.UserInput, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	nameCol, okName := columns["name"]
+	dobCol, okDOB := columns["dob"]
+	if !okName || !okDOB {
+		return nil, fmt.Errorf("invalid CSV: the header must have name and dob columns")
+	}
+
+	var inputs []client.UserInput
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return inputs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if nameCol >= len(record) || dobCol >= len(record) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("invalid CSV: line %d is missing columns", line)
+		}
+		inputs = append(inputs, client.UserInput{Name: record[nameCol], DOB: record[dobCol]})
+	}
+}
+
+func exportCommand() command {
+	return command{
+		name:    "export",
+		summary: "Write every user to a JSON or CSV file that import accepts",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			file := fs.String("file", "-", "file to write (- for stdout)")
+			format := fs.String("format", "", "file format: json or csv (default from the file extension, else json)")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) > 0 {
+					return usagef("unexpected arguments %q", args)
+				}
+				format, err := fileFormat(*format, *file)
+				if err != nil {
+					return err
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				// Fetch everything first so a failure leaves no partial file
+				users, err := collect(api.Iterate(ctx, 0), func(client.User) bool { return true })
+				if err != nil {
+					return err
+				}
+
+				write := writeJSON
+				if format == fileCSV {
+					write = writeCSV
+				}
+
+				if *file == "-" {
+					err = write(c.stdout, users)
+				} else {
+					err = writeFile(*file, users, write)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to write users: %w", err)
+				}
+				fmt.Fprintf(c.stderr, "exported %d users\n", len(users))
+				return nil
+			}
+		},
+	}
+}
+
+// writeFile creates path and writes users to it
+func writeFile(path string, users []client.User, write func(io.Writer, []client.User) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f, users); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeJSON writes users as a JSON array
+func writeJSON(w io.Writer, users []client.User) error {
+	if users == nil {
+		users = []client.User{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(users)
+}
+
+// writeCSV writes users as CSV with a header row
+func writeCSV(w io.Writer, users []client.User) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "name", "dob", "age"})
+	for _, user := range users {
+		writer.Write([]string{strconv.Itoa(int(user.ID)), user.Name, user.DOB, strconv.Itoa(user.Age)})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-profile-api/pkg/client"
+)
+
+// dateLayout is the format of dates of birth
+const dateLayout = "2006-01-02"
+
+// parseIDs parses user IDs given as arguments
+func parseIDs(args []string) ([]int32, error) {
+	if len(args) == 0 {
+		return nil, usagef("missing user ID")
+	}
+
+	ids := make([]int32, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil || id < 1 {
+			return nil, usagef("invalid user ID %q", arg)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// parseID parses the single user ID a command takes
+func parseID(args []string) (int32, error) {
+	if len(args) > 1 {
+		return 0, usagef("expected one user ID, got %d", len(args))
+	}
+	ids, err := parseIDs(args)
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+func getCommand() command {
+	return command{
+		name:    "get",
+		args:    "ID...",
+		summary: "Show users by ID",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			return func(ctx context.Context, c *cli, args []string) error {
+				ids, err := parseIDs(args)
+				if err != nil {
+					return err
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				users := make([]client.User, 0, len(ids))
+				for _, id := range ids {
+					user, err := api.Get(ctx, id)
+					if err != nil {
+						return fmt.Errorf("user %d: %w", id, err)
+					}
+					users = append(users, *user)
+				}
+				if len(users) == 1 {
+					return c.printUser(&users[0])
+				}
+				return c.printUsers(users)
+			}
+		},
+	}
+}
+
+func listCommand() command {
+	return command{
+		name:    "list",
+		summary: "List users ordered by ID",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			limit := fs.Int("limit", 0, "number of users to list (default: the server's page size)")
+			offset := fs.Int("offset", 0, "number of users to skip")
+			all := fs.Bool("all", false, "list every user, fetching as many pages as needed")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) > 0 {
+					return usagef("unexpected arguments %q", args)
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				if *all {
+					users, err := collect(api.Iterate(ctx, *limit), func(client.User) bool { return true })
+					if err != nil {
+						return err
+					}
+					return c.printUsers(users)
+				}
+
+				users, err := api.List(ctx, client.ListOptions{Limit: *limit, Offset: *offset})
+				if err != nil {
+					return err
+				}
+				return c.printUsers(users)
+			}
+		},
+	}
+}
+
+func searchCommand() command {
+	return command{
+		name:    "search",
+		summary: "Find users by name or date of birth",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			name := fs.String("name", "", "match names containing this text, ignoring case")
+			bornAfter := fs.String("born-after", "", "match users born after this date (YYYY-MM-DD)")
+			bornBefore := fs.String("born-before", "", "match users born before this date (YYYY-MM-DD)")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) > 0 {
+					return usagef("unexpected arguments %q", args)
+				}
+				for flagName, value := range map[string]string{"born-after": *bornAfter, "born-before": *bornBefore} {
+					if _, err := time.Parse(dateLayout, value); value != "" && err != nil {
+						return usagef("invalid -%s %q: use YYYY-MM-DD", flagName, value)
+					}
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				// The REST API has no search, so walk every user. Dates in
+				// YYYY-MM-DD order the same as strings.
+				needle := strings.ToLower(*name)
+				users, err := collect(api.Iterate(ctx, 0), func(user client.User) bool {
+					return strings.Contains(strings.ToLower(user.Name), needle) &&
+						(*bornAfter == "" || user.DOB > *bornAfter) &&
+						(*bornBefore == "" || user.DOB < *bornBefore)
+				})
+				if err != nil {
+					return err
+				}
+				return c.printUsers(users)
+			}
+		},
+	}
+}
+
+// collect returns the users from it that match keep
+func collect(it *client.UserIterator, keep func(client.User) bool) ([]client.User, error) {
+	var users []client.User
+	for it.Next() {
+		if keep(it.User()) {
+			users = append(users, it.User())
+		}
+	}
+	return users, it.Err()
+}
+
+func createCommand() command {
+	return command{
+		name:    "create",
+		summary: "Create a user",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			name := fs.String("name", "", "name of the user (required)")
+			dob := fs.String("dob", "", "date of birth as YYYY-MM-DD (required)")
+			timezone := fs.String("timezone", "", "IANA time zone, such as Europe/Paris")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				if len(args) > 0 {
+					return usagef("unexpected arguments %q", args)
+				}
+				if *name == "" || *dob == "" {
+					return usagef("-name and -dob are required")
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				user, err := api.Create(ctx, client.UserInput{Name: *name, DOB: *dob, Timezone: *timezone})
+				if err != nil {
+					return err
+				}
+				return c.printUser(user)
+			}
+		},
+	}
+}
+
+func updateCommand() command {
+	return command{
+		name:    "update",
+		args:    "ID",
+		summary: "Change the name, date of birth or time zone of a user",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			name := fs.String("name", "", "new name")
+			dob := fs.String("dob", "", "new date of birth as YYYY-MM-DD")
+			timezone := fs.String("timezone", "", "new IANA time zone; empty clears it")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				id, err := parseID(args)
+				if err != nil {
+					return err
+				}
+
+				// Only the flags given are sent, so the rest are left alone
+				var patch client.UserPatch
+				fs.Visit(func(f *flag.Flag) {
+					switch f.Name {
+					case "name":
+						patch.Name = name
+					case "dob":
+						patch.DOB = dob
+					case "timezone":
+						patch.Timezone = timezone
+					}
+				})
+				if patch.Name == nil && patch.DOB == nil && patch.Timezone == nil {
+					return usagef("nothing to update; pass -name, -dob or -timezone")
+				}
+
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+				user, err := api.Patch(ctx, id, patch)
+				if err != nil {
+					return err
+				}
+				return c.printUser(user)
+			}
+		},
+	}
+}
+
+func deleteCommand() command {
+	return command{
+		name:    "delete",
+		args:    "ID...",
+		summary: "Delete users",
+		setup: func(fs *flag.FlagSet) func(context.Context, *cli, []string) error {
+			yes := fs.Bool("yes", false, "do not ask for confirmation")
+
+			return func(ctx context.Context, c *cli, args []string) error {
+				ids, err := parseIDs(args)
+				if err != nil {
+					return err
+				}
+				if !*yes && !c.confirm(fmt.Sprintf("Delete %d user(s)?", len(ids))) {
+					return fmt.Errorf("aborted")
+				}
+				api, err := c.client()
+				if err != nil {
+					return err
+				}
+
+				for _, id := range ids {
+					if err := api.Delete(ctx, id); err != nil {
+						return fmt.Errorf("user %d: %w", id, err)
+					}
+					fmt.Fprintf(c.stderr, "deleted user %d\n", id)
+				}
+				return nil
+			}
+		},
+	}
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin
+func (c *cli) confirm(question string) bool {
+	fmt.Fprintf(c.stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(c.stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -1,39 +1,469 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds all application configuration
+// Config holds all application configuration. Values are resolved in order:
+// built-in defaults, the YAML config file, then environment variables.
+// Fields tagged `reload:"hot"` can change at runtime; see Store.
 type Config struct {
-	DatabaseURL string
-	Port        string
-	LogLevel    string
+	Server    ServerConfig    `yaml:"server"`
+	GRPC      GRPCConfig      `yaml:"grpc"`
+	GraphQL   GraphQLConfig   `yaml:"graphql"`
+	OpenAPI   OpenAPIConfig   `yaml:"openapi"`
+	Versions  VersionsConfig  `yaml:"versions"`
+	Birthdays BirthdaysConfig `yaml:"birthdays"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Log       LogConfig       `yaml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Health    HealthConfig    `yaml:"health"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Outbox    OutboxConfig    `yaml:"outbox"`
+	Webhooks  WebhooksConfig  `yaml:"webhooks"`
+	Stream    StreamConfig    `yaml:"stream"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	Admin     AdminConfig     `yaml:"admin"`
 }
 
-// Load loads configuration from environment variables
-func Load() (*Config, error) {
-	_ = godotenv.Load()
+// ServerConfig holds HTTP server settings
+type ServerConfig struct {
+	Port               string        `yaml:"port" env:"PORT"`
+	ServiceName        string        `yaml:"service_name" env:"SERVICE_NAME"`
+	Environment        string        `yaml:"environment" env:"ENVIRONMENT"`
+	ReadTimeout        time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout       time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout        time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout    time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	ShutdownDrainDelay time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	TLS                TLSConfig     `yaml:"tls"`
+}
+
+// GRPCConfig holds gRPC server settings
+type GRPCConfig struct {
+	Enabled bool   `yaml:"enabled" env:"GRPC_ENABLED"`
+	Port    string `yaml:"port" env:"GRPC_PORT"`
+
+	// AuthToken is required as a bearer token on UserService calls when set
+	AuthToken  string `yaml:"auth_token" env:"GRPC_AUTH_TOKEN" secret:"true"`
+	Reflection bool   `yaml:"reflection" env:"GRPC_REFLECTION"`
+}
+
+// GraphQLConfig holds /graphql settings. GraphiQL is served in development.
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled" env:"GRAPHQL_ENABLED"`
+	MaxDepth      int  `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH"`
+	MaxComplexity int  `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY"`
+}
+
+// OpenAPIConfig controls validation of traffic against /openapi.json
+type OpenAPIConfig struct {
+	ValidateRequests bool `yaml:"validate_requests" env:"OPENAPI_VALIDATE_REQUESTS"`
+	// ValidateResponses replaces non-conforming responses with 500; for tests
+	ValidateResponses bool `yaml:"validate_responses" env:"OPENAPI_VALIDATE_RESPONSES"`
+	// RedocIntegrity is the Subresource Integrity hash of the Redoc bundle
+	// loaded by /docs; empty loads it without an integrity check
+	RedocIntegrity string `yaml:"redoc_integrity" env:"OPENAPI_REDOC_INTEGRITY"`
+}
+
+// VersionsConfig controls the REST API versions under /v1 and /v2
+type VersionsConfig struct {
+	// Default is served on the unversioned routes when the request does not
+	// ask for a version
+	Default int `yaml:"default" env:"API_DEFAULT_VERSION"`
+	// V1Deprecated and V1Sunset are YYYY-MM-DD dates announced in the
+	// Deprecation and Sunset headers of v1 responses; empty leaves them out
+	V1Deprecated string `yaml:"v1_deprecated" env:"API_V1_DEPRECATED"`
+	V1Sunset     string `yaml:"v1_sunset" env:"API_V1_SUNSET"`
+}
+
+// BirthdaysConfig controls the birthday endpoints
+type BirthdaysConfig struct {
+	// LeapDay is when February 29 birthdays are observed in common years:
+	// "feb28" or "mar1"
+	LeapDay string `yaml:"leap_day" env:"BIRTHDAYS_LEAP_DAY"`
+	// CalendarLimit is the most events /users/birthdays.ics may hold
+	CalendarLimit int `yaml:"calendar_limit" env:"BIRTHDAYS_CALENDAR_LIMIT"`
+
+	// Scheduler notifies about users' birthdays in the background
+	Scheduler BirthdaySchedulerConfig `yaml:"scheduler"`
+}
+
+// BirthdaySchedulerConfig controls the birthday notification scheduler. Only
+// one instance sends notifications at a time.
+type BirthdaySchedulerConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"BIRTHDAY_SCHEDULER_ENABLED"`
+	Interval time.Duration `yaml:"interval" env:"BIRTHDAY_SCHEDULER_INTERVAL"`
+	// SendAt is the local time of day, HH:MM, from which a day's birthdays
+	// are announced
+	SendAt string `yaml:"send_at" env:"BIRTHDAY_SEND_AT"`
+	// Timezone applies to users without a time zone of their own
+	Timezone string `yaml:"timezone" env:"BIRTHDAY_TIMEZONE"`
+
+	// Notifiers lists how birthdays are announced: log, webhook, smtp
+	Notifiers    []string      `yaml:"notifiers" env:"BIRTHDAY_NOTIFIERS"`
+	WebhookURL   string        `yaml:"webhook_url" env:"BIRTHDAY_WEBHOOK_URL" secret:"true"`
+	SMTPAddr     string        `yaml:"smtp_addr" env:"BIRTHDAY_SMTP_ADDR"`
+	SMTPUsername string        `yaml:"smtp_username" env:"BIRTHDAY_SMTP_USERNAME"`
+	SMTPPassword string        `yaml:"smtp_password" env:"BIRTHDAY_SMTP_PASSWORD" secret:"true"`
+	SMTPFrom     string        `yaml:"smtp_from" env:"BIRTHDAY_SMTP_FROM"`
+	SMTPTo       []string      `yaml:"smtp_to" env:"BIRTHDAY_SMTP_TO"`
+	Timeout      time.Duration `yaml:"timeout" env:"BIRTHDAY_NOTIFIER_TIMEOUT"`
+}
+
+// TLSConfig holds TLS listener settings
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled" env:"TLS_ENABLED"`
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE"`
+}
+
+// DatabaseConfig holds PostgreSQL settings
+type DatabaseConfig struct {
+	URL string `yaml:"url" env:"DATABASE_URL" secret:"true"`
+
+	// QueryComments tags SQL statements with request and trace IDs
+	QueryComments bool `yaml:"query_comments" env:"DB_QUERY_COMMENTS"`
+
+	// Pool sizing and connection lifecycle
+	MaxConns          int           `yaml:"max_conns" env:"DB_MAX_CONNS"`
+	MinConns          int           `yaml:"min_conns" env:"DB_MIN_CONNS"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD"`
+	ConnectTimeout    time.Duration `yaml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+
+	// StatementTimeout is enforced by the server for every statement;
+	// QueryTimeout is the client-side deadline applied to each repository call
+	StatementTimeout time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+	QueryTimeout     time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+
+	// Read replicas serve GetUserByID, ListUsers and CountUsers while their
+	// lag is under ReplicaMaxLag. After a write, a client's reads go to the
+	// primary for PrimaryPinWindow.
+	ReplicaURLs        []string      `yaml:"replica_urls" env:"DATABASE_REPLICA_URLS" secret:"true"`
+	ReplicaMaxLag      time.Duration `yaml:"replica_max_lag" env:"DB_REPLICA_MAX_LAG"`
+	ReplicaCheckPeriod time.Duration `yaml:"replica_check_period" env:"DB_REPLICA_CHECK_PERIOD"`
+	PrimaryPinWindow   time.Duration `yaml:"primary_pin_window" env:"DB_PRIMARY_PIN_WINDOW"`
+
+	// SessionSettings are applied with set_config on every new connection
+	SessionSettings map[string]string `yaml:"session_settings"`
+
+	// Startup retry with exponential backoff
+	ConnectRetries         int           `yaml:"connect_retries" env:"DB_CONNECT_RETRIES"`
+	ConnectRetryBackoff    time.Duration `yaml:"connect_retry_backoff" env:"DB_CONNECT_RETRY_BACKOFF"`
+	ConnectRetryMaxBackoff time.Duration `yaml:"connect_retry_max_backoff" env:"DB_CONNECT_RETRY_MAX_BACKOFF"`
+}
+
+// LogConfig holds logger settings
+type LogConfig struct {
+	Level              string        `yaml:"level" env:"LOG_LEVEL" reload:"hot"`
+	Encoding           string        `yaml:"encoding" env:"LOG_ENCODING"`
+	SamplingInitial    int           `yaml:"sampling_initial" env:"LOG_SAMPLING_INITIAL"`
+	SamplingThereafter int           `yaml:"sampling_thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+	File               LogFileConfig `yaml:"file"`
+}
+
+// LogFileConfig holds rotated log file settings
+type LogFileConfig struct {
+	Path       string `yaml:"path" env:"LOG_FILE"`
+	MaxSizeMB  int    `yaml:"max_size_mb" env:"LOG_FILE_MAX_SIZE_MB"`
+	MaxAgeDays int    `yaml:"max_age_days" env:"LOG_FILE_MAX_AGE_DAYS"`
+	MaxBackups int    `yaml:"max_backups" env:"LOG_FILE_MAX_BACKUPS"`
+}
+
+// TracingConfig holds OpenTelemetry settings
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" env:"TRACING_OTLP_INSECURE"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// HealthConfig holds readiness check settings
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+}
+
+// CacheConfig holds user cache settings
+type CacheConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"CACHE_ENABLED"`
+	Size        int           `yaml:"size" env:"CACHE_SIZE"`
+	TTL         time.Duration `yaml:"ttl" env:"CACHE_TTL"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" env:"CACHE_NEGATIVE_TTL"`
+
+	// Broadcast invalidates entries on other instances via LISTEN/NOTIFY
+	Broadcast bool `yaml:"broadcast" env:"CACHE_BROADCAST"`
+}
+
+// OutboxConfig holds domain event settings
+type OutboxConfig struct {
+	// Enabled records an event for every user change and runs the relay
+	Enabled      bool          `yaml:"enabled" env:"OUTBOX_ENABLED"`
+	BatchSize    int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	Retention    time.Duration `yaml:"retention" env:"OUTBOX_RETENTION"`
+
+	// Sinks lists where events are delivered: stdout, file, webhook, nats
+	Sinks             []string      `yaml:"sinks" env:"OUTBOX_SINKS"`
+	FilePath          string        `yaml:"file_path" env:"OUTBOX_FILE_PATH"`
+	WebhookURL        string        `yaml:"webhook_url" env:"OUTBOX_WEBHOOK_URL" secret:"true"`
+	NATSURL           string        `yaml:"nats_url" env:"OUTBOX_NATS_URL"`
+	NATSSubjectPrefix string        `yaml:"nats_subject_prefix" env:"OUTBOX_NATS_SUBJECT_PREFIX"`
+	SinkTimeout       time.Duration `yaml:"sink_timeout" env:"OUTBOX_SINK_TIMEOUT"`
+}
+
+// WebhooksConfig holds outbound webhook settings. Webhooks are fed by the
+// outbox relay, so they require the outbox.
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED"`
+	BatchSize    int           `yaml:"batch_size" env:"WEBHOOKS_BATCH_SIZE"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	DisableAfter int           `yaml:"disable_after" env:"WEBHOOKS_DISABLE_AFTER"`
+	Retention    time.Duration `yaml:"retention" env:"WEBHOOKS_RETENTION"`
+}
+
+// StreamConfig holds settings for the /users/events stream, which is fed by
+// the events the outbox records
+type StreamConfig struct {
+	// BufferSize is how many recent events are kept for Last-Event-ID resume
+	BufferSize int           `yaml:"buffer_size" env:"STREAM_BUFFER_SIZE"`
+	Heartbeat  time.Duration `yaml:"heartbeat" env:"STREAM_HEARTBEAT"`
+}
+
+// CORSConfig holds cross-origin settings
+type CORSConfig struct {
+	AllowOrigins     []string      `yaml:"allow_origins" env:"CORS_ALLOW_ORIGINS" reload:"hot"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" reload:"hot"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE" reload:"hot"`
+}
+
+// LimitsConfig holds request size, pagination and rate limits
+type LimitsConfig struct {
+	BodyLimitBytes  int           `yaml:"body_limit_bytes" env:"BODY_LIMIT_BYTES"`
+	DefaultPageSize int           `yaml:"default_page_size" env:"DEFAULT_PAGE_SIZE" reload:"hot"`
+	MaxPageSize     int           `yaml:"max_page_size" env:"MAX_PAGE_SIZE" reload:"hot"`
+	RateLimit       int           `yaml:"rate_limit" env:"RATE_LIMIT" reload:"hot"`
+	RateLimitWindow time.Duration `yaml:"rate_limit_window" env:"RATE_LIMIT_WINDOW" reload:"hot"`
+}
+
+// AdminConfig holds admin API settings
+type AdminConfig struct {
+	// Token guards the /admin endpoints; they are disabled when empty
+	Token string `yaml:"token" env:"ADMIN_TOKEN" secret:"true"`
+}
+
+// Default returns the built-in configuration defaults
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:               "3000",
+			ServiceName:        "user-profile-api",
+			Environment:        EnvironmentProduction,
+			ReadTimeout:        10 * time.Second,
+			WriteTimeout:       10 * time.Second,
+			IdleTimeout:        60 * time.Second,
+			ShutdownTimeout:    10 * time.Second,
+			ShutdownDrainDelay: 5 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Enabled:    true,
+			Port:       "9090",
+			Reflection: true,
+		},
+		GraphQL: GraphQLConfig{
+			Enabled:       true,
+			MaxDepth:      8,
+			MaxComplexity: 1000,
+		},
+		Versions: VersionsConfig{
+			Default: 1,
+		},
+		Birthdays: BirthdaysConfig{
+			LeapDay:       "mar1",
+			CalendarLimit: 10000,
+			Scheduler: BirthdaySchedulerConfig{
+				Interval:  time.Minute,
+				SendAt:    "09:00",
+				Timezone:  "UTC",
+				Notifiers: []string{BirthdayNotifierLog},
+				Timeout:   10 * time.Second,
+			},
+		},
+		Database: DatabaseConfig{
+			QueryComments:          true,
+			MaxConns:               10,
+			MinConns:               2,
+			MaxConnLifetime:        time.Hour,
+			MaxConnIdleTime:        30 * time.Minute,
+			HealthCheckPeriod:      time.Minute,
+			ConnectTimeout:         5 * time.Second,
+			StatementTimeout:       30 * time.Second,
+			QueryTimeout:           5 * time.Second,
+			ConnectRetries:         5,
+			ConnectRetryBackoff:    time.Second,
+			ConnectRetryMaxBackoff: 30 * time.Second,
+			ReplicaMaxLag:          10 * time.Second,
+			ReplicaCheckPeriod:     5 * time.Second,
+			PrimaryPinWindow:       5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:              "info",
+			Encoding:           "console",
+			SamplingInitial:    100,
+			SamplingThereafter: 100,
+			File: LogFileConfig{
+				MaxSizeMB:  100,
+				MaxAgeDays: 7,
+				MaxBackups: 5,
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+			SampleRatio:  1,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     time.Second,
+		},
+		Cache: CacheConfig{
+			Enabled:     true,
+			Size:        10000,
+			TTL:         time.Minute,
+			NegativeTTL: 5 * time.Second,
+			Broadcast:   true,
+		},
+		Outbox: OutboxConfig{
+			Enabled:           true,
+			BatchSize:         100,
+			PollInterval:      5 * time.Second,
+			Retention:         7 * 24 * time.Hour,
+			NATSSubjectPrefix: "users",
+			SinkTimeout:       10 * time.Second,
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			BatchSize:    20,
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			DisableAfter: 50,
+			Retention:    30 * 24 * time.Hour,
+		},
+		Stream: StreamConfig{
+			BufferSize: 1000,
+			Heartbeat:  15 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"*"},
+		},
+		Limits: LimitsConfig{
+			BodyLimitBytes:  1 << 20,
+			DefaultPageSize: 10,
+			MaxPageSize:     100,
+			RateLimitWindow: time.Minute,
+		},
+	}
+}
+
+// dotenvFile holds variables for local development; the process environment
+// takes precedence over it
+const dotenvFile = ".env"
 
-	cfg := &Config{
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		Port:        getEnvOrDefault("PORT", "3000"),
-		LogLevel:    getEnvOrDefault("LOG_LEVEL", "info"),
+// Load resolves the configuration from defaults, the optional YAML file at
+// path, the .env file and the environment, and validates the result
+func Load(path string) (*Config, error) {
+	loadDotenv(dotenvFile)
+	return load(path)
+}
+
+// Reload is like Load but re-reads the .env file. Variables set by the
+// process environment still take precedence over the file.
+func Reload(path string) (*Config, error) {
+	loadDotenv(dotenvFile)
+	return load(path)
+}
+
+// dotenv tracks the variables taken from the .env file, so that reloads can
+// update them without overriding the process environment
+var dotenv = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+// loadDotenv sets the variables in the .env file at path that the process
+// environment does not set itself. Variables taken from an earlier version
+// of the file are updated, or unset once removed from it. A missing or
+// unreadable file leaves the environment unchanged.
+func loadDotenv(path string) {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return
+	}
+
+	dotenv.Lock()
+	defer dotenv.Unlock()
+
+	for key := range dotenv.keys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenv.keys, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotenv.keys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenv.keys[key] = true
 	}
+}
+
+func load(path string) (*Config, error) {
+	cfg := Default()
 
-	if cfg.DatabaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadFile overlays the YAML file at path onto cfg, rejecting unknown keys
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
 	}
-	return defaultValue
+
+	return nil
 }
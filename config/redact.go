@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration with secrets masked
+func (c *Config) Redacted() *Config {
+	clone := *c
+	clone.CORS.AllowOrigins = append([]string(nil), c.CORS.AllowOrigins...)
+
+	walkFields(reflect.ValueOf(&clone).Elem(), func(field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get("secret") != "true" {
+			return
+		}
+
+		switch value.Kind() {
+		case reflect.String:
+			if value.String() != "" {
+				value.SetString(redacted)
+			}
+		case reflect.Slice:
+			masked := make([]string, value.Len())
+			for i := range masked {
+				masked[i] = redacted
+			}
+			value.Set(reflect.ValueOf(masked))
+		}
+	})
+
+	return &clone
+}
+
+// YAML renders the configuration, with secrets masked, as a YAML document
+func (c *Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c.Redacted())
+}
+
+// Map returns the configuration, with secrets masked, keyed by the YAML field
+// names so it can be rendered in other formats
+func (c *Config) Map() (map[string]any, error) {
+	out, err := c.YAML()
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]any
+	if err := yaml.Unmarshal(out, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Snapshot is an immutable, versioned configuration
+type Snapshot struct {
+	Config   *Config
+	Version  int64
+	LoadedAt time.Time
+	Checksum string
+}
+
+// Store holds the active configuration and swaps in reloaded versions. Only
+// fields tagged `reload:"hot"` may change; other changes are rejected.
+type Store struct {
+	path    string
+	logger  *zap.Logger
+	current atomic.Pointer[Snapshot]
+
+	// mu serializes reloads and guards subscribers
+	mu          sync.Mutex
+	subscribers []func(old, new *Config)
+}
+
+// NewStore creates a store serving cfg as version 1. path is the config file
+// re-read on reload and may be empty.
+func NewStore(path string, cfg *Config, logger *zap.Logger) *Store {
+	s := &Store{
+		path:   path,
+		logger: logger,
+	}
+	s.current.Store(newSnapshot(cfg, 1))
+	return s
+}
+
+// Current returns the active configuration
+func (s *Store) Current() *Config {
+	return s.current.Load().Config
+}
+
+// Snapshot returns the active configuration along with its version
+func (s *Store) Snapshot() *Snapshot {
+	return s.current.Load()
+}
+
+// Subscribe registers fn to be called after each successful reload
+func (s *Store) Subscribe(fn func(old, new *Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Reload re-reads and validates the configuration, then atomically swaps it
+// in and notifies subscribers. The active configuration is kept on error.
+func (s *Store) Reload(reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	log := s.logger.With(zap.String("reason", reason))
+
+	next, err := Reload(s.path)
+	if err != nil {
+		log.Error("configuration reload rejected", zap.Error(err))
+		return err
+	}
+
+	current := s.current.Load()
+	if changed := coldChanges(current.Config, next); len(changed) > 0 {
+		err := fmt.Errorf("settings require a restart: %s", strings.Join(changed, ", "))
+		log.Error("configuration reload rejected", zap.Error(err))
+		return err
+	}
+
+	snapshot := newSnapshot(next, current.Version+1)
+	if snapshot.Checksum == current.Checksum {
+		log.Info("configuration unchanged", zap.Int64("version", current.Version))
+		return nil
+	}
+
+	s.current.Store(snapshot)
+	for _, fn := range s.subscribers {
+		fn(current.Config, next)
+	}
+
+	log.Info("configuration reloaded", zap.Int64("version", snapshot.Version))
+	return nil
+}
+
+func newSnapshot(cfg *Config, version int64) *Snapshot {
+	return &Snapshot{
+		Config:   cfg,
+		Version:  version,
+		LoadedAt: time.Now().UTC(),
+		Checksum: checksum(cfg),
+	}
+}
+
+// checksum fingerprints the configuration, including secrets, without
+// exposing them
+func checksum(cfg *Config) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", *cfg)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// coldChanges returns the YAML paths of changed fields that are not
+// hot-reloadable
+func coldChanges(old, new *Config) []string {
+	var changed []string
+	compareFields(reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem(), "", &changed)
+	return changed
+}
+
+func compareFields(old, new reflect.Value, prefix string, changed *[]string) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if field.Type.Kind() == reflect.Struct {
+			compareFields(old.Field(i), new.Field(i), name+".", changed)
+			continue
+		}
+		if field.Tag.Get("reload") == "hot" {
+			continue
+		}
+		if !reflect.DeepEqual(old.Field(i).Interface(), new.Field(i).Interface()) {
+			*changed = append(*changed, name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DATABASE_URL", "postgres://env")
+	t.Setenv("PORT", "")
+	t.Setenv("LOG_LEVEL", "")
+
+	write("log:\n  level: info\n")
+	cfg, err := load(path)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	store := NewStore(path, cfg, zap.NewNop())
+	var notified int
+	store.Subscribe(func(old, new *Config) { notified++ })
+
+	// Hot-reloadable change is applied
+	write("log:\n  level: debug\n")
+	if err := store.Reload("test"); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if got := store.Current().Log.Level; got != "debug" {
+		t.Errorf("Log.Level = %s; want debug", got)
+	}
+	if got := store.Snapshot().Version; got != 2 {
+		t.Errorf("Version = %d; want 2", got)
+	}
+
+	// Changing the listen port requires a restart
+	write("log:\n  level: warn\nserver:\n  port: \"4000\"\n")
+	if err := store.Reload("test"); err == nil {
+		t.Error("Reload() error = nil; want rejection of server.port change")
+	}
+	if got := store.Current().Log.Level; got != "debug" {
+		t.Errorf("Log.Level = %s; want previous value kept after rejected reload", got)
+	}
+
+	// Invalid configuration is rejected
+	write("log:\n  level: loud\n")
+	if err := store.Reload("test"); err == nil {
+		t.Error("Reload() error = nil; want validation error")
+	}
+
+	if notified != 1 {
+		t.Errorf("subscribers notified %d times; want 1", notified)
+	}
+}
@@ -0,0 +1,230 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Supported environments
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+// Supported outbox sinks
+const (
+	OutboxSinkStdout  = "stdout"
+	OutboxSinkFile    = "file"
+	OutboxSinkWebhook = "webhook"
+	OutboxSinkNATS    = "nats"
+)
+
+// Supported birthday notifiers
+const (
+	BirthdayNotifierLog     = "log"
+	BirthdayNotifierWebhook = "webhook"
+	BirthdayNotifierSMTP    = "smtp"
+)
+
+// integrityPattern matches Subresource Integrity hashes such as sha384-<base64>
+var integrityPattern = regexp.MustCompile(`^sha(256|384|512)-[A-Za-z0-9+/]+={0,2}$`)
+
+// sessionSettingPattern matches PostgreSQL parameter names such as work_mem or
+// custom.option
+var sessionSettingPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	// Server
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port <= 65535, "server.port must be between 1 and 65535, got %q", c.Server.Port)
+	check(c.Server.ServiceName != "", "server.service_name is required")
+	check(c.Server.Environment == EnvironmentDevelopment || c.Server.Environment == EnvironmentProduction,
+		"server.environment must be %q or %q, got %q", EnvironmentDevelopment, EnvironmentProduction, c.Server.Environment)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.ShutdownDrainDelay >= 0, "server.shutdown_drain_delay must not be negative")
+	if c.Server.TLS.Enabled {
+		check(fileExists(c.Server.TLS.CertFile), "server.tls.cert_file %q does not exist", c.Server.TLS.CertFile)
+		check(fileExists(c.Server.TLS.KeyFile), "server.tls.key_file %q does not exist", c.Server.TLS.KeyFile)
+	}
+
+	// gRPC
+	if c.GRPC.Enabled {
+		grpcPort, err := strconv.Atoi(c.GRPC.Port)
+		check(err == nil && grpcPort > 0 && grpcPort <= 65535, "grpc.port must be between 1 and 65535, got %q", c.GRPC.Port)
+		check(c.GRPC.Port != c.Server.Port, "grpc.port must differ from server.port")
+	}
+
+	// GraphQL
+	if c.GraphQL.Enabled {
+		check(c.GraphQL.MaxDepth >= 0, "graphql.max_depth must not be negative")
+		check(c.GraphQL.MaxComplexity >= 0, "graphql.max_complexity must not be negative")
+	}
+
+	// OpenAPI
+	check(c.OpenAPI.RedocIntegrity == "" || integrityPattern.MatchString(c.OpenAPI.RedocIntegrity),
+		"openapi.redoc_integrity must be a sha256-, sha384- or sha512- hash, got %q", c.OpenAPI.RedocIntegrity)
+
+	// Versions
+	check(c.Versions.Default == 1 || c.Versions.Default == 2, "versions.default must be 1 or 2, got %d", c.Versions.Default)
+	deprecated, err := time.Parse(time.DateOnly, c.Versions.V1Deprecated)
+	check(c.Versions.V1Deprecated == "" || err == nil, "versions.v1_deprecated must be a YYYY-MM-DD date, got %q", c.Versions.V1Deprecated)
+	if c.Versions.V1Sunset != "" {
+		sunset, err := time.Parse(time.DateOnly, c.Versions.V1Sunset)
+		check(err == nil, "versions.v1_sunset must be a YYYY-MM-DD date, got %q", c.Versions.V1Sunset)
+		check(c.Versions.V1Deprecated != "", "versions.v1_sunset requires versions.v1_deprecated")
+		check(err != nil || sunset.After(deprecated), "versions.v1_sunset must be after versions.v1_deprecated")
+	}
+
+	// Birthdays
+	check(c.Birthdays.LeapDay == "feb28" || c.Birthdays.LeapDay == "mar1", "birthdays.leap_day must be feb28 or mar1, got %q", c.Birthdays.LeapDay)
+	check(c.Birthdays.CalendarLimit > 0, "birthdays.calendar_limit must be positive")
+	if s := c.Birthdays.Scheduler; s.Enabled {
+		check(s.Interval > 0, "birthdays.scheduler.interval must be positive")
+		_, err := time.Parse("15:04", s.SendAt)
+		check(err == nil, "birthdays.scheduler.send_at must be an HH:MM time, got %q", s.SendAt)
+		_, err = time.LoadLocation(s.Timezone)
+		check(err == nil, "birthdays.scheduler.timezone must be an IANA time zone, got %q", s.Timezone)
+		check(len(s.Notifiers) > 0, "birthdays.scheduler.notifiers must not be empty")
+		check(s.Timeout > 0, "birthdays.scheduler.timeout must be positive")
+		for _, notifier := range s.Notifiers {
+			switch notifier {
+			case BirthdayNotifierLog:
+			case BirthdayNotifierWebhook:
+				check(s.WebhookURL != "", "birthdays.scheduler.webhook_url is required for the webhook notifier")
+			case BirthdayNotifierSMTP:
+				check(s.SMTPAddr != "", "birthdays.scheduler.smtp_addr is required for the smtp notifier")
+				check(s.SMTPFrom != "", "birthdays.scheduler.smtp_from is required for the smtp notifier")
+				check(len(s.SMTPTo) > 0, "birthdays.scheduler.smtp_to is required for the smtp notifier")
+			default:
+				check(false, "unknown birthday notifier %q; use log, webhook or smtp", notifier)
+			}
+		}
+	}
+
+	// Database
+	check(c.Database.URL != "", "database.url is required (set DATABASE_URL or DATABASE_URL_FILE)")
+	check(c.Database.MaxConns > 0, "database.max_conns must be positive")
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns, "database.min_conns must be between 0 and database.max_conns")
+	check(c.Database.MaxConnLifetime > 0, "database.max_conn_lifetime must be positive")
+	check(c.Database.MaxConnIdleTime > 0, "database.max_conn_idle_time must be positive")
+	check(c.Database.HealthCheckPeriod > 0, "database.health_check_period must be positive")
+	check(c.Database.ConnectTimeout > 0, "database.connect_timeout must be positive")
+	check(c.Database.StatementTimeout >= 0, "database.statement_timeout must not be negative")
+	check(c.Database.QueryTimeout >= 0, "database.query_timeout must not be negative")
+	check(c.Database.ConnectRetries >= 0, "database.connect_retries must not be negative")
+	check(c.Database.ConnectRetryBackoff > 0, "database.connect_retry_backoff must be positive")
+	check(c.Database.ConnectRetryMaxBackoff >= c.Database.ConnectRetryBackoff, "database.connect_retry_max_backoff must be at least database.connect_retry_backoff")
+	check(c.Database.ReplicaMaxLag > 0, "database.replica_max_lag must be positive")
+	check(c.Database.ReplicaCheckPeriod > 0, "database.replica_check_period must be positive")
+	check(c.Database.PrimaryPinWindow >= 0, "database.primary_pin_window must not be negative")
+	for _, url := range c.Database.ReplicaURLs {
+		check(url != c.Database.URL, "database.replica_urls must not include the primary database.url")
+	}
+	for name := range c.Database.SessionSettings {
+		check(sessionSettingPattern.MatchString(name), "database.session_settings key %q is not a valid setting name", name)
+	}
+
+	// Log
+	_, err = zapcore.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level %q is not a valid level", c.Log.Level)
+	check(c.Log.Encoding == "console" || c.Log.Encoding == "json", "log.encoding must be \"console\" or \"json\", got %q", c.Log.Encoding)
+	check(c.Log.SamplingInitial >= 0 && c.Log.SamplingThereafter >= 0, "log sampling values must not be negative")
+	check(c.Log.File.MaxSizeMB >= 0 && c.Log.File.MaxAgeDays >= 0 && c.Log.File.MaxBackups >= 0, "log.file rotation values must not be negative")
+
+	// Tracing
+	check(c.Tracing.Exporter == "none" || c.Tracing.Exporter == "stdout" || c.Tracing.Exporter == "otlp",
+		"tracing.exporter must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint is required for the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	// Health
+	check(c.Health.CheckTimeout > 0, "health.check_timeout must be positive")
+	check(c.Health.CacheTTL >= 0, "health.cache_ttl must not be negative")
+
+	// Cache
+	if c.Cache.Enabled {
+		check(c.Cache.Size > 0, "cache.size must be positive")
+		check(c.Cache.TTL > 0, "cache.ttl must be positive")
+		check(c.Cache.NegativeTTL >= 0, "cache.negative_ttl must not be negative")
+	}
+
+	// Outbox
+	if c.Outbox.Enabled {
+		check(c.Outbox.BatchSize > 0, "outbox.batch_size must be positive")
+		check(c.Outbox.PollInterval > 0, "outbox.poll_interval must be positive")
+		check(c.Outbox.Retention >= 0, "outbox.retention must not be negative")
+		check(c.Outbox.SinkTimeout > 0, "outbox.sink_timeout must be positive")
+		for _, sink := range c.Outbox.Sinks {
+			switch sink {
+			case OutboxSinkStdout:
+			case OutboxSinkFile:
+				check(c.Outbox.FilePath != "", "outbox.file_path is required for the file sink")
+			case OutboxSinkWebhook:
+				check(c.Outbox.WebhookURL != "", "outbox.webhook_url is required for the webhook sink")
+			case OutboxSinkNATS:
+				check(c.Outbox.NATSURL != "", "outbox.nats_url is required for the nats sink")
+			default:
+				check(false, "outbox.sinks entry %q must be one of stdout, file, webhook, nats", sink)
+			}
+		}
+	}
+
+	// Webhooks
+	if c.Webhooks.Enabled {
+		check(c.Outbox.Enabled, "webhooks.enabled requires outbox.enabled")
+		check(c.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive")
+		check(c.Webhooks.PollInterval > 0, "webhooks.poll_interval must be positive")
+		check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+		check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+		check(c.Webhooks.DisableAfter >= 0, "webhooks.disable_after must not be negative")
+		check(c.Webhooks.Retention >= 0, "webhooks.retention must not be negative")
+	}
+
+	// Stream
+	check(c.Stream.BufferSize > 0, "stream.buffer_size must be positive")
+	check(c.Stream.Heartbeat > 0, "stream.heartbeat must be positive")
+
+	// CORS
+	check(len(c.CORS.AllowOrigins) > 0, "cors.allow_origins must list at least one origin")
+	for _, origin := range c.CORS.AllowOrigins {
+		check(!(origin == "*" && c.CORS.AllowCredentials), "cors.allow_credentials cannot be combined with the \"*\" origin")
+	}
+	check(c.CORS.MaxAge >= 0, "cors.max_age must not be negative")
+
+	// Limits
+	check(c.Limits.BodyLimitBytes > 0, "limits.body_limit_bytes must be positive")
+	check(c.Limits.DefaultPageSize > 0, "limits.default_page_size must be positive")
+	check(c.Limits.MaxPageSize >= c.Limits.DefaultPageSize, "limits.max_page_size must be at least limits.default_page_size")
+	check(c.Limits.RateLimit >= 0, "limits.rate_limit must not be negative")
+	check(c.Limits.RateLimit == 0 || c.Limits.RateLimitWindow > 0, "limits.rate_limit_window must be positive when rate limiting is enabled")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
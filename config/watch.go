@@ -0,0 +1,64 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// watchDebounce coalesces the burst of events editors emit when saving
+const watchDebounce = 250 * time.Millisecond
+
+// Watch reloads the configuration whenever the config file changes, until
+// ctx is done. It is a no-op when the store has no file.
+func (s *Store) Watch(ctx context.Context) error {
+	if s.path == "" {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+
+	// Watch the directory, since many editors replace the file rather than
+	// writing it in place
+	target := filepath.Clean(s.path)
+	if err := watcher.Add(filepath.Dir(target)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == target && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(watchDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				s.logger.Warn("config watcher error", zap.Error(err))
+			case <-debounce:
+				debounce = nil
+				_ = s.Reload("file changed")
+			}
+		}
+	}()
+
+	return nil
+}
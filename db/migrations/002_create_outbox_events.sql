@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    data JSONB NOT NULL,
+    request_id TEXT,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT,
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(id) WHERE delivered_at IS NULL;
+CREATE INDEX idx_outbox_events_delivered_at ON outbox_events(delivered_at) WHERE delivered_at IS NOT NULL;
+
+COMMENT ON TABLE outbox_events IS 'Transactional outbox of user lifecycle events awaiting delivery';
+COMMENT ON COLUMN outbox_events.event_type IS 'Event type such as user.created, user.updated or user.deleted';
+COMMENT ON COLUMN outbox_events.user_id IS 'User the event is about; events are delivered in order per user';
+COMMENT ON COLUMN outbox_events.data IS 'User snapshots before and after the change';
+COMMENT ON COLUMN outbox_events.next_attempt_at IS 'Earliest time of the next delivery attempt after a failure';
+COMMENT ON COLUMN outbox_events.delivered_at IS 'Set once every sink has accepted the event';
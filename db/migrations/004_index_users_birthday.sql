@@ -0,0 +1,6 @@
+-- Birthday lookups match the month and day of birth as month * 100 + day,
+-- e.g. 515 for May 15; queries must use the same expression to hit the index
+CREATE INDEX IF NOT EXISTS idx_users_birthday
+    ON users (((EXTRACT(MONTH FROM dob)::int * 100 + EXTRACT(DAY FROM dob)::int)), id);
+
+COMMENT ON INDEX idx_users_birthday IS 'Month and day of birth, for the birthday endpoints';
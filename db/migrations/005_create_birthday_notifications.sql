@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS birthday_notifications (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    birthday DATE NOT NULL,
+    notifier TEXT NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, birthday, notifier)
+);
+
+CREATE INDEX idx_birthday_notifications_birthday ON birthday_notifications(birthday);
+
+COMMENT ON TABLE birthday_notifications IS 'Birthday notifications claimed by the scheduler, one per user, birthday and notifier, so restarts do not send twice';
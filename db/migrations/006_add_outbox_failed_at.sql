@@ -0,0 +1,3 @@
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS failed_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN outbox_events.failed_at IS 'Set when the event cannot be decoded; it is never retried and no longer holds back later events for the user';
@@ -0,0 +1,5 @@
+-- Every read and write of users selects this column, so apply it before
+-- deploying a server that knows about time zones
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT;
+
+COMMENT ON COLUMN users.timezone IS 'IANA time zone deciding when the user''s day starts; NULL uses the default';
@@ -0,0 +1,5 @@
+-- Every read and write of users selects this column, so apply it before
+-- deploying a server that dates birthday calendars by it
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+COMMENT ON COLUMN users.updated_at IS 'When the user was last created or updated, by the server clock; dates the birthday calendar';
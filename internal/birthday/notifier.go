@@ -0,0 +1,237 @@
+package birthday
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"user-profile-api/config"
+
+	"go.uber.org/zap"
+)
+
+// Notification announces a user's birthday
+type Notification struct {
+	UserID int32  `json:"user_id"`
+	Name   string `json:"name"`
+	DOB    string `json:"dob"`
+	// Birthday is the date the birthday is observed on in Timezone
+	Birthday string `json:"birthday"`
+	// Turns is the age the user reaches on Birthday
+	Turns    int    `json:"turns"`
+	Timezone string `json:"timezone"`
+}
+
+// Notifier announces birthdays. Name identifies the notifier in the record
+// of sent notifications, so it must not change between releases.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, n Notification) error
+}
+
+// NewNotifiers builds the notifiers listed in cfg
+func NewNotifiers(cfg config.BirthdaySchedulerConfig, logger *zap.Logger) ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, name := range cfg.Notifiers {
+		switch name {
+		case config.BirthdayNotifierLog:
+			notifiers = append(notifiers, NewLogNotifier(logger))
+		case config.BirthdayNotifierWebhook:
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL, cfg.Timeout))
+		case config.BirthdayNotifierSMTP:
+			notifiers = append(notifiers, NewSMTPNotifier(SMTPOptions{
+				Addr:     cfg.SMTPAddr,
+				Username: cfg.SMTPUsername,
+				Password: cfg.SMTPPassword,
+				From:     cfg.SMTPFrom,
+				To:       cfg.SMTPTo,
+				Timeout:  cfg.Timeout,
+			}))
+		default:
+			return nil, fmt.Errorf("unknown birthday notifier %q", name)
+		}
+	}
+	return notifiers, nil
+}
+
+// LogNotifier writes each birthday to the log
+type LogNotifier struct {
+	logger *zap.Logger
+}
+
+// NewLogNotifier creates a notifier logging to logger
+func NewLogNotifier(logger *zap.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+// Name implements Notifier
+func (n *LogNotifier) Name() string {
+	return config.BirthdayNotifierLog
+}
+
+// Notify logs the birthday
+func (n *LogNotifier) Notify(ctx context.Context, b Notification) error {
+	n.logger.Info("birthday",
+		zap.Int32("user_id", b.UserID),
+		zap.String("name", b.Name),
+		zap.String("birthday", b.Birthday),
+		zap.Int("turns", b.Turns),
+		zap.String("timezone", b.Timezone),
+	)
+	return nil
+}
+
+// WebhookNotifier POSTs each birthday as JSON to a URL. Any 2xx response
+// counts as sent.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a notifier posting to url
+func NewWebhookNotifier(url string, timeout time.Duration) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Name implements Notifier
+func (n *WebhookNotifier) Name() string {
+	return config.BirthdayNotifierWebhook
+}
+
+// Notify posts the birthday, identifying it in the X-Birthday-ID header so
+// receivers can deduplicate
+func (n *WebhookNotifier) Notify(ctx context.Context, b Notification) error {
+	body, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Birthday-ID", fmt.Sprintf("%d-%s", b.UserID, b.Birthday))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPOptions configures an SMTPNotifier
+type SMTPOptions struct {
+	// Addr is the server's host:port
+	Addr string
+	// Username and Password authenticate with PLAIN when Username is set,
+	// which net/smtp only allows over TLS or to localhost
+	Username string
+	Password string
+	From     string
+	To       []string
+	Timeout  time.Duration
+}
+
+// SMTPNotifier emails each birthday to a fixed list of recipients, using
+// STARTTLS when the server offers it
+type SMTPNotifier struct {
+	opts SMTPOptions
+}
+
+// NewSMTPNotifier creates a notifier sending through the server in opts
+func NewSMTPNotifier(opts SMTPOptions) *SMTPNotifier {
+	return &SMTPNotifier{opts: opts}
+}
+
+// Name implements Notifier
+func (n *SMTPNotifier) Name() string {
+	return config.BirthdayNotifierSMTP
+}
+
+// Notify sends one email about the birthday
+func (n *SMTPNotifier) Notify(ctx context.Context, b Notification) error {
+	dialer := &net.Dialer{Timeout: n.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.opts.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(n.opts.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(n.opts.Addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if n.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.opts.Username, n.opts.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.opts.From); err != nil {
+		return fmt.Errorf("SMTP MAIL failed: %w", err)
+	}
+	for _, to := range n.opts.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP RCPT %s failed: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(n.message(b)); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the email: %w", err)
+	}
+	return client.Quit()
+}
+
+// message builds the email for a birthday
+func (n *SMTPNotifier) message(b Notification) []byte {
+	subject := fmt.Sprintf("%s turns %d today", b.Name, b.Turns)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.opts.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.opts.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <birthday-%d-%s@user-profile-api>\r\n", b.UserID, b.Birthday)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	fmt.Fprintf(&msg, "%s (user %d, born %s) turns %d on %s (%s).\r\n", b.Name, b.UserID, b.DOB, b.Turns, b.Birthday, b.Timezone)
+	return []byte(msg.String())
+}
@@ -0,0 +1,177 @@
+package birthday
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/models"
+)
+
+// smtpStandIn is a minimal SMTP server that records the messages it accepts
+type smtpStandIn struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpStandIn{listener: listener, messages: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprint(conn, "220 stand-in ESMTP\r\n")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			fmt.Fprint(conn, "250-stand-in\r\n250 8BITMIME\r\n")
+		case command == "DATA":
+			fmt.Fprint(conn, "354 go ahead\r\n")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.messages <- data.String()
+			fmt.Fprint(conn, "250 queued\r\n")
+		case command == "QUIT":
+			fmt.Fprint(conn, "221 bye\r\n")
+			return
+		default:
+			fmt.Fprint(conn, "250 ok\r\n")
+		}
+	}
+}
+
+var ada = Notification{UserID: 7, Name: "Ada", DOB: "1990-05-17", Birthday: "2026-05-17", Turns: 36, Timezone: "UTC"}
+
+func TestSMTPNotifierSends(t *testing.T) {
+	server := newSMTPStandIn(t)
+	notifier := NewSMTPNotifier(SMTPOptions{
+		Addr:    server.listener.Addr().String(),
+		From:    "birthdays@example.com",
+		To:      []string{"team@example.com"},
+		Timeout: time.Second,
+	})
+
+	if err := notifier.Notify(context.Background(), ada); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-server.messages:
+		for _, want := range []string{
+			"To: team@example.com\r\n",
+			"Subject: Ada turns 36 today\r\n",
+			"Message-ID: <birthday-7-2026-05-17@user-profile-api>\r\n",
+		} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message is missing %q:\n%s", want, msg)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message was sent")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got Notification
+	var id string
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = r.Header.Get("X-Birthday-ID")
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, time.Second)
+	if err := notifier.Notify(context.Background(), ada); err != nil {
+		t.Fatal(err)
+	}
+	if got != ada || id != "7-2026-05-17" {
+		t.Errorf("webhook received %+v with ID %q", got, id)
+	}
+
+	status = http.StatusInternalServerError
+	if err := notifier.Notify(context.Background(), ada); err == nil {
+		t.Error("expected an error for a failed webhook")
+	}
+}
+
+func TestDue(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	losAngeles, _ := time.LoadLocation("America/Los_Angeles")
+	date := func(s string) time.Time { d, _ := models.ParseDate(s); return d }
+	sendAt := 9 * time.Hour
+
+	tests := []struct {
+		name   string
+		dob    string
+		now    time.Time
+		policy models.LeapDayPolicy
+		want   string
+	}{
+		{"before send time", "1990-05-17", time.Date(2026, 5, 17, 8, 59, 0, 0, time.UTC), models.LeapDayMar1, ""},
+		{"at send time", "1990-05-17", time.Date(2026, 5, 17, 9, 0, 0, 0, time.UTC), models.LeapDayMar1, "2026-05-17"},
+		{"ahead of UTC", "1990-05-17", time.Date(2026, 5, 17, 0, 30, 0, 0, time.UTC).In(tokyo), models.LeapDayMar1, "2026-05-17"},
+		{"behind UTC", "1990-05-17", time.Date(2026, 5, 18, 2, 0, 0, 0, time.UTC).In(losAngeles), models.LeapDayMar1, "2026-05-17"},
+		{"another day", "1990-05-17", time.Date(2026, 5, 18, 10, 0, 0, 0, time.UTC), models.LeapDayMar1, ""},
+		{"leap day on March 1", "2000-02-29", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), models.LeapDayMar1, "2026-03-01"},
+		{"leap day not on February 28", "2000-02-29", time.Date(2026, 2, 28, 10, 0, 0, 0, time.UTC), models.LeapDayMar1, ""},
+		{"leap day on February 28", "2000-02-29", time.Date(2026, 2, 28, 10, 0, 0, 0, time.UTC), models.LeapDayFeb28, "2026-02-28"},
+		{"leap day in a leap year", "2000-02-29", time.Date(2028, 2, 29, 10, 0, 0, 0, time.UTC), models.LeapDayFeb28, "2028-02-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			birthday, ok := due(date(tt.dob), tt.now, sendAt, tt.policy)
+			got := ""
+			if ok {
+				got = models.FormatDate(birthday)
+			}
+			if got != tt.want {
+				t.Errorf("due(%s, %s) = %q, want %q", tt.dob, tt.now, got, tt.want)
+			}
+		})
+	}
+}
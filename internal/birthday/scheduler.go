@@ -0,0 +1,322 @@
+// Package birthday announces users' birthdays in the background.
+//
+// The Scheduler wakes up every interval and, for each user, works out the
+// current date in the user's time zone. Once the local time passes the
+// configured send time on their birthday, each notifier is claimed in the
+// birthday_notifications table and then run, so a day's birthdays are
+// announced once per time zone and never twice across restarts.
+package birthday
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"time"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// schedulerLockID is the session-level advisory lock that makes a single
+// instance send notifications at a time
+const schedulerLockID = 0x6269727468646179 // "birthday"
+
+// monthDay matches the expression of idx_users_birthday
+const monthDay = `(EXTRACT(MONTH FROM dob)::int * 100 + EXTRACT(DAY FROM dob)::int)`
+
+// schedulerMetrics is published under "birthdays" at /admin/metrics
+var schedulerMetrics = expvar.NewMap("birthdays")
+
+// Scheduler sends birthday notifications through its notifiers
+type Scheduler struct {
+	pool      pool
+	notifiers []Notifier
+	logger    *zap.Logger
+	interval  time.Duration
+	sendAt    time.Duration
+	location  *time.Location
+	leapDay   models.LeapDayPolicy
+	clock     service.Clock
+
+	// zones caches the time zones of users, by name
+	zones map[string]*time.Location
+}
+
+// Options configures a Scheduler
+type Options struct {
+	// Interval is how often due birthdays are checked
+	Interval time.Duration
+	// SendAt is the time after local midnight from which a day's birthdays
+	// are announced
+	SendAt time.Duration
+	// Location applies to users without a time zone of their own
+	Location *time.Location
+	// LeapDay is when February 29 birthdays are observed in common years
+	LeapDay models.LeapDayPolicy
+	// Clock tells the time of each check; nil uses service.SystemClock
+	Clock service.Clock
+}
+
+// NewScheduler creates a scheduler reading users through pool
+func NewScheduler(pool *pgxpool.Pool, notifiers []Notifier, opts Options, logger *zap.Logger) *Scheduler {
+	if opts.Clock == nil {
+		opts.Clock = service.SystemClock
+	}
+	return &Scheduler{
+		pool:      pgxPool{pool},
+		notifiers: notifiers,
+		logger:    logger,
+		interval:  opts.Interval,
+		sendAt:    opts.SendAt,
+		location:  opts.Location,
+		leapDay:   opts.LeapDay,
+		clock:     opts.Clock,
+		zones:     make(map[string]*time.Location),
+	}
+}
+
+// pool hands out the connection a tick runs on; tests substitute a fake
+// for *pgxpool.Pool
+type pool interface {
+	acquire(ctx context.Context) (conn, error)
+}
+
+// conn is a dedicated database connection
+type conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	// release returns the connection to its pool. A broken connection is
+	// closed instead, which ends its session and the locks it holds.
+	release(broken bool)
+}
+
+// pgxPool adapts *pgxpool.Pool to pool
+type pgxPool struct {
+	*pgxpool.Pool
+}
+
+func (p pgxPool) acquire(ctx context.Context) (conn, error) {
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pgxConn{c}, nil
+}
+
+// pgxConn adapts *pgxpool.Conn to conn
+type pgxConn struct {
+	*pgxpool.Conn
+}
+
+func (c pgxConn) release(broken bool) {
+	if broken {
+		c.Conn.Conn().Close(context.Background())
+	}
+	c.Release()
+}
+
+// Run sends notifications until ctx is done
+func (s *Scheduler) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := s.tick(ctx, s.clock.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Warn("birthday scheduler failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// candidate is a user who may have a birthday somewhere today
+type candidate struct {
+	id       int32
+	name     string
+	dob      time.Time
+	timezone string
+}
+
+// sentKey identifies a claimed notification
+type sentKey struct {
+	userID   int32
+	birthday string
+	notifier string
+}
+
+// tick sends the notifications that are due at now. It does nothing while
+// another instance holds the scheduler lock.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) error {
+	conn, err := s.pool.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	broken := false
+	defer func() { conn.release(broken) }()
+
+	// A session lock on a dedicated connection keeps other instances out
+	// without holding a transaction open while the notifiers send
+	var leader bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerLockID).Scan(&leader); err != nil {
+		return err
+	}
+	if !leader {
+		return nil
+	}
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, schedulerLockID); err != nil {
+			s.logger.Warn("failed to release birthday scheduler lock", zap.Error(err))
+			broken = true
+		}
+	}()
+
+	// Local dates lie within a day of the UTC date. February 29 birthdays
+	// are always fetched, since they may be observed on another day.
+	utc := now.UTC()
+	monthDays := []int{229}
+	for _, day := range []time.Time{utc.AddDate(0, 0, -1), utc, utc.AddDate(0, 0, 1)} {
+		monthDays = append(monthDays, int(day.Month())*100+day.Day())
+	}
+
+	rows, err := conn.Query(ctx, `SELECT id, name, dob, COALESCE(timezone, '') FROM users WHERE `+monthDay+` = ANY($1)`, monthDays)
+	if err != nil {
+		return fmt.Errorf("failed to find birthdays: %w", err)
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.name, &c.dob, &c.timezone); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan user: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to find birthdays: %w", err)
+	}
+
+	sent, err := s.sent(ctx, conn, utc.AddDate(0, 0, -2))
+	if err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		location := s.zone(c.timezone)
+		birthday, ok := due(c.dob, now.In(location), s.sendAt, s.leapDay)
+		if !ok {
+			continue
+		}
+
+		n := Notification{
+			UserID:   c.id,
+			Name:     c.name,
+			DOB:      models.FormatDate(c.dob),
+			Birthday: models.FormatDate(birthday),
+			Turns:    birthday.Year() - c.dob.Year(),
+			Timezone: location.String(),
+		}
+		for _, notifier := range s.notifiers {
+			if sent[sentKey{c.id, n.Birthday, notifier.Name()}] {
+				continue
+			}
+			if err := s.notify(ctx, conn, notifier, n, birthday); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sent returns the notifications claimed for birthdays since the given date
+func (s *Scheduler) sent(ctx context.Context, conn conn, since time.Time) (map[sentKey]bool, error) {
+	rows, err := conn.Query(ctx, `SELECT user_id, birthday, notifier FROM birthday_notifications WHERE birthday >= $1`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sent notifications: %w", err)
+	}
+	defer rows.Close()
+
+	sent := make(map[sentKey]bool)
+	for rows.Next() {
+		var key sentKey
+		var birthday time.Time
+		if err := rows.Scan(&key.userID, &birthday, &key.notifier); err != nil {
+			return nil, err
+		}
+		key.birthday = models.FormatDate(birthday)
+		sent[key] = true
+	}
+	return sent, rows.Err()
+}
+
+// notify claims a notification and sends it. The claim is stored first, so
+// a crash while sending skips the notification rather than repeating it; a
+// failed send releases the claim so the next tick retries. Only database
+// errors are returned.
+func (s *Scheduler) notify(ctx context.Context, conn conn, notifier Notifier, n Notification, birthday time.Time) error {
+	result, err := conn.Exec(ctx, `INSERT INTO birthday_notifications (user_id, birthday, notifier)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, n.UserID, birthday, notifier.Name())
+	if err != nil {
+		return fmt.Errorf("failed to claim birthday notification: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	log := s.logger.With(zap.Int32("user_id", n.UserID), zap.String("birthday", n.Birthday), zap.String("notifier", notifier.Name()))
+	if notifyErr := notifier.Notify(ctx, n); notifyErr != nil {
+		schedulerMetrics.Add("failures", 1)
+		log.Warn("birthday notification failed", zap.Error(notifyErr))
+		// The claim is released even when the send failed because ctx
+		// was canceled
+		_, err := conn.Exec(context.WithoutCancel(ctx), `DELETE FROM birthday_notifications WHERE user_id = $1 AND birthday = $2 AND notifier = $3`,
+			n.UserID, birthday, notifier.Name())
+		if err != nil {
+			return fmt.Errorf("failed to release birthday notification: %w", err)
+		}
+		return nil
+	}
+
+	schedulerMetrics.Add("sent", 1)
+	log.Debug("birthday notification sent")
+	return nil
+}
+
+// zone returns the time zone named name, falling back to the default for
+// users without a valid one
+func (s *Scheduler) zone(name string) *time.Location {
+	if name == "" {
+		return s.location
+	}
+	if location, ok := s.zones[name]; ok {
+		return location
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		s.logger.Warn("invalid user time zone; using the default", zap.String("timezone", name), zap.Error(err))
+		location = s.location
+	}
+	s.zones[name] = location
+	return location
+}
+
+// due reports whether a birthday is to be announced at local, the current
+// time in the user's time zone, and returns its date
+func due(dob, local time.Time, sendAt time.Duration, policy models.LeapDayPolicy) (time.Time, bool) {
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	birthday := policy.Birthday(dob, today.Year())
+	if !birthday.Equal(today) {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return birthday, local.Sub(midnight) >= sendAt
+}
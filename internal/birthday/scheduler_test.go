@@ -0,0 +1,285 @@
+package birthday
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// fakeDB is a pool of one connection over users and birthday_notifications
+// held in memory
+type fakeDB struct {
+	users []candidate
+	// claims are the rows of birthday_notifications
+	claims map[sentKey]bool
+	// lockedElsewhere makes another instance hold the scheduler lock
+	lockedElsewhere bool
+
+	locked   bool
+	released int
+	broken   bool
+}
+
+func newFakeDB(users ...candidate) *fakeDB {
+	return &fakeDB{users: users, claims: make(map[sentKey]bool)}
+}
+
+func (db *fakeDB) acquire(ctx context.Context) (conn, error) {
+	return db, nil
+}
+
+func (db *fakeDB) release(broken bool) {
+	db.released++
+	db.broken = db.broken || broken
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	// Statements run on a canceled context fail, as they would on a pool
+	if err := ctx.Err(); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	switch {
+	case strings.HasPrefix(sql, "SELECT pg_advisory_unlock"):
+		db.locked = false
+		return pgconn.NewCommandTag("SELECT 1"), nil
+	case strings.HasPrefix(sql, "INSERT INTO birthday_notifications"):
+		key := db.key(args)
+		if db.claims[key] {
+			return pgconn.NewCommandTag("INSERT 0 0"), nil
+		}
+		db.claims[key] = true
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case strings.HasPrefix(sql, "DELETE FROM birthday_notifications"):
+		delete(db.claims, db.key(args))
+		return pgconn.NewCommandTag("DELETE 1"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected statement: " + sql)
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows [][]any
+	switch {
+	case strings.HasPrefix(sql, "SELECT id, name, dob"):
+		for _, u := range db.users {
+			rows = append(rows, []any{u.id, u.name, u.dob, u.timezone})
+		}
+	case strings.HasPrefix(sql, "SELECT user_id, birthday, notifier"):
+		for key := range db.claims {
+			birthday, _ := models.ParseDate(key.birthday)
+			rows = append(rows, []any{key.userID, birthday, key.notifier})
+		}
+	default:
+		return nil, errors.New("unexpected query: " + sql)
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if !strings.HasPrefix(sql, "SELECT pg_try_advisory_lock") {
+		return &fakeRows{err: errors.New("unexpected query: " + sql)}
+	}
+	leader := !db.lockedElsewhere && !db.locked
+	db.locked = db.locked || leader
+	return &fakeRows{rows: [][]any{{leader}}}
+}
+
+// key returns the notification named by the arguments of a claim or release
+func (db *fakeDB) key(args []any) sentKey {
+	return sentKey{args[0].(int32), models.FormatDate(args[1].(time.Time)), args[2].(string)}
+}
+
+// fakeRows returns rows held in memory
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+	err  error
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.err == nil && r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.next == 0 {
+		r.next = 1
+	}
+	for i, value := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Close() {}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+// recordingNotifier records what it sends and fails while err is set
+type recordingNotifier struct {
+	name string
+	err  error
+	sent []Notification
+}
+
+func (n *recordingNotifier) Name() string {
+	return n.name
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func newTestScheduler(db *fakeDB, notifiers ...Notifier) *Scheduler {
+	return &Scheduler{
+		pool:      db,
+		notifiers: notifiers,
+		logger:    zap.NewNop(),
+		sendAt:    9 * time.Hour,
+		location:  time.UTC,
+		leapDay:   models.LeapDayMar1,
+		clock:     service.SystemClock,
+		zones:     make(map[string]*time.Location),
+	}
+}
+
+func TestTick(t *testing.T) {
+	now := time.Date(2026, 5, 17, 10, 0, 0, 0, time.UTC)
+	db := newFakeDB(
+		candidate{id: 7, name: "Ada", dob: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)},
+		// It is still May 17 in Tokyo
+		candidate{id: 8, name: "Bob", dob: time.Date(1990, 5, 18, 0, 0, 0, 0, time.UTC), timezone: "Asia/Tokyo"},
+	)
+	email := &recordingNotifier{name: "email"}
+	webhook := &recordingNotifier{name: "webhook", err: errors.New("unreachable")}
+	s := newTestScheduler(db, email, webhook)
+
+	if err := s.tick(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if len(email.sent) != 1 || email.sent[0] != ada {
+		t.Errorf("email sent %+v; want only %+v", email.sent, ada)
+	}
+	if !db.claims[sentKey{7, "2026-05-17", "email"}] || len(db.claims) != 1 {
+		t.Errorf("claims = %v; want the email to Ada only, the failed webhook released", db.claims)
+	}
+	if db.locked || db.released != 1 || db.broken {
+		t.Errorf("locked = %v, released = %d, broken = %v; want the lock released and the connection returned", db.locked, db.released, db.broken)
+	}
+
+	// The next tick retries the webhook but does not repeat the email
+	webhook.err = nil
+	if err := s.tick(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(email.sent) != 1 || len(webhook.sent) != 1 {
+		t.Errorf("sent %d emails and %d webhooks; want 1 of each", len(email.sent), len(webhook.sent))
+	}
+}
+
+func TestRunUsesClock(t *testing.T) {
+	db := newFakeDB(candidate{id: 7, name: "Ada", dob: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)})
+	ctx, cancel := context.WithCancel(context.Background())
+	email := &recordingNotifier{name: "email"}
+	// Stop after the first notification
+	s := newTestScheduler(db, &stoppingNotifier{email, cancel})
+	s.interval = time.Hour
+	s.clock = service.ClockFunc(func() time.Time { return time.Date(2026, 5, 17, 10, 0, 0, 0, time.UTC) })
+
+	s.Run(ctx)
+	if len(email.sent) != 1 || email.sent[0] != ada {
+		t.Errorf("sent %+v; want Ada's birthday on the clock's date", email.sent)
+	}
+}
+
+// stoppingNotifier cancels the scheduler after each notification
+type stoppingNotifier struct {
+	*recordingNotifier
+	cancel context.CancelFunc
+}
+
+func (n *stoppingNotifier) Notify(ctx context.Context, notification Notification) error {
+	defer n.cancel()
+	return n.recordingNotifier.Notify(ctx, notification)
+}
+
+func TestTickWithoutLock(t *testing.T) {
+	db := newFakeDB(candidate{id: 7, name: "Ada", dob: time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)})
+	db.lockedElsewhere = true
+	email := &recordingNotifier{name: "email"}
+
+	if err := newTestScheduler(db, email).tick(context.Background(), time.Date(2026, 5, 17, 10, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(email.sent) != 0 || len(db.claims) != 0 {
+		t.Errorf("sent %v and claimed %v while another instance held the lock", email.sent, db.claims)
+	}
+	if db.released != 1 {
+		t.Errorf("released the connection %d times; want 1", db.released)
+	}
+}
+
+func TestNotifyClaims(t *testing.T) {
+	birthday := time.Date(2026, 5, 17, 0, 0, 0, 0, time.UTC)
+
+	t.Run("claimed elsewhere", func(t *testing.T) {
+		// Another instance claimed it after this one loaded the sent list
+		db := newFakeDB()
+		db.claims[sentKey{7, "2026-05-17", "email"}] = true
+		email := &recordingNotifier{name: "email"}
+
+		if err := newTestScheduler(db).notify(context.Background(), db, email, ada, birthday); err != nil {
+			t.Fatal(err)
+		}
+		if len(email.sent) != 0 {
+			t.Errorf("sent a notification that was already claimed")
+		}
+	})
+
+	t.Run("canceled send", func(t *testing.T) {
+		db := newFakeDB()
+		ctx, cancel := context.WithCancel(context.Background())
+		notifier := &cancelingNotifier{cancel: cancel}
+
+		if err := newTestScheduler(db).notify(ctx, db, notifier, ada, birthday); err != nil {
+			t.Fatal(err)
+		}
+		if len(db.claims) != 0 {
+			t.Errorf("claims = %v; want the claim of the canceled send released", db.claims)
+		}
+	})
+}
+
+// cancelingNotifier fails because its context is canceled while it sends,
+// as on shutdown
+type cancelingNotifier struct {
+	cancel context.CancelFunc
+}
+
+func (n *cancelingNotifier) Name() string {
+	return "email"
+}
+
+func (n *cancelingNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.cancel()
+	return ctx.Err()
+}
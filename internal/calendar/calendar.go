@@ -0,0 +1,115 @@
+// Package calendar writes birthdays as iCalendar (RFC 5545) and contacts as
+// vCard (RFC 6350) documents. Both formats share the content line syntax
+// implemented here: CRLF line endings, lines folded at 75 octets and
+// escaped text values.
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ProductID identifies the API in the calendars it writes
+const ProductID = "-//user-profile-api//Birthdays//EN"
+
+// Event is a yearly all-day event, such as a birthday
+type Event struct {
+	UID     string
+	Summary string
+	// Date is the first occurrence
+	Date time.Time
+	// RRule is the recurrence rule, without the RRULE: prefix
+	RRule string
+}
+
+// EncodeCalendar writes events as an iCalendar named name. stamp is the
+// DTSTAMP of every event, normally the current time.
+func EncodeCalendar(name string, events []Event, stamp time.Time) []byte {
+	var w writer
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", ProductID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", escape(name))
+	for _, e := range events {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", escape(e.UID))
+		w.line("DTSTAMP", stamp.UTC().Format("20060102T150405Z"))
+		// A date without DTEND lasts the whole day
+		w.line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		if e.RRule != "" {
+			w.line("RRULE", e.RRule)
+		}
+		w.line("SUMMARY", escape(e.Summary))
+		w.line("TRANSP", "TRANSPARENT")
+		w.line("END", "VEVENT")
+	}
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
+
+// Card is a contact
+type Card struct {
+	// UID is a URI identifying the contact
+	UID  string
+	Name string
+	// Birthday is left out when zero
+	Birthday time.Time
+}
+
+// EncodeCards writes cards as consecutive version 4.0 vCards
+func EncodeCards(cards []Card) []byte {
+	var w writer
+	for _, c := range cards {
+		w.line("BEGIN", "VCARD")
+		w.line("VERSION", "4.0")
+		if c.UID != "" {
+			w.line("UID", c.UID)
+		}
+		w.line("FN", escape(c.Name))
+		if !c.Birthday.IsZero() {
+			w.line("BDAY", c.Birthday.Format("20060102"))
+		}
+		w.line("END", "VCARD")
+	}
+	return w.buf.Bytes()
+}
+
+// maxLine is the longest a content line may be, in octets, before folding
+const maxLine = 75
+
+// writer writes content lines
+type writer struct {
+	buf bytes.Buffer
+}
+
+// line writes name:value, folding it onto continuation lines that start
+// with a space. Folds never split a UTF-8 sequence.
+func (w *writer) line(name, value string) {
+	line := name + ":" + value
+	limit := maxLine
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.buf.WriteString(line[:cut])
+		w.buf.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the continuation line
+		limit = maxLine - 1
+	}
+	w.buf.WriteString(line)
+	w.buf.WriteString("\r\n")
+}
+
+// textEscaper escapes the characters that are special in TEXT values
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escape returns s as a TEXT value
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
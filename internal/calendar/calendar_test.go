@@ -0,0 +1,70 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeCalendar(t *testing.T) {
+	stamp := time.Date(2026, 5, 1, 12, 30, 0, 0, time.UTC)
+	got := string(EncodeCalendar("Birthdays", []Event{{
+		UID:     "user-7-birthday@user-profile-api",
+		Summary: "Smith, Ada's birthday",
+		Date:    time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC),
+		RRule:   "FREQ=YEARLY;BYYEARDAY=60",
+	}}, stamp))
+
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//user-profile-api//Birthdays//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"METHOD:PUBLISH\r\n" +
+		"X-WR-CALNAME:Birthdays\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:user-7-birthday@user-profile-api\r\n" +
+		"DTSTAMP:20260501T123000Z\r\n" +
+		"DTSTART;VALUE=DATE:20000229\r\n" +
+		"RRULE:FREQ=YEARLY;BYYEARDAY=60\r\n" +
+		"SUMMARY:Smith\\, Ada's birthday\r\n" +
+		"TRANSP:TRANSPARENT\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if got != want {
+		t.Errorf("EncodeCalendar() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncodeCards(t *testing.T) {
+	got := string(EncodeCards([]Card{
+		{UID: "urn:user-profile-api:user:7", Name: "Ada; Lovelace", Birthday: time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC)},
+		{Name: "Nameless\nLine"},
+	}))
+
+	want := "BEGIN:VCARD\r\nVERSION:4.0\r\nUID:urn:user-profile-api:user:7\r\nFN:Ada\\; Lovelace\r\nBDAY:18151210\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Nameless\\nLine\r\nEND:VCARD\r\n"
+	if got != want {
+		t.Errorf("EncodeCards() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFolding(t *testing.T) {
+	var w writer
+	w.line("FN", strings.Repeat("é", 60))
+
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\r\n"), "\r\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), lines)
+	}
+	for _, line := range lines {
+		if len(line) > maxLine {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	if !strings.HasPrefix(lines[1], " ") {
+		t.Errorf("continuation line does not start with a space: %q", lines[1])
+	}
+	if unfolded := lines[0] + lines[1][1:]; unfolded != "FN:"+strings.Repeat("é", 60) {
+		t.Errorf("unfolded line = %q", unfolded)
+	}
+}
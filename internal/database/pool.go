@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"user-profile-api/config"
+	"user-profile-api/internal/tracing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Connect creates a tuned connection pool and waits until the database
+// answers, retrying with exponential backoff
+func Connect(ctx context.Context, cfg config.DatabaseConfig, applicationName string, logger *zap.Logger) (*pgxpool.Pool, error) {
+	poolConfig, err := NewPoolConfig(cfg, applicationName)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	if err := pingWithRetry(ctx, pool, cfg, logger); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// OpenReplicas creates a pool for each read replica. Replicas are not pinged:
+// an unreachable replica is simply kept out of rotation by its health checks.
+func OpenReplicas(ctx context.Context, cfg config.DatabaseConfig, applicationName string) ([]*pgxpool.Pool, error) {
+	var pools []*pgxpool.Pool
+	for i, url := range cfg.ReplicaURLs {
+		replicaCfg := cfg
+		replicaCfg.URL = url
+
+		poolConfig, err := NewPoolConfig(replicaCfg, applicationName)
+		if err != nil {
+			closeAll(pools)
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+		if err != nil {
+			closeAll(pools)
+			return nil, fmt.Errorf("replica %d: failed to create connection pool: %w", i, err)
+		}
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
+}
+
+func closeAll(pools []*pgxpool.Pool) {
+	for _, pool := range pools {
+		pool.Close()
+	}
+}
+
+// NewPoolConfig builds the pgx pool configuration from the database settings
+func NewPoolConfig(cfg config.DatabaseConfig, applicationName string) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.MinConns = int32(cfg.MinConns)
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	poolConfig.ConnConfig.Tracer = tracing.NewPgxTracer()
+
+	// Query comments make every statement text unique, which would fill the
+	// statement cache with single-use entries; send unnamed statements instead
+	if cfg.QueryComments {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeExec
+	}
+
+	// Identify our sessions in pg_stat_activity unless the URL already does
+	params := poolConfig.ConnConfig.RuntimeParams
+	if _, ok := params["application_name"]; !ok {
+		params["application_name"] = applicationName
+	}
+
+	// Let the server abort runaway statements
+	if cfg.StatementTimeout > 0 {
+		params["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	if len(cfg.SessionSettings) > 0 {
+		settings := cfg.SessionSettings
+		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+			for name, value := range settings {
+				if _, err := conn.Exec(ctx, "SELECT set_config($1, $2, false)", name, value); err != nil {
+					return fmt.Errorf("failed to apply session setting %s: %w", name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return poolConfig, nil
+}
+
+// pingWithRetry pings the database until it responds or the retries are exhausted
+func pingWithRetry(ctx context.Context, pool *pgxpool.Pool, cfg config.DatabaseConfig, logger *zap.Logger) error {
+	backoff := cfg.ConnectRetryBackoff
+
+	for attempt := 0; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+		err := pool.Ping(pingCtx)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("failed to ping database after %d attempts: %w", attempt+1, err)
+		}
+
+		logger.Warn("database not reachable, retrying",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > cfg.ConnectRetryMaxBackoff {
+			backoff = cfg.ConnectRetryMaxBackoff
+		}
+	}
+}
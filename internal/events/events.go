@@ -0,0 +1,59 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Channel is the LISTEN/NOTIFY channel on which every recorded event is
+// published as JSON when its transaction commits
+const Channel = "user_events"
+
+// User lifecycle event types
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+)
+
+// Types lists every event type
+var Types = []string{UserCreated, UserUpdated, UserDeleted}
+
+// Event is a domain event recorded in the outbox. ID increases with every
+// event, so consumers can use it to deduplicate and to order events.
+type Event struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	UserID     int32     `json:"user_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Data       Change    `json:"data"`
+}
+
+// Change holds the user before and after the event. Before is nil for
+// user.created and After is nil for user.deleted.
+type Change struct {
+	Before *User `json:"before,omitempty"`
+	After  *User `json:"after,omitempty"`
+}
+
+// User is the user snapshot carried by events
+type User struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	DOB  string `json:"dob"`
+	// Timezone is absent for users without one
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Marshal encodes the event as JSON
+func (e Event) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+// Unmarshal decodes an event encoded with Marshal
+func Unmarshal(data []byte) (Event, error) {
+	var event Event
+	err := json.Unmarshal(data, &event)
+	return event, err
+}
@@ -0,0 +1,191 @@
+package eventstream
+
+import (
+	"context"
+	"expvar"
+	"sync"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/pgnotify"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// subscriberBuffer is how many messages a subscriber may fall behind before
+// it is dropped; it can then reconnect and resume with Last-Event-ID
+const subscriberBuffer = 64
+
+// streamMetrics is published under "event_stream" at /admin/metrics
+var streamMetrics = expvar.NewMap("event_stream")
+
+// Message is delivered to subscribers. Reset means events may have been
+// missed and the client should refetch its state.
+type Message struct {
+	Event events.Event
+	Reset bool
+}
+
+// Filter selects the events a subscriber receives; nil accepts all
+type Filter func(events.Event) bool
+
+// Broker fans events received over LISTEN/NOTIFY out to subscribers and
+// keeps the most recent ones so clients can resume after a reconnect.
+// Every instance listens, so clients see all changes whichever instance
+// they are connected to.
+type Broker struct {
+	mu          sync.Mutex
+	buffer      []events.Event
+	size        int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+	logger      *zap.Logger
+}
+
+// Subscription receives messages on C until it is closed. C is closed when
+// the subscriber falls too far behind or the broker shuts down.
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	filter Filter
+	broker *Broker
+}
+
+// NewBroker creates a broker remembering the last size events
+func NewBroker(size int, logger *zap.Logger) *Broker {
+	return &Broker{
+		size:        size,
+		subscribers: make(map[*Subscription]struct{}),
+		logger:      logger,
+	}
+}
+
+// Start listens for events through pool until ctx is done, then closes the
+// broker
+func (b *Broker) Start(ctx context.Context, pool *pgxpool.Pool) {
+	listener := pgnotify.NewListener(pool, b.logger)
+	go func() {
+		listener.Listen(ctx, events.Channel, b.publishPayload, b.reset)
+		b.Close()
+	}()
+}
+
+// Subscribe registers a subscriber and returns the buffered events it
+// should replay first. With resume set, replay holds the events received
+// after lastEventID; if that event is no longer buffered, everything
+// buffered is replayed and gap is true.
+func (b *Broker) Subscribe(lastEventID int64, resume bool, filter Filter) (sub *Subscription, replay []events.Event, gap bool) {
+	ch := make(chan Message, subscriberBuffer)
+	sub = &Subscription{C: ch, ch: ch, filter: filter, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return sub, nil, false
+	}
+	b.subscribers[sub] = struct{}{}
+	streamMetrics.Add("subscribers", 1)
+
+	if !resume {
+		return sub, nil, false
+	}
+
+	// Events are buffered in commit order, which may differ from ID order,
+	// so resume from the position of the last seen event
+	start, gap := 0, true
+	for i := len(b.buffer) - 1; i >= 0; i-- {
+		if b.buffer[i].ID == lastEventID {
+			start, gap = i+1, false
+			break
+		}
+	}
+	for _, event := range b.buffer[start:] {
+		if sub.accepts(event) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, gap
+}
+
+// Close unsubscribes
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.removeLocked(s)
+}
+
+func (s *Subscription) accepts(event events.Event) bool {
+	return s.filter == nil || s.filter(event)
+}
+
+// Close disconnects every subscriber and stops accepting new ones
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.removeLocked(sub)
+	}
+}
+
+// publishPayload handles a notification carrying an encoded event
+func (b *Broker) publishPayload(payload string) {
+	event, err := events.Unmarshal([]byte(payload))
+	if err != nil {
+		b.logger.Warn("invalid event notification", zap.Error(err))
+		return
+	}
+	b.publish(event)
+}
+
+// publish buffers event and sends it to matching subscribers
+func (b *Broker) publish(event events.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = append(b.buffer, event)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for sub := range b.subscribers {
+		if sub.accepts(event) {
+			b.sendLocked(sub, Message{Event: event})
+		}
+	}
+}
+
+// reset runs after the listener reconnects. Notifications sent while it was
+// disconnected are lost, so the buffer can no longer resume anyone.
+func (b *Broker) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = nil
+	for sub := range b.subscribers {
+		b.sendLocked(sub, Message{Reset: true})
+	}
+}
+
+// sendLocked delivers msg without blocking, dropping subscribers that are
+// too far behind
+func (b *Broker) sendLocked(sub *Subscription, msg Message) {
+	select {
+	case sub.ch <- msg:
+	default:
+		streamMetrics.Add("dropped_subscribers", 1)
+		b.removeLocked(sub)
+	}
+}
+
+func (b *Broker) removeLocked(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+	streamMetrics.Add("subscribers", -1)
+}
@@ -0,0 +1,73 @@
+package eventstream
+
+import (
+	"testing"
+
+	"user-profile-api/internal/events"
+
+	"go.uber.org/zap"
+)
+
+func publishIDs(b *Broker, ids ...int64) {
+	for _, id := range ids {
+		b.publish(events.Event{ID: id, UserID: int32(id % 2)})
+	}
+}
+
+func eventIDs(evts []events.Event) []int64 {
+	ids := make([]int64, len(evts))
+	for i, e := range evts {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+func TestBrokerResumesAfterLastEventID(t *testing.T) {
+	b := NewBroker(3, zap.NewNop())
+	// Commit order differs from ID order
+	publishIDs(b, 1, 3, 2, 4)
+
+	sub, replay, gap := b.Subscribe(3, true, nil)
+	defer sub.Close()
+
+	if gap {
+		t.Error("gap = true; want false")
+	}
+	if got := eventIDs(replay); len(got) != 2 || got[0] != 2 || got[1] != 4 {
+		t.Errorf("replay = %v; want [2 4]", got)
+	}
+}
+
+func TestBrokerReportsGap(t *testing.T) {
+	b := NewBroker(2, zap.NewNop())
+	publishIDs(b, 1, 2, 3)
+
+	sub, replay, gap := b.Subscribe(1, true, nil)
+	defer sub.Close()
+
+	if !gap {
+		t.Error("gap = false; want true for an evicted event")
+	}
+	if got := eventIDs(replay); len(got) != 2 {
+		t.Errorf("replay = %v; want whole buffer", got)
+	}
+}
+
+func TestBrokerFiltersAndDropsSlowSubscribers(t *testing.T) {
+	b := NewBroker(10, zap.NewNop())
+	sub, _, _ := b.Subscribe(0, false, func(e events.Event) bool { return e.UserID == 1 })
+
+	publishIDs(b, 2, 3)
+	if msg := <-sub.C; msg.Event.ID != 3 {
+		t.Errorf("received event %d; want 3", msg.Event.ID)
+	}
+
+	for i := int64(0); i <= subscriberBuffer; i++ {
+		b.publish(events.Event{ID: 100 + i, UserID: 1})
+	}
+	for range sub.C {
+	}
+	if len(b.subscribers) != 0 {
+		t.Error("slow subscriber was not dropped")
+	}
+}
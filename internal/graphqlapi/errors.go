@@ -0,0 +1,56 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/service"
+
+	"go.uber.org/zap"
+)
+
+// Error codes reported under "code" in the extensions of GraphQL errors
+const (
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeNotFound         = "NOT_FOUND"
+	CodeQueryTooComplex  = "QUERY_TOO_COMPLEX"
+	CodeDeadlineExceeded = "DEADLINE_EXCEEDED"
+	CodeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+// codedError is a GraphQL error carrying a machine-readable code
+type codedError struct {
+	message string
+	code    string
+}
+
+func (e *codedError) Error() string {
+	return e.message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *codedError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// badInput reports invalid arguments
+func badInput(message string) error {
+	return &codedError{message: message, code: CodeBadUserInput}
+}
+
+// resolverError maps service errors to coded GraphQL errors. Unexpected
+// errors are logged and reported without details.
+func (e *Executor) resolverError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return &codedError{message: err.Error(), code: CodeNotFound}
+	case errors.Is(err, service.ErrInvalidDate), errors.Is(err, service.ErrFutureDOB), errors.Is(err, service.ErrInvalidTimezone):
+		return badInput(err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return &codedError{message: "deadline exceeded", code: CodeDeadlineExceeded}
+	}
+
+	logger.FromContext(ctx, e.logger).Error("GraphQL resolver failed", zap.Error(err))
+	return &codedError{message: "internal server error", code: CodeInternal}
+}
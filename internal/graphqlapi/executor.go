@@ -0,0 +1,115 @@
+// Package graphqlapi exposes the user service as a GraphQL schema, with
+// per-request batching of user lookups and limits on query depth and cost.
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+
+	"user-profile-api/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.uber.org/zap"
+)
+
+// Options configures query limits
+type Options struct {
+	// MaxDepth bounds how deeply selections may nest; zero disables the check
+	MaxDepth int
+	// MaxComplexity bounds the estimated cost of an operation; zero disables
+	// the check
+	MaxComplexity int
+	// DefaultPageSize is the page size assumed when estimating the cost of a
+	// users connection requested without first
+	DefaultPageSize int
+}
+
+// Request is a GraphQL request as posted over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Executor runs GraphQL requests against the user service
+type Executor struct {
+	schema   graphql.Schema
+	users    *service.UserService
+	opts     Options
+	logger   *zap.Logger
+	validate *validator.Validate
+}
+
+// New builds the schema and an executor backed by users
+func New(users *service.UserService, opts Options, logger *zap.Logger) (*Executor, error) {
+	e := &Executor{
+		users:    users,
+		opts:     opts,
+		logger:   logger,
+		validate: validator.New(),
+	}
+
+	schema, err := e.buildSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	e.schema = schema
+
+	return e, nil
+}
+
+// Execute parses, validates, checks the limits of and runs req. mutation
+// reports whether the operation that ran was a mutation, which may have
+// written even if the result holds errors.
+func (e *Executor) Execute(ctx context.Context, req Request) (result *graphql.Result, mutation bool) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+
+	if result := graphql.ValidateDocument(&e.schema, doc, nil); !result.IsValid {
+		return &graphql.Result{Errors: result.Errors}, false
+	}
+
+	if err := e.checkLimits(doc, req.OperationName, req.Variables); err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+			Message:    err.Error(),
+			Extensions: err.Extensions(),
+		}}}, false
+	}
+
+	operation := selectOperation(doc, req.OperationName)
+	mutation = operation != nil && operation.Operation == ast.OperationTypeMutation
+
+	return graphql.Execute(graphql.ExecuteParams{
+		Schema:        e.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoader(ctx, newUserLoader(e.users)),
+	}), mutation
+}
+
+// selectOperation returns the operation of doc named operationName, or its
+// only operation when the name is empty. It returns nil if there is none,
+// which execution reports.
+func selectOperation(doc *ast.Document, operationName string) *ast.OperationDefinition {
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		def, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+			operation = def
+		}
+	}
+	return operation
+}
@@ -0,0 +1,172 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/service"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+)
+
+// memoryRepository serves users from memory and counts batch lookups
+type memoryRepository struct {
+	repository.Repository
+	users   []repository.User
+	batches int
+}
+
+func (r *memoryRepository) GetUsersByIDs(ctx context.Context, ids []int32) ([]repository.User, error) {
+	r.batches++
+	var users []repository.User
+	for _, user := range r.users {
+		for _, id := range ids {
+			if user.ID == id {
+				users = append(users, user)
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	user := repository.User{ID: int32(len(r.users) + 1), Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users = append(r.users, user)
+	return &user, nil
+}
+
+func (r *memoryRepository) SearchUsers(ctx context.Context, filter repository.UserFilter, afterID, limit int32) ([]repository.User, error) {
+	var users []repository.User
+	for _, user := range r.users {
+		if user.ID > afterID && len(users) < int(limit) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func newTestExecutor(t *testing.T, opts Options) (*Executor, *memoryRepository) {
+	t.Helper()
+
+	repo := &memoryRepository{}
+	for i, name := range []string{"Alice", "Bob", "Carol"} {
+		repo.users = append(repo.users, repository.User{ID: int32(i + 1), Name: name, DOB: time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)})
+	}
+
+	executor, err := New(service.NewUserService(repo, zap.NewNop()), opts, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return executor, repo
+}
+
+// execute runs query and returns the result as JSON
+func execute(t *testing.T, executor *Executor, query string, variables map[string]any) (*graphql.Result, string) {
+	t.Helper()
+
+	result, _ := executor.Execute(context.Background(), Request{Query: query, Variables: variables})
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, string(body)
+}
+
+func TestUserLookupsAreBatched(t *testing.T) {
+	executor, repo := newTestExecutor(t, Options{})
+
+	result, body := execute(t, executor, `{ a: user(id: 1) { name } b: user(id: 2) { name } c: user(id: 9) { name } }`, nil)
+	if result.HasErrors() {
+		t.Fatalf("errors: %s", body)
+	}
+	if want := `{"data":{"a":{"name":"Alice"},"b":{"name":"Bob"},"c":null}}`; body != want {
+		t.Errorf("result = %s; want %s", body, want)
+	}
+	if repo.batches != 1 {
+		t.Errorf("batch lookups = %d; want 1", repo.batches)
+	}
+}
+
+func TestUsersConnectionPages(t *testing.T) {
+	executor, _ := newTestExecutor(t, Options{})
+	query := `query($after: String) { users(first: 2, after: $after) { edges { node { id } } pageInfo { hasNextPage endCursor } } }`
+
+	result, body := execute(t, executor, query, nil)
+	if result.HasErrors() {
+		t.Fatalf("errors: %s", body)
+	}
+	conn := result.Data.(map[string]any)["users"].(map[string]any)
+	info := conn["pageInfo"].(map[string]any)
+	if len(conn["edges"].([]any)) != 2 || info["hasNextPage"] != true {
+		t.Fatalf("first page = %s; want 2 users and a next page", body)
+	}
+
+	result, body = execute(t, executor, query, map[string]any{"after": info["endCursor"]})
+	if !strings.Contains(body, `"edges":[{"node":{"id":3}}],"pageInfo":{"endCursor"`) || !strings.Contains(body, `"hasNextPage":false`) {
+		t.Errorf("second page = %s; want only user 3 and no next page", body)
+	}
+
+	_, body = execute(t, executor, `{ users(after: "bogus") { edges { cursor } } }`, nil)
+	if !strings.Contains(body, CodeBadUserInput) {
+		t.Errorf("invalid cursor result = %s; want %s", body, CodeBadUserInput)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	executor, _ := newTestExecutor(t, Options{MaxDepth: 3, MaxComplexity: 40, DefaultPageSize: 10})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"within limits", `{ users(first: 5) { edges { cursor } pageInfo { hasNextPage } } }`, ""},
+		{"too deep", `{ users { edges { node { id } } } }`, "query depth 4 exceeds the limit of 3"},
+		{"too complex", `{ users(first: 30) { edges { cursor } } }`, "query complexity 61 exceeds the limit of 40"},
+		{"default page size", `{ users { edges { cursor } pageInfo { hasNextPage } } }`, "query complexity 41 exceeds the limit of 40"},
+		{"fragments", `{ users(first: 5) { ...page } } fragment page on UserConnection { edges { node { id } } }`, "query depth 4 exceeds the limit of 3"},
+		{"introspection is free", `{ __schema { types { name fields { name type { name ofType { name } } } } } }`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, body := execute(t, executor, tt.query, nil)
+			if tt.wantErr == "" {
+				if result.HasErrors() {
+					t.Errorf("errors: %s", body)
+				}
+				return
+			}
+			if !strings.Contains(body, tt.wantErr) || !strings.Contains(body, CodeQueryTooComplex) {
+				t.Errorf("result = %s; want %q", body, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateUserTimezone(t *testing.T) {
+	executor, _ := newTestExecutor(t, Options{})
+
+	result, body := execute(t, executor, `mutation { createUser(input: {name: "Dan", dob: "1990-05-17", timezone: "Asia/Tokyo"}) { id timezone } }`, nil)
+	if result.HasErrors() {
+		t.Fatalf("errors: %s", body)
+	}
+	if want := `{"data":{"createUser":{"id":4,"timezone":"Asia/Tokyo"}}}`; body != want {
+		t.Errorf("result = %s; want %s", body, want)
+	}
+
+	_, body = execute(t, executor, `{ user(id: 1) { timezone } }`, nil)
+	if want := `{"data":{"user":{"timezone":null}}}`; body != want {
+		t.Errorf("result = %s; want %s", body, want)
+	}
+
+	_, body = execute(t, executor, `mutation { createUser(input: {name: "Eve", dob: "1990-05-17", timezone: "Mars/Olympus"}) { id } }`, nil)
+	if !strings.Contains(body, CodeBadUserInput) {
+		t.Errorf("unknown time zone result = %s; want %s", body, CodeBadUserInput)
+	}
+}
@@ -0,0 +1,117 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// connectionFields are list fields whose cost scales with their first argument
+var connectionFields = map[string]bool{"users": true}
+
+// checkLimits rejects operations nested deeper than MaxDepth or whose
+// estimated cost exceeds MaxComplexity. Every field costs one, and the
+// selections under a connection cost once per requested item. Introspection
+// fields are free, so tools like GraphiQL keep working.
+func (e *Executor) checkLimits(doc *ast.Document, operationName string, variables map[string]any) *codedError {
+	operation := selectOperation(doc, operationName)
+	if operation == nil {
+		// Execution reports the unknown operation
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, definition := range doc.Definitions {
+		if def, ok := definition.(*ast.FragmentDefinition); ok {
+			fragments[def.Name.Value] = def
+		}
+	}
+
+	a := &analyzer{fragments: fragments, variables: variables, defaultPageSize: e.opts.DefaultPageSize}
+	depth, cost := a.selectionSet(operation.SelectionSet)
+
+	if e.opts.MaxDepth > 0 && depth > e.opts.MaxDepth {
+		return &codedError{
+			message: fmt.Sprintf("query depth %d exceeds the limit of %d", depth, e.opts.MaxDepth),
+			code:    CodeQueryTooComplex,
+		}
+	}
+	if e.opts.MaxComplexity > 0 && cost > e.opts.MaxComplexity {
+		return &codedError{
+			message: fmt.Sprintf("query complexity %d exceeds the limit of %d", cost, e.opts.MaxComplexity),
+			code:    CodeQueryTooComplex,
+		}
+	}
+	return nil
+}
+
+// analyzer measures the depth and cost of a validated operation
+type analyzer struct {
+	fragments       map[string]*ast.FragmentDefinition
+	variables       map[string]any
+	defaultPageSize int
+}
+
+// selectionSet returns the depth and cost of set, expanding fragments
+func (a *analyzer) selectionSet(set *ast.SelectionSet) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	for _, selection := range set.Selections {
+		var d, c int
+		switch sel := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			d, c = a.selectionSet(sel.SelectionSet)
+			d++
+			if connectionFields[sel.Name.Value] {
+				c *= a.pageSize(sel)
+			}
+			c++
+		case *ast.InlineFragment:
+			d, c = a.selectionSet(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if fragment := a.fragments[sel.Name.Value]; fragment != nil {
+				d, c = a.selectionSet(fragment.SelectionSet)
+			}
+		}
+
+		depth = max(depth, d)
+		cost += c
+	}
+	return depth, cost
+}
+
+// pageSize returns the first argument of a connection field, resolving
+// variables and falling back to the default page size
+func (a *analyzer) pageSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := a.variables[value.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+	return max(a.defaultPageSize, 1)
+}
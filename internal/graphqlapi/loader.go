@@ -0,0 +1,86 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/service"
+)
+
+type loaderKey struct{}
+
+// userLoader batches the by-ID lookups of one request. Resolvers queue IDs
+// and return thunks; the executor resolves thunks only after every sibling
+// field has run, so the first thunk fetches all queued IDs in one query.
+type userLoader struct {
+	users *service.UserService
+
+	mu      sync.Mutex
+	pending []int32
+	loaded  map[int32]*models.UserResponse
+	failed  map[int32]error
+}
+
+func newUserLoader(users *service.UserService) *userLoader {
+	return &userLoader{
+		users:  users,
+		loaded: make(map[int32]*models.UserResponse),
+		failed: make(map[int32]error),
+	}
+}
+
+// withLoader attaches loader to ctx for the resolvers
+func withLoader(ctx context.Context, loader *userLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// loaderFrom returns the request's loader
+func loaderFrom(ctx context.Context) *userLoader {
+	return ctx.Value(loaderKey{}).(*userLoader)
+}
+
+// load queues id and returns a thunk yielding the user, or nil if it does
+// not exist
+func (l *userLoader) load(ctx context.Context, id int32) func() (any, error) {
+	l.mu.Lock()
+	if _, ok := l.loaded[id]; !ok {
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.flush(ctx)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if err := l.failed[id]; err != nil {
+			return nil, err
+		}
+		if user := l.loaded[id]; user != nil {
+			return user, nil
+		}
+		return nil, nil
+	}
+}
+
+// flush fetches every queued ID
+func (l *userLoader) flush(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) == 0 {
+		return
+	}
+
+	ids := l.pending
+	l.pending = nil
+
+	users, err := l.users.GetUsersByIDs(ctx, ids)
+	for _, id := range ids {
+		if err != nil {
+			l.failed[id] = err
+			continue
+		}
+		l.loaded[id] = users[id]
+	}
+}
@@ -0,0 +1,314 @@
+package graphqlapi
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/repository"
+
+	"github.com/graphql-go/graphql"
+)
+
+// cursorPrefix marks opaque connection cursors, which encode a user ID
+const cursorPrefix = "user:"
+
+// userConnection is the source of the UserConnection type
+type userConnection struct {
+	Edges    []userEdge `json:"edges"`
+	PageInfo pageInfo   `json:"pageInfo"`
+}
+
+type userEdge struct {
+	Cursor string              `json:"cursor"`
+	Node   models.UserResponse `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+// buildSchema defines the GraphQL types and wires them to the user service
+func (e *Executor) buildSchema() (graphql.Schema, error) {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "A user profile",
+		Fields: graphql.Fields{
+			"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"dob": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "Date of birth as YYYY-MM-DD",
+			},
+			"age": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Age in whole years, computed on every request",
+			},
+			"timezone": &graphql.Field{
+				Type:        graphql.String,
+				Description: "IANA time zone such as Europe/Paris; null when the user has none",
+				Resolve:     resolveTimezone,
+			},
+		},
+	})
+
+	userEdgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserEdge",
+		Fields: graphql.Fields{
+			"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"node":   &graphql.Field{Type: graphql.NewNonNull(userType)},
+		},
+	})
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"endCursor":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	userConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserConnection",
+		Fields: graphql.Fields{
+			"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userEdgeType)))},
+			"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+		},
+	})
+
+	userFilterType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserFilter",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"nameContains": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Matches names containing the string, ignoring case",
+			},
+			"bornAfter": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Exclusive lower bound on the date of birth, as YYYY-MM-DD",
+			},
+			"bornBefore": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "Exclusive upper bound on the date of birth, as YYYY-MM-DD",
+			},
+		},
+	})
+
+	userInputType := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UserInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"dob":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"timezone": &graphql.InputObjectFieldConfig{
+				Type:        graphql.String,
+				Description: "IANA time zone such as Europe/Paris; absent or null leaves the user without one",
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "Looks up a user by ID; null if there is none",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: e.resolveUser,
+			},
+			"users": &graphql.Field{
+				Type:        graphql.NewNonNull(userConnectionType),
+				Description: "Pages through users ordered by ID",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: userFilterType},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int},
+					"after":  &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: e.resolveUsers,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(userInputType)},
+				},
+				Resolve: e.resolveCreateUser,
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(userInputType)},
+				},
+				Resolve: e.resolveUpdateUser,
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: e.resolveDeleteUser,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// resolveUser batches the lookup with the request's other user(id) fields
+func (e *Executor) resolveUser(p graphql.ResolveParams) (any, error) {
+	ctx := p.Context
+	thunk := loaderFrom(ctx).load(ctx, int32(p.Args["id"].(int)))
+
+	return func() (any, error) {
+		user, err := thunk()
+		if err != nil {
+			return nil, e.resolverError(ctx, err)
+		}
+		return user, nil
+	}, nil
+}
+
+// resolveUsers returns one page of the users connection
+func (e *Executor) resolveUsers(p graphql.ResolveParams) (any, error) {
+	filter, err := parseFilter(p.Args["filter"])
+	if err != nil {
+		return nil, err
+	}
+
+	first, _ := p.Args["first"].(int)
+	if first < 0 {
+		return nil, badInput("first must not be negative")
+	}
+
+	var afterID int32
+	if after, ok := p.Args["after"].(string); ok {
+		if afterID, err = decodeCursor(after); err != nil {
+			return nil, err
+		}
+	}
+
+	users, hasMore, err := e.users.SearchUsers(p.Context, filter, int32(first), afterID)
+	if err != nil {
+		return nil, e.resolverError(p.Context, err)
+	}
+
+	conn := &userConnection{
+		Edges:    make([]userEdge, len(users)),
+		PageInfo: pageInfo{HasNextPage: hasMore},
+	}
+	for i, user := range users {
+		conn.Edges[i] = userEdge{Cursor: encodeCursor(user.ID), Node: user}
+	}
+	if len(users) > 0 {
+		conn.PageInfo.EndCursor = &conn.Edges[len(users)-1].Cursor
+	}
+	return conn, nil
+}
+
+// resolveCreateUser creates a user
+func (e *Executor) resolveCreateUser(p graphql.ResolveParams) (any, error) {
+	input := p.Args["input"].(map[string]any)
+	req := &models.CreateUserRequest{Name: input["name"].(string), DOB: input["dob"].(string)}
+	req.Timezone, _ = input["timezone"].(string)
+	if err := e.validate.Struct(req); err != nil {
+		return nil, badInput(fmt.Sprintf("validation failed: %v", err))
+	}
+
+	user, err := e.users.CreateUser(p.Context, req)
+	if err != nil {
+		return nil, e.resolverError(p.Context, err)
+	}
+	return e.users.WithAge(p.Context, user), nil
+}
+
+// resolveUpdateUser replaces a user's name, date of birth and time zone
+func (e *Executor) resolveUpdateUser(p graphql.ResolveParams) (any, error) {
+	input := p.Args["input"].(map[string]any)
+	req := &models.UpdateUserRequest{Name: input["name"].(string), DOB: input["dob"].(string)}
+	req.Timezone, _ = input["timezone"].(string)
+	if err := e.validate.Struct(req); err != nil {
+		return nil, badInput(fmt.Sprintf("validation failed: %v", err))
+	}
+
+	user, err := e.users.UpdateUser(p.Context, int32(p.Args["id"].(int)), req)
+	if err != nil {
+		return nil, e.resolverError(p.Context, err)
+	}
+	return e.users.WithAge(p.Context, user), nil
+}
+
+// resolveDeleteUser deletes a user
+func (e *Executor) resolveDeleteUser(p graphql.ResolveParams) (any, error) {
+	if err := e.users.DeleteUser(p.Context, int32(p.Args["id"].(int))); err != nil {
+		return nil, e.resolverError(p.Context, err)
+	}
+	return true, nil
+}
+
+// resolveTimezone returns a user's time zone, or null when they have none
+func resolveTimezone(p graphql.ResolveParams) (any, error) {
+	if user, ok := p.Source.(*models.UserResponse); ok && user.Timezone != "" {
+		return user.Timezone, nil
+	}
+	if user, ok := p.Source.(models.UserResponse); ok && user.Timezone != "" {
+		return user.Timezone, nil
+	}
+	return nil, nil
+}
+
+// parseFilter converts the filter argument to a repository filter
+func parseFilter(arg any) (filter repository.UserFilter, err error) {
+	fields, _ := arg.(map[string]any)
+
+	filter.NameContains, _ = fields["nameContains"].(string)
+	if filter.BornAfter, err = parseDateField(fields, "bornAfter"); err != nil {
+		return filter, err
+	}
+	if filter.BornBefore, err = parseDateField(fields, "bornBefore"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseDateField parses an optional YYYY-MM-DD input field; absent fields
+// yield the zero time
+func parseDateField(fields map[string]any, key string) (time.Time, error) {
+	value, ok := fields[key].(string)
+	if !ok {
+		return time.Time{}, nil
+	}
+	date, err := models.ParseDate(value)
+	if err != nil {
+		return time.Time{}, badInput(fmt.Sprintf("%s must be a date as YYYY-MM-DD", key))
+	}
+	return date, nil
+}
+
+// encodeCursor returns the opaque cursor for a user ID
+func encodeCursor(id int32) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(int(id))))
+}
+
+// decodeCursor returns the user ID encoded in cursor
+func decodeCursor(cursor string) (int32, error) {
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err == nil {
+		if value, ok := strings.CutPrefix(string(raw), cursorPrefix); ok {
+			if id, err := strconv.ParseInt(value, 10, 32); err == nil {
+				return int32(id), nil
+			}
+		}
+	}
+	return 0, badInput("after is not a valid cursor")
+}
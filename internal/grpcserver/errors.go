@@ -0,0 +1,31 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/service"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus maps service errors to gRPC status errors. Unexpected errors are
+// logged and reported as Internal without details.
+func toStatus(ctx context.Context, fallback *zap.Logger, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidDate), errors.Is(err, service.ErrFutureDOB), errors.Is(err, service.ErrInvalidTimezone):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	}
+
+	logger.FromContext(ctx, fallback).Error("request failed", zap.Error(err))
+	return status.Error(codes.Internal, "internal server error")
+}
@@ -0,0 +1,215 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/requestctx"
+	"user-profile-api/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys mirroring the REST headers
+const (
+	RequestIDKey = "x-request-id"
+	TraceIDKey   = "x-trace-id"
+)
+
+// interceptor wraps a unary or streaming call; next runs the rest of the
+// chain with the given context
+type interceptor func(ctx context.Context, fullMethod string, next func(context.Context) error) error
+
+// unary adapts an interceptor to unary calls
+func unary(i interceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		err = i(ctx, info.FullMethod, func(ctx context.Context) error {
+			resp, err = handler(ctx, req)
+			return err
+		})
+		return resp, err
+	}
+}
+
+// stream adapts an interceptor to streaming calls
+func stream(i interceptor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return i(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		})
+	}
+}
+
+// serverStream overrides the context of a stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// recovery turns panics into Internal errors
+func recovery(log *zap.Logger) interceptor {
+	return func(ctx context.Context, fullMethod string, next func(context.Context) error) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.FromContext(ctx, log).Error("panic in gRPC handler",
+					zap.String("method", fullMethod),
+					zap.Any("panic", r),
+					zap.ByteString("stack", debug.Stack()),
+				)
+				err = status.Error(codes.Internal, "internal server error")
+			}
+		}()
+		return next(ctx)
+	}
+}
+
+// requestID propagates the caller's x-request-id, or generates one, and
+// returns it in the response header
+func requestID() interceptor {
+	return func(ctx context.Context, fullMethod string, next func(context.Context) error) error {
+		id := firstValue(ctx, RequestIDKey)
+		if !requestctx.ValidRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+		return next(requestctx.WithRequestID(ctx, id))
+	}
+}
+
+// tracingInterceptor starts a server span for each call, continuing any trace
+// propagated by the caller through traceparent metadata
+func tracingInterceptor() interceptor {
+	tracer := otel.Tracer("user-profile-api/internal/grpcserver")
+
+	return func(ctx context.Context, fullMethod string, next func(context.Context) error) error {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+		service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+		ctx, span := tracer.Start(ctx, service+"/"+method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.RPCSystemGRPC,
+				semconv.RPCService(service),
+				semconv.RPCMethod(method),
+			),
+		)
+		defer span.End()
+
+		if spanCtx := span.SpanContext(); spanCtx.HasTraceID() {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(TraceIDKey, spanCtx.TraceID().String()))
+		}
+
+		err := next(ctx)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if serverError(code) {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+		return err
+	}
+}
+
+// logging stores a request-scoped logger in the context and logs each
+// completed call
+func logging(log *zap.Logger) interceptor {
+	return func(ctx context.Context, fullMethod string, next func(context.Context) error) error {
+		start := time.Now()
+
+		fields := []zap.Field{zap.String("request_id", requestctx.RequestID(ctx))}
+		fields = append(fields, tracing.LogFields(ctx)...)
+		reqLog := log.With(fields...)
+		ctx = logger.NewContext(ctx, reqLog)
+
+		err := next(ctx)
+
+		var addr string
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		reqLog.Info("rpc completed",
+			zap.String("method", fullMethod),
+			zap.Stringer("code", status.Code(err)),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("peer", addr),
+		)
+		return err
+	}
+}
+
+// auth requires token as a bearer token on UserService calls, like the
+// Fiber AdminAuth middleware. Health and reflection stay open so probes and
+// tooling work without credentials.
+func auth(token string) interceptor {
+	return func(ctx context.Context, fullMethod string, next func(context.Context) error) error {
+		if token == "" || !strings.HasPrefix(fullMethod, "/user.v1.") {
+			return next(ctx)
+		}
+
+		provided, ok := strings.CutPrefix(firstValue(ctx, "authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return status.Error(codes.Unauthenticated, "unauthorized")
+		}
+		return next(ctx)
+	}
+}
+
+// serverError reports whether code indicates a server-side failure
+func serverError(code codes.Code) bool {
+	switch code {
+	case codes.Unknown, codes.DeadlineExceeded, codes.Unimplemented, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// firstValue returns the first incoming metadata value for key
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// metadataCarrier adapts incoming metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (m metadataCarrier) Get(key string) string {
+	if values := metadata.MD(m).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (m metadataCarrier) Set(key, value string) {
+	metadata.MD(m).Set(key, value)
+}
+
+func (m metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
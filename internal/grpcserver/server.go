@@ -0,0 +1,104 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+
+	userv1 "user-profile-api/api/user/v1"
+	"user-profile-api/internal/service"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Options configures the gRPC server
+type Options struct {
+	// AuthToken, when set, is required as a bearer token on UserService calls
+	AuthToken string
+	// Reflection registers the server reflection service for tools like grpcurl
+	Reflection bool
+}
+
+// Server serves the gRPC user API, the standard health service and,
+// optionally, reflection
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+	logger *zap.Logger
+}
+
+// New creates a gRPC server backed by users
+func New(users *service.UserService, opts Options, logger *zap.Logger) *Server {
+	interceptors := []interceptor{
+		recovery(logger),
+		requestID(),
+		tracingInterceptor(),
+		logging(logger),
+		auth(opts.AuthToken),
+	}
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	for _, i := range interceptors {
+		unaryInterceptors = append(unaryInterceptors, unary(i))
+		streamInterceptors = append(streamInterceptors, stream(i))
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+
+	userv1.RegisterUserServiceServer(grpcServer, newUserServer(users, logger))
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	if opts.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	return &Server{
+		grpc:   grpcServer,
+		health: healthServer,
+		logger: logger,
+	}
+}
+
+// Serve accepts connections on lis until the server stops
+func (s *Server) Serve(lis net.Listener) error {
+	s.SetServing(true)
+	return s.grpc.Serve(lis)
+}
+
+// SetServing reports the server, and the user service, as serving or not
+// through the health service
+func (s *Server) SetServing(serving bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serving {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(userv1.UserService_ServiceDesc.ServiceName, status)
+}
+
+// Shutdown stops accepting calls and waits for running ones to finish until
+// ctx is done, then closes the remaining connections
+func (s *Server) Shutdown(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.logger.Warn("gRPC graceful shutdown timed out, closing connections")
+		s.grpc.Stop()
+	}
+}
@@ -0,0 +1,166 @@
+package grpcserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	userv1 "user-profile-api/api/user/v1"
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/service"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// memoryRepository serves a fixed set of users from memory
+type memoryRepository struct {
+	repository.Repository
+	users map[int32]repository.User
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id int32) (*repository.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryRepository) ListUsers(ctx context.Context, limit, offset int32) ([]repository.User, error) {
+	return r.SearchUsers(ctx, repository.UserFilter{}, offset, limit)
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	user := repository.User{ID: int32(len(r.users) + 1), Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users[user.ID] = user
+	return &user, nil
+}
+
+// SearchUsers ignores the filter and relies on IDs being consecutive
+func (r *memoryRepository) SearchUsers(ctx context.Context, filter repository.UserFilter, afterID, limit int32) ([]repository.User, error) {
+	var users []repository.User
+	for id := afterID + 1; int(id) <= len(r.users) && len(users) < int(limit); id++ {
+		users = append(users, r.users[id])
+	}
+	return users, nil
+}
+
+func newTestClient(t *testing.T, opts Options) userv1.UserServiceClient {
+	t.Helper()
+
+	repo := &memoryRepository{users: map[int32]repository.User{
+		1: {ID: 1, Name: "Alice", DOB: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		2: {ID: 2, Name: "Bob", DOB: time.Date(1985, 1, 2, 0, 0, 0, 0, time.UTC)},
+		3: {ID: 3, Name: "Carol", DOB: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}}
+	server := New(service.NewUserService(repo, zap.NewNop()), opts, zap.NewNop())
+
+	lis := bufconn.Listen(1 << 20)
+	go server.Serve(lis)
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+func TestGetUser(t *testing.T) {
+	client := newTestClient(t, Options{})
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDKey, "req-123")
+
+	var header metadata.MD
+	resp, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: 1}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.User.Name != "Alice" || resp.User.Dob != "1990-05-15" {
+		t.Errorf("GetUser() = %v; want Alice born 1990-05-15", resp.User)
+	}
+	if got := header.Get(RequestIDKey); len(got) != 1 || got[0] != "req-123" {
+		t.Errorf("%s header = %v; want [req-123]", RequestIDKey, got)
+	}
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{Id: 9})
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("GetUser() code = %v; want NotFound", code)
+	}
+}
+
+func TestStreamUsers(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	stream, err := client.StreamUsers(context.Background(), &userv1.StreamUsersRequest{PageSize: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for {
+		user, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, user.Name)
+	}
+
+	if got := strings.Join(names, ","); got != "Bob,Carol" {
+		t.Errorf("StreamUsers() sent %s; want Bob,Carol", got)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	client := newTestClient(t, Options{AuthToken: "secret"})
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{Id: 1})
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("GetUser() without token code = %v; want Unauthenticated", code)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	if _, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: 1}); err != nil {
+		t.Errorf("GetUser() with token error = %v", err)
+	}
+}
+
+func TestCreateUserTimezone(t *testing.T) {
+	client := newTestClient(t, Options{})
+
+	resp, err := client.CreateUser(context.Background(), &userv1.CreateUserRequest{Name: "Dan", Dob: "1990-05-17", Timezone: "Asia/Tokyo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.GetUser(context.Background(), &userv1.GetUserRequest{Id: resp.User.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.User.Timezone != "Asia/Tokyo" || got.User.Timezone != "Asia/Tokyo" {
+		t.Errorf("time zone = %q, then %q; want Asia/Tokyo", resp.User.Timezone, got.User.Timezone)
+	}
+
+	_, err = client.CreateUser(context.Background(), &userv1.CreateUserRequest{Name: "Eve", Dob: "1990-05-17", Timezone: "Mars/Olympus"})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("CreateUser() with an unknown time zone code = %v; want InvalidArgument", code)
+	}
+}
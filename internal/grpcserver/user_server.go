@@ -0,0 +1,149 @@
+package grpcserver
+
+import (
+	"context"
+
+	userv1 "user-profile-api/api/user/v1"
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/requestctx"
+	"user-profile-api/internal/service"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// userServer implements userv1.UserServiceServer on top of UserService
+type userServer struct {
+	userv1.UnimplementedUserServiceServer
+	users    *service.UserService
+	logger   *zap.Logger
+	validate *validator.Validate
+}
+
+func newUserServer(users *service.UserService, logger *zap.Logger) *userServer {
+	return &userServer{
+		users:    users,
+		logger:   logger,
+		validate: validator.New(),
+	}
+}
+
+// CreateUser creates a user
+func (s *userServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.CreateUserResponse, error) {
+	in := &models.CreateUserRequest{Name: req.GetName(), DOB: req.GetDob(), Timezone: req.GetTimezone()}
+	if err := s.validate.Struct(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	user, err := s.users.CreateUser(ctx, in)
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+
+	return &userv1.CreateUserResponse{User: fromUserResponse(s.users.WithAge(ctx, user))}, nil
+}
+
+// GetUser returns a user by ID
+func (s *userServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	ctx = s.userContext(ctx, req.GetId())
+
+	user, err := s.users.GetUserByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+
+	return &userv1.GetUserResponse{User: fromUserResponse(user)}, nil
+}
+
+// UpdateUser replaces a user's name, date of birth and time zone
+func (s *userServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.UpdateUserResponse, error) {
+	ctx = s.userContext(ctx, req.GetId())
+
+	in := &models.UpdateUserRequest{Name: req.GetName(), DOB: req.GetDob(), Timezone: req.GetTimezone()}
+	if err := s.validate.Struct(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "validation failed: %v", err)
+	}
+
+	user, err := s.users.UpdateUser(ctx, req.GetId(), in)
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+
+	return &userv1.UpdateUserResponse{User: fromUserResponse(s.users.WithAge(ctx, user))}, nil
+}
+
+// DeleteUser deletes a user by ID
+func (s *userServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*userv1.DeleteUserResponse, error) {
+	ctx = s.userContext(ctx, req.GetId())
+
+	if err := s.users.DeleteUser(ctx, req.GetId()); err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+
+	return &userv1.DeleteUserResponse{}, nil
+}
+
+// ListUsers returns one page of users
+func (s *userServer) ListUsers(ctx context.Context, req *userv1.ListUsersRequest) (*userv1.ListUsersResponse, error) {
+	if req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	users, err := s.users.ListUsers(ctx, req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, toStatus(ctx, s.logger, err)
+	}
+
+	resp := &userv1.ListUsersResponse{Users: make([]*userv1.User, len(users))}
+	for i := range users {
+		resp.Users[i] = fromUserResponse(&users[i])
+	}
+	return resp, nil
+}
+
+// StreamUsers streams every user from the requested offset, fetching one
+// page at a time. Only the first page is found by offset; later pages
+// continue after the last ID sent, so users created or deleted during the
+// stream are neither sent twice nor skipped.
+func (s *userServer) StreamUsers(req *userv1.StreamUsersRequest, stream userv1.UserService_StreamUsersServer) error {
+	ctx := stream.Context()
+	if req.GetOffset() < 0 {
+		return status.Error(codes.InvalidArgument, "offset must not be negative")
+	}
+
+	users, err := s.users.ListUsers(ctx, req.GetPageSize(), req.GetOffset())
+	for err == nil && len(users) > 0 {
+		for i := range users {
+			if err := stream.Send(fromUserResponse(&users[i])); err != nil {
+				return err
+			}
+		}
+		users, _, err = s.users.SearchUsers(ctx, repository.UserFilter{}, req.GetPageSize(), users[len(users)-1].ID)
+	}
+	if err != nil {
+		return toStatus(ctx, s.logger, err)
+	}
+	return nil
+}
+
+// userContext tags the request context, and its logger, with the user being
+// operated on
+func (s *userServer) userContext(ctx context.Context, id int32) context.Context {
+	ctx = requestctx.WithUserID(ctx, id)
+	return logger.With(ctx, s.logger, zap.Int32("user_id", id))
+}
+
+// fromUserResponse converts a service user to its protobuf form
+func fromUserResponse(user *models.UserResponse) *userv1.User {
+	return &userv1.User{
+		Id:       user.ID,
+		Name:     user.Name,
+		Dob:      user.DOB,
+		Age:      int32(user.Age),
+		Timezone: user.Timezone,
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"user-profile-api/config"
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AdminHandler handles operational requests under /admin
+type AdminHandler struct {
+	level  zap.AtomicLevel
+	store  *config.Store
+	logger *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(level zap.AtomicLevel, store *config.Store, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		level:  level,
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetConfig handles GET /admin/config and returns the active configuration
+// version with secrets redacted
+func (h *AdminHandler) GetConfig(c *fiber.Ctx) error {
+	snapshot := h.store.Snapshot()
+
+	cfg, err := snapshot.Config.Map()
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.ConfigResponse{
+		Version:  snapshot.Version,
+		LoadedAt: snapshot.LoadedAt,
+		Checksum: snapshot.Checksum,
+		Config:   cfg,
+	})
+}
+
+// GetLogLevel handles GET /admin/log-level
+func (h *AdminHandler) GetLogLevel(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(models.LogLevelResponse{
+		Level: h.level.Level().String(),
+	})
+}
+
+// SetLogLevel handles PUT /admin/log-level
+func (h *AdminHandler) SetLogLevel(c *fiber.Ctx) error {
+	var req models.LogLevelRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	level, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid log level",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	previous := h.level.Level()
+	h.level.SetLevel(level)
+
+	logger.FromContext(c.UserContext(), h.logger).Info("log level changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", level),
+	)
+
+	return c.Status(fiber.StatusOK).JSON(models.LogLevelResponse{
+		Level: level.String(),
+	})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+
+	"user-profile-api/internal/openapi"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RedocVersion is the Redoc release /docs loads. Update
+// openapi.redoc_integrity whenever it changes.
+const RedocVersion = "2.1.5"
+
+// DocsHandler serves the OpenAPI document and a browsable rendering of it
+type DocsHandler struct {
+	doc  *openapi.Document
+	page string
+}
+
+// NewDocsHandler creates a new docs handler. integrity is the Subresource
+// Integrity hash of the Redoc bundle, such as "sha384-..."; empty leaves
+// the check out.
+func NewDocsHandler(doc *openapi.Document, integrity string) *DocsHandler {
+	attrs := ""
+	if integrity != "" {
+		attrs = fmt.Sprintf(` integrity="%s" crossorigin="anonymous"`, integrity)
+	}
+	return &DocsHandler{
+		doc:  doc,
+		page: fmt.Sprintf(redocPage, RedocVersion, attrs),
+	}
+}
+
+// Spec handles GET /openapi.json
+func (h *DocsHandler) Spec(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(h.doc.JSON())
+}
+
+// UI handles GET /docs with Redoc, loaded from a CDN
+func (h *DocsHandler) UI(c *fiber.Ctx) error {
+	c.Type("html")
+	return c.SendString(h.page)
+}
+
+// redocPage is formatted with the Redoc version and the attributes of its
+// script tag
+const redocPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>User Profile API - Reference</title>
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/v%s/bundles/redoc.standalone.js"%s></script>
+</body>
+</html>
+`
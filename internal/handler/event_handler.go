@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/eventstream"
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// EventHandler streams user changes as Server-Sent Events
+type EventHandler struct {
+	broker    *eventstream.Broker
+	heartbeat time.Duration
+	logger    *zap.Logger
+}
+
+// NewEventHandler creates a new event handler
+func NewEventHandler(broker *eventstream.Broker, heartbeat time.Duration, logger *zap.Logger) *EventHandler {
+	return &EventHandler{
+		broker:    broker,
+		heartbeat: heartbeat,
+		logger:    logger,
+	}
+}
+
+// Stream handles GET /users/events. Clients resume with the Last-Event-ID
+// header (or last_event_id query parameter, since EventSource cannot set
+// headers on its first request) and may restrict the stream with
+// ?user_id=1,2.
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	filter, err := userFilter(c.Query("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid user_id",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+	var resumeFrom int64
+	resume := lastEventID != ""
+	if resume {
+		if resumeFrom, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: "invalid Last-Event-ID",
+				Code:  models.ErrorCodeInvalidRequest,
+			})
+		}
+	}
+
+	sub, replay, gap := h.broker.Subscribe(resumeFrom, resume, filter)
+	log := logger.FromContext(c.UserContext(), h.logger)
+	log.Debug("event stream opened", zap.Bool("resume", resume), zap.Int("replay", len(replay)), zap.Bool("gap", gap))
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// The server's write timeout would cut the stream off, so every write
+	// gets its own deadline instead; a client that stops reading is dropped
+	conn := c.Context().Conn()
+	writeTimeout := 2 * h.heartbeat
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		defer log.Debug("event stream closed")
+
+		send := func(write func()) bool {
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			write()
+			return w.Flush() == nil
+		}
+
+		if !send(func() { fmt.Fprintf(w, "retry: %d\n\n", (3 * time.Second).Milliseconds()) }) {
+			return
+		}
+		if gap && !send(func() { writeReset(w) }) {
+			return
+		}
+		for _, event := range replay {
+			if !send(func() { writeEvent(w, event) }) {
+				return
+			}
+		}
+
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case msg, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				write := func() { writeEvent(w, msg.Event) }
+				if msg.Reset {
+					write = func() { writeReset(w) }
+				}
+				if !send(write) {
+					return
+				}
+			case <-ticker.C:
+				// Comments keep proxies from closing an idle connection
+				if !send(func() { fmt.Fprint(w, ": heartbeat\n\n") }) {
+					return
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// writeEvent writes event in SSE format
+func writeEvent(w *bufio.Writer, event events.Event) {
+	data, err := event.Marshal()
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
+
+// writeReset tells the client it may have missed events
+func writeReset(w *bufio.Writer) {
+	fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+}
+
+// userFilter parses a comma-separated list of user IDs
+func userFilter(raw string) (eventstream.Filter, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	ids := make(map[int32]bool)
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids[int32(id)] = true
+	}
+
+	return func(event events.Event) bool {
+		return ids[event.UserID]
+	}, nil
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"user-profile-api/internal/graphqlapi"
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"go.uber.org/zap"
+)
+
+// GraphQLHandler serves the GraphQL API and, in development, GraphiQL
+type GraphQLHandler struct {
+	executor  *graphqlapi.Executor
+	graphiQL  bool
+	pinWindow time.Duration
+	logger    *zap.Logger
+}
+
+// NewGraphQLHandler creates a new GraphQL handler. After a mutation, the
+// client's reads are pinned to the primary for pinWindow; zero disables
+// pinning, as when no replicas are configured.
+func NewGraphQLHandler(executor *graphqlapi.Executor, graphiQL bool, pinWindow time.Duration, logger *zap.Logger) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor:  executor,
+		graphiQL:  graphiQL,
+		pinWindow: pinWindow,
+		logger:    logger,
+	}
+}
+
+// Execute handles POST /graphql. Errors raised while executing a valid
+// request are reported in the errors array of a 200 response.
+func (h *GraphQLHandler) Execute(c *fiber.Ctx) error {
+	var req graphqlapi.Request
+	if err := c.BodyParser(&req); err != nil || req.Query == "" {
+		logger.FromContext(c.UserContext(), h.logger).Warn("invalid GraphQL request", zap.Error(err))
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"errors": gqlerrors.FormatErrors(errors.New("request body must be JSON with a query")),
+		})
+	}
+
+	result, mutation := h.executor.Execute(c.UserContext(), req)
+	if mutation && h.pinWindow > 0 {
+		middleware.PinPrimary(c, h.pinWindow)
+	}
+	return c.JSON(result)
+}
+
+// GraphiQL handles GET /graphql with the in-browser IDE; it is only served
+// in development
+func (h *GraphQLHandler) GraphiQL(c *fiber.Ctx) error {
+	if !h.graphiQL {
+		return c.Status(fiber.StatusMethodNotAllowed).JSON(fiber.Map{
+			"errors": gqlerrors.FormatErrors(errors.New("use POST for GraphQL requests")),
+		})
+	}
+
+	c.Type("html")
+	return c.SendString(graphiQLPage)
+}
+
+// graphiQLPage loads GraphiQL from a CDN and points it at this server
+const graphiQLPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>User Profile API - GraphiQL</title>
+  <style>body { margin: 0; height: 100vh; } #graphiql { height: 100vh; }</style>
+  <link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css">
+</head>
+<body>
+  <div id="graphiql">Loading...</div>
+  <script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+  <script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+  <script>
+    const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+    ReactDOM.createRoot(document.getElementById('graphiql')).render(
+      React.createElement(GraphiQL, { fetcher: fetcher, defaultEditorToolsVisibility: true })
+    );
+  </script>
+</body>
+</html>
+`
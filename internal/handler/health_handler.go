@@ -1,17 +1,22 @@
 package handler
 
 import (
+	"user-profile-api/internal/health"
 	"user-profile-api/internal/models"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	checker *health.Checker
+}
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
 }
 
 // Default handles GET /
@@ -21,10 +26,56 @@ func (h *HealthHandler) Default(c *fiber.Ctx) error {
 	})
 }
 
-
-// Check handles GET /health
+// Check handles GET /health and returns the detailed readiness report
 func (h *HealthHandler) Check(c *fiber.Ctx) error {
+	report := h.checker.Check(c.UserContext())
+	return c.Status(readinessStatusCode(report.Status)).JSON(report)
+}
+
+// Live handles GET /livez. The process is alive as long as it can serve.
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(models.HealthResponse{
-		Status: "ok",
+		Status: health.StatusOK,
+	})
+}
+
+// Startup handles GET /startupz
+func (h *HealthHandler) Startup(c *fiber.Ctx) error {
+	if !h.checker.Started() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.HealthResponse{
+			Status: health.StatusStarting,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.HealthResponse{
+		Status: health.StatusOK,
 	})
 }
+
+// Ready handles GET /readyz. Pass ?verbose=true for per-dependency details.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	if h.checker.ShuttingDown() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(models.HealthResponse{
+			Status: health.StatusShuttingDown,
+		})
+	}
+
+	report := h.checker.Check(c.UserContext())
+	status := readinessStatusCode(report.Status)
+
+	if c.QueryBool("verbose") {
+		return c.Status(status).JSON(report)
+	}
+
+	return c.Status(status).JSON(models.HealthResponse{
+		Status: report.Status,
+	})
+}
+
+// readinessStatusCode maps a readiness status to an HTTP status code
+func readinessStatusCode(status string) int {
+	if status != health.StatusOK {
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusOK
+}
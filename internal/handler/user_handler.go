@@ -1,10 +1,22 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"user-profile-api/internal/calendar"
+	"user-profile-api/internal/logger"
 	"user-profile-api/internal/models"
+	"user-profile-api/internal/render"
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/requestctx"
 	"user-profile-api/internal/service"
 
 	"github.com/go-playground/validator/v10"
@@ -28,35 +40,43 @@ func NewUserHandler(service *service.UserService, logger *zap.Logger) *UserHandl
 	}
 }
 
+// log returns the request-scoped logger
+func (h *UserHandler) log(c *fiber.Ctx) *zap.Logger {
+	return logger.FromContext(c.UserContext(), h.logger)
+}
+
+// userContext tags the request context, and its logger, with the user being operated on
+func (h *UserHandler) userContext(c *fiber.Ctx, id int32) context.Context {
+	ctx := requestctx.WithUserID(c.UserContext(), id)
+	ctx = logger.With(ctx, h.logger, zap.Int32("user_id", id))
+	c.SetUserContext(ctx)
+	return ctx
+}
+
 // CreateUser handles POST /users
 func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 	var req models.CreateUserRequest
 	
-	if err := c.BodyParser(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "invalid request body",
-		})
+	if err := render.Bind(c, &req); err != nil {
+		return h.badBody(c, err)
 	}
 
 	// Validate request
 	if err := h.validate.Struct(&req); err != nil {
-		h.logger.Warn("validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		h.log(c).Warn("validation failed", zap.Error(err))
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
 			Error: fmt.Sprintf("validation failed: %v", err),
+			Code:  models.ErrorCodeValidationFailed,
 		})
 	}
 
 	// Create user
-	user, err := h.service.CreateUser(c.Context(), &req)
+	user, err := h.service.CreateUser(c.UserContext(), &req)
 	if err != nil {
-		h.logger.Error("failed to create user", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: err.Error(),
-		})
+		return h.fail(c, "failed to create user", err)
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(user)
+	return render.Respond(c, fiber.StatusCreated, h.written(c, user))
 }
 
 // GetUser handles GET /users/:id
@@ -64,26 +84,59 @@ func (h *UserHandler) GetUser(c *fiber.Ctx) error {
 	
 	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
 			Error: "invalid user ID",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	ctx := h.userContext(c, int32(id))
+
+	fields, include, err := h.selection(c)
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
 		})
 	}
 
 	// Get user
-	user, err := h.service.GetUserByID(c.Context(), int32(id))
+	user, err := h.service.GetUserByID(ctx, int32(id), include...)
 	if err != nil {
-		if err.Error() == "user not found" {
-			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-				Error: "user not found",
-			})
-		}
-		h.logger.Error("failed to get user", zap.Error(err), zap.Int64("id", id))
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "internal server error",
+		return h.fail(c, "failed to get user", err)
+	}
+	user.Select(fields)
+
+	return render.Respond(c, fiber.StatusOK, user)
+}
+
+// GetAge handles GET /users/:id/age, the user's age on ?at=, which defaults
+// to today in the user's time zone
+func (h *UserHandler) GetAge(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid user ID",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	ctx := h.userContext(c, int32(id))
+
+	at, err := queryDate(c, "at", time.Time{})
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(user)
+	age, err := h.service.GetAge(ctx, int32(id), at)
+	if err != nil {
+		return h.fail(c, "failed to get age", err)
+	}
+
+	return render.Respond(c, fiber.StatusOK, age)
 }
 
 // UpdateUser handles PUT /users/:id
@@ -91,43 +144,70 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
 			Error: "invalid user ID",
+			Code:  models.ErrorCodeInvalidRequest,
 		})
 	}
 
+	ctx := h.userContext(c, int32(id))
+
 	var req models.UpdateUserRequest
 	
-	if err := c.BodyParser(&req); err != nil {
-		h.logger.Warn("invalid request body", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
-			Error: "invalid request body",
-		})
+	if err := render.Bind(c, &req); err != nil {
+		return h.badBody(c, err)
 	}
 
 	// Validate request
 	if err := h.validate.Struct(&req); err != nil {
-		h.logger.Warn("validation failed", zap.Error(err))
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		h.log(c).Warn("validation failed", zap.Error(err))
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
 			Error: fmt.Sprintf("validation failed: %v", err),
+			Code:  models.ErrorCodeValidationFailed,
 		})
 	}
 
 	// Update user
-	user, err := h.service.UpdateUser(c.Context(), int32(id), &req)
+	user, err := h.service.UpdateUser(ctx, int32(id), &req)
 	if err != nil {
-		if err.Error() == "user not found" {
-			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-				Error: "user not found",
-			})
-		}
-		h.logger.Error("failed to update user", zap.Error(err), zap.Int64("id", id))
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: err.Error(),
+		return h.fail(c, "failed to update user", err)
+	}
+
+	return render.Respond(c, fiber.StatusOK, h.written(c, user))
+}
+
+// PatchUser handles PATCH /users/:id, changing only the fields present in
+// the body
+func (h *UserHandler) PatchUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: "invalid user ID",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	ctx := h.userContext(c, int32(id))
+
+	var req models.PatchUserRequest
+	if err := render.Bind(c, &req); err != nil {
+		return h.badBody(c, err)
+	}
+
+	if err := h.validate.Struct(&req); err != nil {
+		h.log(c).Warn("validation failed", zap.Error(err))
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: fmt.Sprintf("validation failed: %v", err),
+			Code:  models.ErrorCodeValidationFailed,
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(user)
+	user, err := h.service.PatchUser(ctx, int32(id), &req)
+	if err != nil {
+		return h.fail(c, "failed to patch user", err)
+	}
+
+	return render.Respond(c, fiber.StatusOK, h.written(c, user))
 }
 
 // DeleteUser handles DELETE /users/:id
@@ -135,22 +215,17 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 
 	id, err := strconv.ParseInt(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
 			Error: "invalid user ID",
+			Code:  models.ErrorCodeInvalidRequest,
 		})
 	}
 
-	err = h.service.DeleteUser(c.Context(), int32(id))
+	ctx := h.userContext(c, int32(id))
+
+	err = h.service.DeleteUser(ctx, int32(id))
 	if err != nil {
-		if err.Error() == "user not found" {
-			return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
-				Error: "user not found",
-			})
-		}
-		h.logger.Error("failed to delete user", zap.Error(err), zap.Int64("id", id))
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "internal server error",
-		})
+		return h.fail(c, "failed to delete user", err)
 	}
 
 	return c.SendStatus(fiber.StatusNoContent)
@@ -158,16 +233,253 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 
 // ListUsers handles GET /users
 func (h *UserHandler) ListUsers(c *fiber.Ctx) error {
-	limit := c.QueryInt("limit", 10)
+	limit := c.QueryInt("limit")
+	offset := c.QueryInt("offset", 0)
+
+	fields, include, err := h.selection(c)
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	// Version 2 wraps the page with the offset of the next one
+	if requestctx.APIVersion(c.UserContext()) >= 2 {
+		users, hasMore, err := h.service.ListUsersPage(c.UserContext(), int32(limit), int32(offset), include...)
+		if err != nil {
+			return h.fail(c, "failed to list users", err)
+		}
+		selectFields(users, fields)
+
+		resp := models.UserListResponse{Data: users}
+		if hasMore {
+			next := int32(offset + len(users))
+			resp.NextOffset = &next
+		}
+		return render.Respond(c, fiber.StatusOK, resp)
+	}
+
+	users, err := h.service.ListUsers(c.UserContext(), int32(limit), int32(offset), include...)
+	if err != nil {
+		return h.fail(c, "failed to list users", err)
+	}
+	selectFields(users, fields)
+
+	return render.Respond(c, fiber.StatusOK, users)
+}
+
+// ListBirthdays handles GET /users/birthdays, listing the birthdays from
+// ?from= to ?to=, which default to today and six days after ?from=
+func (h *UserHandler) ListBirthdays(c *fiber.Ctx) error {
+	from, err := queryDate(c, "from", h.today(c))
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+	to, err := queryDate(c, "to", from.AddDate(0, 0, 6))
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	return h.birthdays(c, from, to)
+}
+
+// BirthdaysToday handles GET /users/birthdays/today
+func (h *UserHandler) BirthdaysToday(c *fiber.Ctx) error {
+	date := h.today(c)
+	return h.birthdays(c, date, date)
+}
+
+// BirthdayCalendar handles GET /users/birthdays.ics, an iCalendar with a
+// yearly event for the birthday of every user matching ?name=,
+// ?born_after= and ?born_before=, that calendar apps can subscribe to.
+// With ?from= or ?to=, only the birthdays between them are kept; they
+// default like ListBirthdays.
+func (h *UserHandler) BirthdayCalendar(c *fiber.Ctx) error {
+	filter := repository.UserFilter{NameContains: c.Query("name")}
+	var from, to time.Time
+	var err error
+	if filter.BornAfter, err = queryDate(c, "born_after", time.Time{}); err == nil {
+		filter.BornBefore, err = queryDate(c, "born_before", time.Time{})
+	}
+	if err == nil && (c.Query("from") != "" || c.Query("to") != "") {
+		if from, err = queryDate(c, "from", h.today(c)); err == nil {
+			to, err = queryDate(c, "to", from.AddDate(0, 0, 6))
+		}
+	}
+	if err != nil {
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+	}
+
+	events, modified, err := h.service.BirthdayCalendar(c.UserContext(), filter, from, to)
+	if err != nil {
+		return h.fail(c, "failed to build the birthday calendar", err)
+	}
+
+	// The events are stamped with the last change to their users, so the
+	// same users give the same body on every instance, and the ETag only
+	// changes with them. Removing a user changes the ETag but not
+	// Last-Modified.
+	body := calendar.EncodeCalendar("Birthdays", events, modified)
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+	c.Set(fiber.HeaderETag, etag)
+	if !modified.IsZero() {
+		c.Set(fiber.HeaderLastModified, modified.Format(http.TimeFormat))
+	}
+	if notModified(c, etag, modified) {
+		return c.Status(fiber.StatusNotModified).Send(nil)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="birthdays.ics"`)
+	return c.Send(body)
+}
+
+// notModified reports whether the client's copy, named by If-None-Match or
+// dated by If-Modified-Since, is current. If-None-Match takes precedence;
+// a zero modified is never current by date.
+func notModified(c *fiber.Ctx, etag string, modified time.Time) bool {
+	if match := c.Get(fiber.HeaderIfNoneMatch); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince))
+	return err == nil && !modified.IsZero() && !modified.After(since)
+}
+
+// birthdays responds with a page of the birthdays from from to to,
+// paginated like ListUsers
+func (h *UserHandler) birthdays(c *fiber.Ctx, from, to time.Time) error {
+	limit := c.QueryInt("limit")
 	offset := c.QueryInt("offset", 0)
 
-	users, err := h.service.ListUsers(c.Context(), int32(limit), int32(offset))
+	birthdays, hasMore, err := h.service.ListBirthdays(c.UserContext(), from, to, int32(limit), int32(offset))
 	if err != nil {
-		h.logger.Error("failed to list users", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
-			Error: "internal server error",
+		return h.fail(c, "failed to list birthdays", err)
+	}
+
+	// Version 2 wraps the page with the offset of the next one
+	if requestctx.APIVersion(c.UserContext()) >= 2 {
+		resp := models.BirthdayListResponse{Data: birthdays}
+		if hasMore {
+			next := int32(offset + len(birthdays))
+			resp.NextOffset = &next
+		}
+		return render.Respond(c, fiber.StatusOK, resp)
+	}
+
+	return render.Respond(c, fiber.StatusOK, birthdays)
+}
+
+// queryDate parses a YYYY-MM-DD query parameter, returning def when it is
+// absent
+func queryDate(c *fiber.Ctx, name string, def time.Time) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return def, nil
+	}
+	date, err := models.ParseDate(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be a YYYY-MM-DD date, got %q", name, value)
+	}
+	return date, nil
+}
+
+// today returns the current date in the request's time zone, at midnight
+// UTC
+func (h *UserHandler) today(c *fiber.Ctx) time.Time {
+	now := h.service.Today(c.UserContext())
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// selection reads the fields and include query parameters of user reads.
+// Fields named in ?fields= are included even when ?include= leaves them out.
+func (h *UserHandler) selection(c *fiber.Ctx) ([]string, []models.Include, error) {
+	include, err := models.ParseIncludes(c.Query("include"))
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, implied, err := models.ParseFields(c.Query("fields"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, inc := range implied {
+		if !slices.Contains(include, inc) {
+			include = append(include, inc)
+		}
+	}
+	// Every vCard needs the name for its FN
+	if fields != nil && render.FormatOf(c) == render.VCard && !slices.Contains(fields, "name") {
+		fields = append(fields, "name")
+	}
+	return fields, include, nil
+}
+
+// selectFields limits the fields written for each user
+func selectFields(users []models.UserResponse, fields []string) {
+	for i := range users {
+		users[i].Select(fields)
+	}
+}
+
+// written returns the body for a user that was just written; version 1
+// leaves the age out
+func (h *UserHandler) written(c *fiber.Ctx, user *models.CreateUserResponse) any {
+	if requestctx.APIVersion(c.UserContext()) >= 2 {
+		return h.service.WithAge(c.UserContext(), user)
+	}
+	return user
+}
+
+// badBody responds to a request body render.Bind could not read
+func (h *UserHandler) badBody(c *fiber.Ctx, err error) error {
+	h.log(c).Warn("invalid request body", zap.Error(err))
+	if errors.Is(err, render.ErrUnsupportedMediaType) {
+		return render.Respond(c, fiber.StatusUnsupportedMediaType, models.ErrorResponse{
+			Error: fmt.Sprintf("unsupported content type %q", c.Get(fiber.HeaderContentType)),
+			Code:  models.ErrorCodeUnsupportedMediaType,
+		})
+	}
+	return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+		Error: "invalid request body",
+		Code:  models.ErrorCodeInvalidRequest,
+	})
+}
+
+// fail maps service errors to responses
+func (h *UserHandler) fail(c *fiber.Ctx, msg string, err error) error {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return render.Respond(c, fiber.StatusNotFound, models.ErrorResponse{
+			Error: "user not found",
+			Code:  models.ErrorCodeNotFound,
+		})
+	case errors.Is(err, service.ErrInvalidDate), errors.Is(err, service.ErrFutureDOB), errors.Is(err, service.ErrInvalidRange),
+		errors.Is(err, service.ErrBeforeBirth), errors.Is(err, service.ErrCalendarTooLarge), errors.Is(err, service.ErrInvalidTimezone):
+		return render.Respond(c, fiber.StatusBadRequest, models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeValidationFailed,
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(users)
+	h.log(c).Error(msg, zap.Error(err))
+	return render.Respond(c, fiber.StatusInternalServerError, models.ErrorResponse{
+		Error: "internal server error",
+		Code:  models.ErrorCodeInternal,
+	})
 }
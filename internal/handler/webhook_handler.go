@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/webhook"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler handles HTTP requests for webhook subscriptions
+type WebhookHandler struct {
+	service  *webhook.Service
+	logger   *zap.Logger
+	validate *validator.Validate
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(service *webhook.Service, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		service:  service,
+		logger:   logger,
+		validate: validator.New(),
+	}
+}
+
+// CreateWebhook handles POST /webhooks
+func (h *WebhookHandler) CreateWebhook(c *fiber.Ctx) error {
+	var req models.CreateWebhookRequest
+	if !h.parse(c, &req) {
+		return nil
+	}
+
+	sub, err := h.service.Create(c.UserContext(), req.URL, req.Events, req.Secret)
+	if err != nil {
+		return h.fail(c, "failed to create webhook", err)
+	}
+
+	resp := toWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *WebhookHandler) ListWebhooks(c *fiber.Ctx) error {
+	subs, err := h.service.List(c.UserContext())
+	if err != nil {
+		return h.fail(c, "failed to list webhooks", err)
+	}
+
+	responses := make([]models.WebhookResponse, len(subs))
+	for i := range subs {
+		responses[i] = toWebhookResponse(&subs[i])
+	}
+	return c.Status(fiber.StatusOK).JSON(responses)
+}
+
+// GetWebhook handles GET /webhooks/:id
+func (h *WebhookHandler) GetWebhook(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+
+	sub, err := h.service.Get(c.UserContext(), id)
+	if err != nil {
+		return h.fail(c, "failed to get webhook", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(toWebhookResponse(sub))
+}
+
+// UpdateWebhook handles PUT /webhooks/:id
+func (h *WebhookHandler) UpdateWebhook(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdateWebhookRequest
+	if !h.parse(c, &req) {
+		return nil
+	}
+
+	sub, err := h.service.Update(c.UserContext(), id, req.URL, req.Events, req.Active)
+	if err != nil {
+		return h.fail(c, "failed to update webhook", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(toWebhookResponse(sub))
+}
+
+// DeleteWebhook handles DELETE /webhooks/:id
+func (h *WebhookHandler) DeleteWebhook(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+
+	if err := h.service.Delete(c.UserContext(), id); err != nil {
+		return h.fail(c, "failed to delete webhook", err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListDeliveries handles GET /webhooks/:id/deliveries
+func (h *WebhookHandler) ListDeliveries(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.UserContext(), id, c.Query("status"), limit, offset)
+	if err != nil {
+		return h.fail(c, "failed to list deliveries", err)
+	}
+
+	responses := make([]models.WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = toDeliveryResponse(&deliveries[i])
+	}
+	return c.Status(fiber.StatusOK).JSON(responses)
+}
+
+// GetDelivery handles GET /webhooks/:id/deliveries/:deliveryID
+func (h *WebhookHandler) GetDelivery(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+	deliveryID, ok := h.id(c, "deliveryID")
+	if !ok {
+		return nil
+	}
+
+	delivery, err := h.service.GetDelivery(c.UserContext(), id, deliveryID)
+	if err != nil {
+		return h.fail(c, "failed to get delivery", err)
+	}
+	return c.Status(fiber.StatusOK).JSON(toDeliveryResponse(delivery))
+}
+
+// Redeliver handles POST /webhooks/:id/deliveries/:deliveryID/redeliver
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	id, ok := h.id(c, "id")
+	if !ok {
+		return nil
+	}
+	deliveryID, ok := h.id(c, "deliveryID")
+	if !ok {
+		return nil
+	}
+
+	delivery, err := h.service.Redeliver(c.UserContext(), id, deliveryID)
+	if err != nil {
+		return h.fail(c, "failed to redeliver", err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(toDeliveryResponse(delivery))
+}
+
+// log returns the request-scoped logger
+func (h *WebhookHandler) log(c *fiber.Ctx) *zap.Logger {
+	return logger.FromContext(c.UserContext(), h.logger)
+}
+
+// id parses a numeric route parameter. If it is invalid, a 400 response is
+// written and ok is false.
+func (h *WebhookHandler) id(c *fiber.Ctx, param string) (id int64, ok bool) {
+	id, err := strconv.ParseInt(c.Params(param), 10, 64)
+	if err != nil {
+		_ = c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid " + param,
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// parse decodes and validates the request body. If either fails, a 400
+// response is written and ok is false.
+func (h *WebhookHandler) parse(c *fiber.Ctx, req any) (ok bool) {
+	if err := c.BodyParser(req); err != nil {
+		h.log(c).Warn("invalid request body", zap.Error(err))
+		_ = c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: "invalid request body",
+			Code:  models.ErrorCodeInvalidRequest,
+		})
+		return false
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		h.log(c).Warn("validation failed", zap.Error(err))
+		_ = c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: fmt.Sprintf("validation failed: %v", err),
+			Code:  models.ErrorCodeValidationFailed,
+		})
+		return false
+	}
+	return true
+}
+
+// fail maps service errors to responses
+func (h *WebhookHandler) fail(c *fiber.Ctx, msg string, err error) error {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound), errors.Is(err, webhook.ErrDeliveryNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeNotFound,
+		})
+	case errors.Is(err, webhook.ErrInvalid):
+		return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeValidationFailed,
+		})
+	case errors.Is(err, webhook.ErrInactive), errors.Is(err, webhook.ErrInFlight):
+		return c.Status(fiber.StatusConflict).JSON(models.ErrorResponse{
+			Error: err.Error(),
+			Code:  models.ErrorCodeConflict,
+		})
+	}
+
+	h.log(c).Error(msg, zap.Error(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+		Error: "internal server error",
+		Code:  models.ErrorCodeInternal,
+	})
+}
+
+// toWebhookResponse converts a subscription to a response DTO without its secret
+func toWebhookResponse(sub *webhook.Subscription) models.WebhookResponse {
+	return models.WebhookResponse{
+		ID:                  sub.ID,
+		URL:                 sub.URL,
+		Events:              sub.Events,
+		Active:              sub.Active,
+		DisabledReason:      sub.DisabledReason,
+		ConsecutiveFailures: sub.ConsecutiveFailures,
+		CreatedAt:           sub.CreatedAt,
+		UpdatedAt:           sub.UpdatedAt,
+	}
+}
+
+// toDeliveryResponse converts a delivery and its attempt log to a response DTO
+func toDeliveryResponse(delivery *webhook.Delivery) models.WebhookDeliveryResponse {
+	resp := models.WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == webhook.StatusPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	for _, attempt := range delivery.Log {
+		resp.Log = append(resp.Log, models.WebhookAttemptResponse{
+			AttemptedAt:  attempt.AttemptedAt,
+			StatusCode:   attempt.StatusCode,
+			Error:        attempt.Error,
+			ResponseBody: attempt.ResponseBody,
+			DurationMS:   attempt.Duration.Milliseconds(),
+		})
+	}
+	return resp
+}
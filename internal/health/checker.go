@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"user-profile-api/internal/models"
+)
+
+// Probe statuses
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusStarting     = "starting"
+	StatusShuttingDown = "shutting_down"
+)
+
+// CheckFunc verifies a single dependency
+type CheckFunc func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Checker runs dependency checks for the readiness probe and tracks the
+// startup and shutdown state of the process
+type Checker struct {
+	checks   []namedCheck
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	// mu serializes check runs so concurrent probes share one result
+	mu       sync.Mutex
+	cached   *models.ReadinessResponse
+	cachedAt time.Time
+
+	started      atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// NewChecker creates a checker that bounds each check by timeout and reuses
+// results for cacheTTL to avoid stampeding dependencies
+func NewChecker(timeout, cacheTTL time.Duration) *Checker {
+	return &Checker{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Register adds a named dependency check. It must be called before serving.
+func (c *Checker) Register(name string, check CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// MarkStarted flags startup as complete
+func (c *Checker) MarkStarted() {
+	c.started.Store(true)
+}
+
+// Started reports whether startup has completed
+func (c *Checker) Started() bool {
+	return c.started.Load()
+}
+
+// MarkShuttingDown makes the readiness probe fail so traffic is drained
+func (c *Checker) MarkShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// ShuttingDown reports whether graceful shutdown has begun
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Check runs all registered checks, or returns the cached result if it is
+// still fresh
+func (c *Checker) Check(ctx context.Context) models.ReadinessResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && time.Since(c.cachedAt) < c.cacheTTL {
+		return *c.cached
+	}
+
+	// Detach from the caller so a disconnecting client does not cache a failure
+	report := c.run(context.WithoutCancel(ctx))
+	c.cached = &report
+	c.cachedAt = time.Now()
+
+	return report
+}
+
+// run executes the checks concurrently
+func (c *Checker) run(ctx context.Context) models.ReadinessResponse {
+	results := make([]models.DependencyStatus, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := nc.check(checkCtx)
+
+			results[i] = models.DependencyStatus{
+				Name:      nc.name,
+				Status:    StatusOK,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = StatusFail
+				results[i].Error = err.Error()
+			}
+		}(i, nc)
+	}
+	wg.Wait()
+
+	report := models.ReadinessResponse{
+		Status:    StatusOK,
+		CheckedAt: time.Now().UTC(),
+		Checks:    results,
+	}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCheckerReportsFailingDependency(t *testing.T) {
+	checker := NewChecker(time.Second, 0)
+	checker.Register("ok", func(ctx context.Context) error { return nil })
+	checker.Register("db", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	report := checker.Check(context.Background())
+
+	if report.Status != StatusFail {
+		t.Errorf("Status = %s; want %s", report.Status, StatusFail)
+	}
+	if len(report.Checks) != 2 {
+		t.Fatalf("len(Checks) = %d; want 2", len(report.Checks))
+	}
+	if report.Checks[1].Status != StatusFail || report.Checks[1].Error != "connection refused" {
+		t.Errorf("db check = %+v; want failed with error", report.Checks[1])
+	}
+}
+
+func TestCheckerCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	checker := NewChecker(time.Second, time.Minute)
+	checker.Register("db", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		if report := checker.Check(context.Background()); report.Status != StatusOK {
+			t.Fatalf("Status = %s; want %s", report.Status, StatusOK)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("check ran %d times; want 1", got)
+	}
+}
+
+func TestCheckerTimesOutSlowDependency(t *testing.T) {
+	checker := NewChecker(10*time.Millisecond, 0)
+	checker.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if report := checker.Check(context.Background()); report.Status != StatusFail {
+		t.Errorf("Status = %s; want %s", report.Status, StatusFail)
+	}
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"context"
+
+	"user-profile-api/internal/tracing"
+
+	"go.uber.org/zap"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given logger
+func NewContext(ctx context.Context, log *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext returns the request-scoped logger stored in ctx. When ctx carries
+// no logger, fallback is returned annotated with the active trace, if any.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if log, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return log
+	}
+	if fields := tracing.LogFields(ctx); len(fields) > 0 {
+		return fallback.With(fields...)
+	}
+	return fallback
+}
+
+// With returns a copy of ctx whose logger includes the given fields
+func With(ctx context.Context, fallback *zap.Logger, fields ...zap.Field) context.Context {
+	return NewContext(ctx, FromContext(ctx, fallback).With(fields...))
+}
@@ -1,37 +1,95 @@
 package logger
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Supported log encodings
+const (
+	EncodingConsole = "console"
+	EncodingJSON    = "json"
 )
 
-func New(level string) (*zap.Logger, error) {
-	zapLevel, err := zapcore.ParseLevel(level)
+// Config holds logger settings
+type Config struct {
+	Level    string
+	Encoding string
+
+	// Sampling keeps the first SamplingInitial entries with the same level and
+	// message each second, then every SamplingThereafter-th. Zero disables it.
+	SamplingInitial    int
+	SamplingThereafter int
+
+	// FilePath additionally writes logs to a rotated file when set
+	FilePath       string
+	FileMaxSizeMB  int
+	FileMaxAgeDays int
+	FileMaxBackups int
+}
+
+// New builds a logger from cfg. The returned AtomicLevel controls the level of
+// the logger at runtime.
+func New(cfg Config) (*zap.Logger, zap.AtomicLevel, error) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	if zapLevel, err := zapcore.ParseLevel(cfg.Level); err == nil {
+		level.SetLevel(zapLevel)
+	}
+
+	encoder, err := newEncoder(cfg.Encoding)
 	if err != nil {
-		zapLevel = zapcore.InfoLevel
+		return nil, level, err
 	}
 
-	// Create console encoder config for better readability
-	encoderConfig := zap.NewDevelopmentEncoderConfig()
-	encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
-	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
-	encoderConfig.ConsoleSeparator = " | "
-
-	// Create config with console encoder
-	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
-		Development:      false,
-		Encoding:         "console",
-		EncoderConfig:    encoderConfig,
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
+	// Write to stdout, plus the rotated file when configured
+	sinks := []zapcore.WriteSyncer{zapcore.Lock(os.Stdout)}
+	if cfg.FilePath != "" {
+		sinks = append(sinks, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   cfg.FilePath,
+			MaxSize:    cfg.FileMaxSizeMB,
+			MaxAge:     cfg.FileMaxAgeDays,
+			MaxBackups: cfg.FileMaxBackups,
+		}))
 	}
 
-	logger, err := config.Build()
-	if err != nil {
-		return nil, err
+	core := zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(sinks...), level)
+	if cfg.SamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter)
 	}
 
-	return logger, nil
+	logger := zap.New(core,
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)),
+	)
+
+	return logger, level, nil
+}
+
+// newEncoder creates the encoder for the requested encoding
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	switch encoding {
+	case "", EncodingConsole:
+		// Create console encoder config for better readability
+		encoderConfig := zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05")
+		encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+		encoderConfig.ConsoleSeparator = " | "
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	case EncodingJSON:
+		// Machine-readable encoder for log pipelines
+		encoderConfig := zap.NewProductionEncoderConfig()
+		encoderConfig.TimeKey = "time"
+		encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+		encoderConfig.EncodeDuration = zapcore.MillisDurationEncoder
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown log encoding %q", encoding)
+	}
 }
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminAuth requires the configured admin token as a bearer token
+func AdminAuth(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provided, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(models.ErrorResponse{
+				Error: "unauthorized",
+				Code:  models.ErrorCodeUnauthorized,
+			})
+		}
+
+		return c.Next()
+	}
+}
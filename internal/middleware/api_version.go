@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// API versioning. Versioned routes live under /v1, /v2 and so on; the
+// unversioned routes serve the version asked for in APIVersionHeader or an
+// Accept media type such as application/vnd.user-profile.v2+json.
+const (
+	APIVersionHeader       = "API-Version"
+	DeprecationHeader      = "Deprecation"
+	SunsetHeader           = "Sunset"
+	versionMediaTypePrefix = "application/vnd.user-profile.v"
+	versionMediaTypeSuffix = "+json"
+	versionSourcePath      = "path"
+	versionSourceHeader    = "header"
+	versionSourceAccept    = "accept"
+	versionSourceDefault   = "default"
+)
+
+// versionMetrics counts requests by version and by how the version was
+// chosen, e.g. "v1.default", and is published under "api_versions" at
+// /admin/metrics
+var versionMetrics = expvar.NewMap("api_versions")
+
+// Deprecation announces that an API version is going away
+type Deprecation struct {
+	// Since is sent in the Deprecation header (RFC 9745)
+	Since time.Time
+	// Sunset is sent in the Sunset header (RFC 8594); zero leaves it out
+	Sunset time.Time
+	// Successor is linked with rel="successor-version"; empty leaves it out
+	Successor string
+}
+
+// VersionOptions configures APIVersion
+type VersionOptions struct {
+	// Supported lists the versions clients may ask for
+	Supported []int
+	// Default serves unversioned requests that do not ask for a version
+	Default int
+	// Deprecated holds the deprecation of each deprecated version
+	Deprecated map[int]Deprecation
+}
+
+// APIVersion serves requests with the given version, or negotiates it from
+// the request headers when version is zero. The version is stored in the
+// request context, echoed in APIVersionHeader and counted, and deprecated
+// versions are announced in the response headers.
+func APIVersion(version int, opts VersionOptions) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		v, source := version, versionSourcePath
+		if v == 0 {
+			c.Vary(APIVersionHeader, fiber.HeaderAccept)
+
+			var err error
+			if v, source, err = negotiateVersion(c, opts); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+					Error: err.Error(),
+					Code:  models.ErrorCodeInvalidRequest,
+				})
+			}
+		}
+
+		versionMetrics.Add(fmt.Sprintf("v%d.%s", v, source), 1)
+		c.SetUserContext(requestctx.WithAPIVersion(c.UserContext(), v))
+		c.Set(APIVersionHeader, strconv.Itoa(v))
+
+		if d, ok := opts.Deprecated[v]; ok {
+			c.Set(DeprecationHeader, "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			if !d.Sunset.IsZero() {
+				c.Set(SunsetHeader, d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Successor != "" {
+				c.Append(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor))
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// negotiateVersion picks the version from APIVersionHeader, then from the
+// Accept header, then falls back to the default. It also reports which of
+// them decided.
+func negotiateVersion(c *fiber.Ctx, opts VersionOptions) (int, string, error) {
+	if value := c.Get(APIVersionHeader); value != "" {
+		version, err := parseVersion(value, opts)
+		return version, versionSourceHeader, err
+	}
+
+	for _, mediaType := range strings.Split(c.Get(fiber.HeaderAccept), ",") {
+		mediaType, _, _ = strings.Cut(mediaType, ";")
+		value, ok := strings.CutPrefix(strings.ToLower(strings.TrimSpace(mediaType)), versionMediaTypePrefix)
+		if !ok {
+			continue
+		}
+		version, err := parseVersion(strings.TrimSuffix(value, versionMediaTypeSuffix), opts)
+		return version, versionSourceAccept, err
+	}
+
+	return opts.Default, versionSourceDefault, nil
+}
+
+// parseVersion parses a version such as "2" or "v2" and checks that it is
+// supported
+func parseVersion(value string, opts VersionOptions) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "v"))
+	if err != nil || !slices.Contains(opts.Supported, version) {
+		supported := make([]string, len(opts.Supported))
+		for i, v := range opts.Supported {
+			supported[i] = strconv.Itoa(v)
+		}
+		return 0, fmt.Errorf("unsupported API version %q; supported versions are %s", value, strings.Join(supported, ", "))
+	}
+	return version, nil
+}
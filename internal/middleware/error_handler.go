@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"user-profile-api/internal/logger"
 	"user-profile-api/internal/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -8,19 +9,16 @@ import (
 )
 
 // ErrorHandler creates a centralized error handling middleware
-func ErrorHandler(logger *zap.Logger) fiber.Handler {
+func ErrorHandler(log *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		
+
 		err := c.Next()
 
 		if err == nil {
 			return nil
 		}
 
-		requestID, _ := c.Locals("request_id").(string)
-
-		logger.Error("request error",
-			zap.String("request_id", requestID),
+		logger.FromContext(c.UserContext(), log).Error("request error",
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
 			zap.Error(err),
@@ -30,11 +28,28 @@ func ErrorHandler(logger *zap.Logger) fiber.Handler {
 		if e, ok := err.(*fiber.Error); ok {
 			return c.Status(e.Code).JSON(models.ErrorResponse{
 				Error: e.Message,
+				Code:  errorCode(e.Code),
 			})
 		}
 
 		return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
 			Error: "internal server error",
+			Code:  models.ErrorCodeInternal,
 		})
 	}
 }
+
+// errorCode returns the error code for a Fiber error status
+func errorCode(status int) string {
+	switch {
+	case status == fiber.StatusNotFound:
+		return models.ErrorCodeNotFound
+	case status == fiber.StatusUnauthorized:
+		return models.ErrorCodeUnauthorized
+	case status == fiber.StatusTooManyRequests:
+		return models.ErrorCodeRateLimited
+	case status < fiber.StatusInternalServerError:
+		return models.ErrorCodeInvalidRequest
+	}
+	return models.ErrorCodeInternal
+}
@@ -3,12 +3,17 @@ package middleware
 import (
 	"time"
 
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/tracing"
+
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
 )
 
-// Logger creates a logging middleware
-func Logger(logger *zap.Logger) fiber.Handler {
+// Logger creates a logging middleware. It also stores a request-scoped logger,
+// tagged with the request and trace IDs, in the request context so that
+// services and repositories log with the same identifiers.
+func Logger(log *zap.Logger) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Start timer
 		start := time.Now()
@@ -16,6 +21,12 @@ func Logger(logger *zap.Logger) fiber.Handler {
 		// Get request ID from context
 		requestID, _ := c.Locals("request_id").(string)
 
+		// Build the request-scoped logger
+		fields := []zap.Field{zap.String("request_id", requestID)}
+		fields = append(fields, tracing.LogFields(c.UserContext())...)
+		reqLog := log.With(fields...)
+		c.SetUserContext(logger.NewContext(c.UserContext(), reqLog))
+
 		// Process request
 		err := c.Next()
 
@@ -23,8 +34,7 @@ func Logger(logger *zap.Logger) fiber.Handler {
 		duration := time.Since(start)
 
 		// Log request
-		logger.Info("request completed",
-			zap.String("request_id", requestID),
+		logger.FromContext(c.UserContext(), reqLog).Info("request completed",
 			zap.String("method", c.Method()),
 			zap.String("path", c.Path()),
 			zap.Int("status", c.Response().StatusCode()),
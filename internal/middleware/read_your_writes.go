@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"user-profile-api/internal/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Read-your-writes pinning. Browsers get a cookie; other clients can echo the
+// ReadPrimaryUntilHeader value back in ReadPrimaryHeader, or send "always".
+const (
+	PrimaryPinCookie       = "db_primary_pin"
+	ReadPrimaryHeader      = "X-Read-Primary"
+	ReadPrimaryUntilHeader = "X-Read-Primary-Until"
+)
+
+// ReadYourWrites pins a client's reads to the primary database for window
+// after it performs a successful write, so it never reads stale data from a
+// lagging replica
+func ReadYourWrites(window time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		applyPrimaryPin(c)
+
+		err := c.Next()
+
+		if err == nil && isWrite(c.Method()) && c.Response().StatusCode() < fiber.StatusBadRequest {
+			PinPrimary(c, window)
+		}
+
+		return err
+	}
+}
+
+// PrimaryPin honors the pins set by ReadYourWrites and PinPrimary without
+// pinning on writes itself, for routes such as GraphQL where the method does
+// not tell reads from writes
+func PrimaryPin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		applyPrimaryPin(c)
+		return c.Next()
+	}
+}
+
+// PinPrimary pins the client's reads to the primary for window, through the
+// cookie and the ReadPrimaryUntilHeader response header
+func PinPrimary(c *fiber.Ctx, window time.Duration) {
+	until := time.Now().Add(window)
+	value := strconv.FormatInt(until.Unix(), 10)
+
+	c.Cookie(&fiber.Cookie{
+		Name:     PrimaryPinCookie,
+		Value:    value,
+		Expires:  until,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+	c.Set(ReadPrimaryUntilHeader, value)
+}
+
+// applyPrimaryPin pins the request's reads to the primary if the client
+// presents an active pin
+func applyPrimaryPin(c *fiber.Ctx) {
+	now := time.Now()
+	if pinned(c.Cookies(PrimaryPinCookie), now) || pinned(c.Get(ReadPrimaryHeader), now) {
+		c.SetUserContext(requestctx.WithPrimaryPin(c.UserContext()))
+	}
+}
+
+// pinned reports whether a pin value ("always" or a unix deadline) is active
+func pinned(value string, now time.Time) bool {
+	if value == "" {
+		return false
+	}
+	if value == "always" {
+		return true
+	}
+
+	until, err := strconv.ParseInt(value, 10, 64)
+	return err == nil && now.Unix() < until
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	app := fiber.New()
+	app.Use(ReadYourWrites(time.Minute))
+	app.Get("/users", func(c *fiber.Ctx) error {
+		return c.SendString(strconv.FormatBool(requestctx.PrimaryPinned(c.UserContext())))
+	})
+	app.Post("/users", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusCreated) })
+	app.Put("/users", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusBadRequest) })
+
+	// A successful write pins the client
+	resp, err := app.Test(httptest.NewRequest("POST", "/users", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	until, err := strconv.ParseInt(resp.Header.Get(ReadPrimaryUntilHeader), 10, 64)
+	if err != nil || time.Until(time.Unix(until, 0)) <= 50*time.Second {
+		t.Errorf("%s = %q; want about a minute from now", ReadPrimaryUntilHeader, resp.Header.Get(ReadPrimaryUntilHeader))
+	}
+	var cookie string
+	for _, c := range resp.Cookies() {
+		if c.Name == PrimaryPinCookie {
+			cookie = c.Value
+		}
+	}
+	if cookie != strconv.FormatInt(until, 10) {
+		t.Errorf("%s cookie = %q; want the pin deadline", PrimaryPinCookie, cookie)
+	}
+
+	// Failed writes and reads do not
+	for _, method := range []string{"PUT", "GET"} {
+		resp, err := app.Test(httptest.NewRequest(method, "/users", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(ReadPrimaryUntilHeader); got != "" || len(resp.Cookies()) != 0 {
+			t.Errorf("%s pinned the client until %q", method, got)
+		}
+	}
+
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	tests := []struct {
+		name   string
+		cookie string
+		header string
+		want   string
+	}{
+		{"unpinned", "", "", "false"},
+		{"cookie", cookie, "", "true"},
+		{"expired cookie", past, "", "false"},
+		{"header", "", cookie, "true"},
+		{"always", "", "always", "true"},
+		{"malformed", "soon", "", "false"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/users", nil)
+			if tt.cookie != "" {
+				req.Header.Set(fiber.HeaderCookie, PrimaryPinCookie+"="+tt.cookie)
+			}
+			if tt.header != "" {
+				req.Header.Set(ReadPrimaryHeader, tt.header)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := make([]byte, 5)
+			n, _ := resp.Body.Read(body)
+			if got := string(body[:n]); got != tt.want {
+				t.Errorf("pinned = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
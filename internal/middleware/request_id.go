@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"user-profile-api/internal/requestctx"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
@@ -12,13 +14,14 @@ const RequestIDHeader = "X-Request-ID"
 func RequestID() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestID := c.Get(RequestIDHeader)
-		
-		// Generate new UUID if not present
-		if requestID == "" {
+
+		// Generate new UUID if not present or not safe to propagate
+		if !requestctx.ValidRequestID(requestID) {
 			requestID = uuid.New().String()
 		}
 
 		c.Locals("request_id", requestID)
+		c.SetUserContext(requestctx.WithRequestID(c.UserContext(), requestID))
 		c.Set(RequestIDHeader, requestID)
 
 		return c.Next()
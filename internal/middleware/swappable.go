@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Swappable is a middleware whose implementation can be replaced at runtime,
+// e.g. when reloadable configuration changes
+type Swappable struct {
+	handler atomic.Pointer[fiber.Handler]
+}
+
+// NewSwappable creates a swappable middleware initially delegating to h
+func NewSwappable(h fiber.Handler) *Swappable {
+	s := &Swappable{}
+	s.Swap(h)
+	return s
+}
+
+// Swap atomically replaces the delegate. Requests already in flight finish
+// with the previous one.
+func (s *Swappable) Swap(h fiber.Handler) {
+	s.handler.Store(&h)
+}
+
+// Handler returns the fiber handler to register
+func (s *Swappable) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return (*s.handler.Load())(c)
+	}
+}
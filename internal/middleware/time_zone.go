@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/requestctx"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TimeZoneHeader names the IANA time zone, like the TZ environment
+// variable, in which a request's "today" is taken; the tz query parameter
+// takes precedence
+const TimeZoneHeader = "TZ"
+
+// TimeZone stores the time zone a request asks for in the request context,
+// where it overrides the time zone of each user when ages are computed.
+// Unknown time zones are rejected with 400.
+func TimeZone() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Vary(TimeZoneHeader)
+
+		name := c.Query("tz", c.Get(TimeZoneHeader))
+		if name == "" {
+			return c.Next()
+		}
+		location, err := models.LoadTimezone(name)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+				Error: fmt.Sprintf("invalid time zone %q: %v", name, err),
+				Code:  models.ErrorCodeInvalidRequest,
+			})
+		}
+
+		c.SetUserContext(requestctx.WithLocation(c.UserContext(), location))
+		return c.Next()
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIDHeader is the response header carrying the trace ID
+const TraceIDHeader = "X-Trace-ID"
+
+// Tracing starts a server span for each request, continuing any trace
+// propagated by the caller through the W3C traceparent header
+func Tracing() fiber.Handler {
+	tracer := otel.Tracer("user-profile-api/internal/middleware")
+
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headerCarrier{c: c})
+
+		ctx, span := tracer.Start(ctx, fmt.Sprintf("%s %s", c.Method(), c.Path()),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethod(c.Method()),
+				// Fiber reuses the URL buffer once the request is done
+				semconv.HTTPTarget(utils.CopyString(c.OriginalURL())),
+				semconv.ClientAddress(c.IP()),
+			),
+		)
+		defer span.End()
+
+		c.SetUserContext(ctx)
+		if spanCtx := span.SpanContext(); spanCtx.HasTraceID() {
+			c.Set(TraceIDHeader, spanCtx.TraceID().String())
+		}
+
+		// Process request
+		err := c.Next()
+
+		// Name the span after the matched route to keep cardinality low
+		route := c.Route().Path
+		status := c.Response().StatusCode()
+		span.SetName(fmt.Sprintf("%s %s", c.Method(), route))
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPStatusCode(status),
+		)
+
+		if err != nil {
+			span.RecordError(err)
+		}
+		if err != nil || status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+
+		return err
+	}
+}
+
+// headerCarrier adapts the Fiber request headers to a propagation.TextMapCarrier
+type headerCarrier struct {
+	c *fiber.Ctx
+}
+
+var _ propagation.TextMapCarrier = headerCarrier{}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	keys := make([]string, 0)
+	h.c.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
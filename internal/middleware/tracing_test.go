@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider that keeps every ended span
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+// attributes returns the attributes of a span by key
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracing(t *testing.T) {
+	recorder := recordSpans(t)
+
+	app := fiber.New()
+	app.Use(Tracing())
+	app.Get("/users/:id", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+	app.Get("/boom", func(c *fiber.Ctx) error { return errors.New("boom") })
+
+	req := httptest.NewRequest("GET", "/users/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get(TraceIDHeader); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("%s = %q; want the caller's trace ID", TraceIDHeader, got)
+	}
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/boom", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans; want 2", len(spans))
+	}
+
+	ok := spans[0]
+	if ok.Name() != "GET /users/:id" || ok.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q of kind %v; want GET /users/:id of kind server", ok.Name(), ok.SpanKind())
+	}
+	if ok.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("parent span = %s; want the caller's span", ok.Parent().SpanID())
+	}
+	attrs := attributes(ok)
+	if attrs["http.route"].AsString() != "/users/:id" || attrs["http.status_code"].AsInt64() != 200 || attrs["http.target"].AsString() != "/users/7" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if ok.Status().Code != codes.Unset {
+		t.Errorf("status = %v; want unset", ok.Status())
+	}
+
+	failed := spans[1]
+	if failed.Status().Code != codes.Error || len(failed.Events()) == 0 {
+		t.Errorf("failed request status = %v with %d events; want an error and a recorded exception", failed.Status(), len(failed.Events()))
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// LeapDayPolicy says when February 29 birthdays are observed in common years
+type LeapDayPolicy string
+
+// Leap day policies
+const (
+	LeapDayFeb28 LeapDayPolicy = "feb28"
+	LeapDayMar1  LeapDayPolicy = "mar1"
+)
+
+// Birthday returns the date the birthday of someone born on dob is observed
+// in year
+func (p LeapDayPolicy) Birthday(dob time.Time, year int) time.Time {
+	if p == LeapDayFeb28 && dob.Month() == time.February && dob.Day() == 29 && !IsLeapYear(year) {
+		return time.Date(year, time.February, 28, 0, 0, 0, 0, time.UTC)
+	}
+	return anniversary(dob, year)
+}
+
+// Age returns the age of someone born on dob on the date of at, in at's time
+// zone. It increases on the birthday observed in at's year.
+func (p LeapDayPolicy) Age(dob, at time.Time) int {
+	age := at.Year() - dob.Year()
+	if dateOf(at).Before(p.Birthday(dob, at.Year())) {
+		age--
+	}
+	return age
+}
+
+// NextBirthday returns the date of the next birthday observed on or after
+// today
+func (p LeapDayPolicy) NextBirthday(dob, today time.Time) time.Time {
+	today = dateOf(today)
+	next := p.Birthday(dob, today.Year())
+	if next.Before(today) {
+		next = p.Birthday(dob, today.Year()+1)
+	}
+	return next
+}
+
+// DaysUntilBirthday returns the number of days from today to the next
+// birthday observed; it is 0 on the birthday itself
+func (p LeapDayPolicy) DaysUntilBirthday(dob, today time.Time) int {
+	return daysBetween(today, p.NextBirthday(dob, today))
+}
+
+// RRule returns the iCalendar recurrence rule of the birthdays of someone
+// born on dob. February 29 birthdays recur on the last day of February, or
+// on the 60th day of the year, which is February 29 in leap years and
+// March 1 otherwise.
+func (p LeapDayPolicy) RRule(dob time.Time) string {
+	if dob.Month() != time.February || dob.Day() != 29 {
+		return "FREQ=YEARLY"
+	}
+	if p == LeapDayFeb28 {
+		return "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+	}
+	return "FREQ=YEARLY;BYYEARDAY=60"
+}
+
+// IsLeapYear reports whether year has a February 29
+func IsLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// BirthdayResponse represents a user's birthday within a requested range
+type BirthdayResponse struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	DOB  string `json:"dob"`
+	// Birthday is the date the birthday is observed within the range
+	Birthday string `json:"birthday"`
+	// Turns is the age the user reaches on Birthday
+	Turns int `json:"turns"`
+}
+
+// BirthdayListResponse represents a page of birthdays in API version 2
+type BirthdayListResponse struct {
+	Data []BirthdayResponse `json:"data"`
+	// NextOffset is the offset of the next page; it is absent on the last page
+	NextOffset *int32 `json:"next_offset,omitempty"`
+}
+
+// Rows returns the birthdays, so CSV responses hold one row per birthday
+func (r BirthdayListResponse) Rows() any {
+	return r.Data
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// Optional computed fields are derived from the date of birth and today's
+// date. A February 29 birthday falls on March 1 in common years, matching
+// CalculateAge; the LeapDayPolicy methods of the same names observe it on
+// the date the policy says.
+
+// AgeInMonths returns the number of whole months from dob to today
+func AgeInMonths(dob, today time.Time) int {
+	months := (today.Year()-dob.Year())*12 + int(today.Month()) - int(dob.Month())
+	if today.Day() < dob.Day() {
+		months--
+	}
+	return months
+}
+
+// AgeInDays returns the number of days from dob to today
+func AgeInDays(dob, today time.Time) int {
+	return daysBetween(dob, today)
+}
+
+// NextBirthday returns the date of the next birthday on or after today
+func NextBirthday(dob, today time.Time) time.Time {
+	return LeapDayMar1.NextBirthday(dob, today)
+}
+
+// DaysUntilBirthday returns the number of days from today to the next
+// birthday; it is 0 on the birthday itself
+func DaysUntilBirthday(dob, today time.Time) int {
+	return LeapDayMar1.DaysUntilBirthday(dob, today)
+}
+
+// AgeBracket returns the age range an age falls in, such as "25-34"
+func AgeBracket(age int) string {
+	switch {
+	case age < 18:
+		return "0-17"
+	case age < 25:
+		return "18-24"
+	case age < 35:
+		return "25-34"
+	case age < 45:
+		return "35-44"
+	case age < 55:
+		return "45-54"
+	case age < 65:
+		return "55-64"
+	}
+	return "65+"
+}
+
+// anniversary returns the birthday in year; time.Date moves February 29 to
+// March 1 in common years
+func anniversary(dob time.Time, year int) time.Time {
+	return time.Date(year, dob.Month(), dob.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dateOf returns the calendar date of t at midnight UTC
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of calendar days from one date to another
+func daysBetween(from, to time.Time) int {
+	return int(dateOf(to).Sub(dateOf(from)).Hours() / 24)
+}
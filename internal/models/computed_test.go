@@ -0,0 +1,116 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputedFields(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	today := date(2025, 3, 10)
+
+	tests := []struct {
+		name          string
+		dob           time.Time
+		wantMonths    int
+		wantDays      int
+		wantNext      string
+		wantDaysUntil int
+	}{
+		{"birthday today", date(1990, 3, 10), 420, 12784, "2025-03-10", 0},
+		{"birthday tomorrow", date(1990, 3, 11), 419, 12783, "2025-03-11", 1},
+		{"birthday passed", date(2000, 1, 31), 301, 9170, "2026-01-31", 327},
+		{"february 29", date(2004, 2, 29), 252, 7680, "2026-03-01", 356},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AgeInMonths(tt.dob, today); got != tt.wantMonths {
+				t.Errorf("AgeInMonths = %d; want %d", got, tt.wantMonths)
+			}
+			if got := AgeInDays(tt.dob, today); got != tt.wantDays {
+				t.Errorf("AgeInDays = %d; want %d", got, tt.wantDays)
+			}
+			if got := FormatDate(NextBirthday(tt.dob, today)); got != tt.wantNext {
+				t.Errorf("NextBirthday = %s; want %s", got, tt.wantNext)
+			}
+			if got := DaysUntilBirthday(tt.dob, today); got != tt.wantDaysUntil {
+				t.Errorf("DaysUntilBirthday = %d; want %d", got, tt.wantDaysUntil)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	fields, include, err := ParseFields("name, id,next_birthday,name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 3 || len(include) != 1 || include[0] != IncludeNextBirthday {
+		t.Errorf("ParseFields = %v, %v; want three fields including next_birthday", fields, include)
+	}
+
+	if _, _, err := ParseFields("id,email"); err == nil {
+		t.Error("ParseFields accepted an unknown field")
+	}
+	if _, err := ParseIncludes("age_bracket,zodiac"); err == nil {
+		t.Error("ParseIncludes accepted an unknown include")
+	}
+}
+
+func TestLeapDayPolicy(t *testing.T) {
+	dob := time.Date(2004, time.February, 29, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		policy LeapDayPolicy
+		year   int
+		want   string
+	}{
+		{LeapDayFeb28, 2027, "2027-02-28"},
+		{LeapDayMar1, 2027, "2027-03-01"},
+		{LeapDayFeb28, 2028, "2028-02-29"},
+		{LeapDayMar1, 2100, "2100-03-01"},
+	}
+	for _, tt := range tests {
+		if got := FormatDate(tt.policy.Birthday(dob, tt.year)); got != tt.want {
+			t.Errorf("%s.Birthday(%d) = %s; want %s", tt.policy, tt.year, got, tt.want)
+		}
+	}
+
+	// February 28, 2026 and March 1, 2026 under each policy
+	feb28 := time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)
+	observed := []struct {
+		policy        LeapDayPolicy
+		today         time.Time
+		wantAge       int
+		wantNext      string
+		wantDaysUntil int
+	}{
+		{LeapDayFeb28, feb28, 22, "2026-02-28", 0},
+		{LeapDayMar1, feb28, 21, "2026-03-01", 1},
+		{LeapDayFeb28, feb28.AddDate(0, 0, 1), 22, "2027-02-28", 364},
+		{LeapDayMar1, feb28.AddDate(0, 0, 1), 22, "2026-03-01", 0},
+	}
+	for _, tt := range observed {
+		day := FormatDate(tt.today)
+		if got := tt.policy.Age(dob, tt.today); got != tt.wantAge {
+			t.Errorf("%s.Age(%s) = %d; want %d", tt.policy, day, got, tt.wantAge)
+		}
+		if got := FormatDate(tt.policy.NextBirthday(dob, tt.today)); got != tt.wantNext {
+			t.Errorf("%s.NextBirthday(%s) = %s; want %s", tt.policy, day, got, tt.wantNext)
+		}
+		if got := tt.policy.DaysUntilBirthday(dob, tt.today); got != tt.wantDaysUntil {
+			t.Errorf("%s.DaysUntilBirthday(%s) = %d; want %d", tt.policy, day, got, tt.wantDaysUntil)
+		}
+	}
+
+	if got := LeapDayFeb28.RRule(dob); got != "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1" {
+		t.Errorf("feb28 rule = %s", got)
+	}
+	if got := LeapDayMar1.RRule(dob); got != "FREQ=YEARLY;BYYEARDAY=60" {
+		t.Errorf("mar1 rule = %s", got)
+	}
+	if got := LeapDayMar1.RRule(dob.AddDate(0, 0, 1)); got != "FREQ=YEARLY" {
+		t.Errorf("March 1 rule = %s", got)
+	}
+}
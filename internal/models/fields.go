@@ -0,0 +1,126 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Include names an optional computed field of UserResponse, requested with
+// ?include=
+type Include string
+
+// Optional computed fields
+const (
+	IncludeAgeMonths         Include = "age_months"
+	IncludeAgeDays           Include = "age_days"
+	IncludeNextBirthday      Include = "next_birthday"
+	IncludeDaysUntilBirthday Include = "days_until_birthday"
+	IncludeAgeBracket        Include = "age_bracket"
+)
+
+// includes lists the optional computed fields in the order they are written
+var includes = []Include{
+	IncludeAgeMonths,
+	IncludeAgeDays,
+	IncludeNextBirthday,
+	IncludeDaysUntilBirthday,
+	IncludeAgeBracket,
+}
+
+// userFields lists every field of UserResponse in the order it is written
+var userFields = []string{
+	"id", "name", "dob", "age", "timezone",
+	string(IncludeAgeMonths),
+	string(IncludeAgeDays),
+	string(IncludeNextBirthday),
+	string(IncludeDaysUntilBirthday),
+	string(IncludeAgeBracket),
+}
+
+// ParseIncludes parses a comma-separated list of optional computed fields,
+// as given in ?include=
+func ParseIncludes(list string) ([]Include, error) {
+	var parsed []Include
+	for _, name := range splitList(list) {
+		include := Include(name)
+		if !slices.Contains(includes, include) {
+			names := make([]string, len(includes))
+			for i, inc := range includes {
+				names[i] = string(inc)
+			}
+			return nil, fmt.Errorf("unknown include %q; use %s", name, strings.Join(names, ", "))
+		}
+		if !slices.Contains(parsed, include) {
+			parsed = append(parsed, include)
+		}
+	}
+	return parsed, nil
+}
+
+// ParseFields parses a comma-separated list of UserResponse fields, as given
+// in ?fields=. Naming an optional computed field also includes it.
+func ParseFields(list string) (fields []string, include []Include, err error) {
+	for _, name := range splitList(list) {
+		if !slices.Contains(userFields, name) {
+			return nil, nil, fmt.Errorf("unknown field %q; use %s", name, strings.Join(userFields, ", "))
+		}
+		if !slices.Contains(fields, name) {
+			fields = append(fields, name)
+		}
+		if inc := Include(name); slices.Contains(includes, inc) && !slices.Contains(include, inc) {
+			include = append(include, inc)
+		}
+	}
+	return fields, include, nil
+}
+
+// splitList splits a comma-separated list, dropping blank entries
+func splitList(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Select limits the fields written for u to fields; nil writes them all
+func (u *UserResponse) Select(fields []string) {
+	u.fields = fields
+}
+
+// MarshalJSON writes the selected fields, in their usual order
+func (u UserResponse) MarshalJSON() ([]byte, error) {
+	type plain UserResponse
+	data, err := json.Marshal(plain(u))
+	if err != nil || u.fields == nil {
+		return data, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, name := range userFields {
+		value, ok := all[name]
+		if !ok || !slices.Contains(u.fields, name) {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
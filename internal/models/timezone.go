@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidTimezone is returned for names that are not IANA time zones
+var ErrInvalidTimezone = errors.New("time zone must be an IANA name such as Europe/Paris")
+
+// timezones caches loaded time zones by name, since time.LoadLocation reads
+// the zone database every time
+var timezones sync.Map
+
+// LoadTimezone returns the IANA time zone called name, such as Europe/Paris
+// or UTC. The server's own zone, Local, is not accepted.
+func LoadTimezone(name string) (*time.Location, error) {
+	if cached, ok := timezones.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	timezones.Store(name, location)
+	return location, nil
+}
@@ -14,19 +14,34 @@ type User struct {
 type CreateUserRequest struct {
 	Name string `json:"name" validate:"required,min=1,max=255"`
 	DOB  string `json:"dob" validate:"required,datetime=2006-01-02"`
+	// Timezone is an IANA time zone such as Europe/Paris; empty leaves the
+	// user without one
+	Timezone string `json:"timezone,omitempty" validate:"max=64"`
 }
 
 // UpdateUserRequest represents the request body for updating a user
 type UpdateUserRequest struct {
 	Name string `json:"name" validate:"required,min=1,max=255"`
 	DOB  string `json:"dob" validate:"required,datetime=2006-01-02"`
+	// Timezone replaces the user's time zone; empty removes it
+	Timezone string `json:"timezone,omitempty" validate:"max=64"`
+}
+
+// PatchUserRequest represents the request body for partially updating a
+// user; absent fields are left unchanged
+type PatchUserRequest struct {
+	Name *string `json:"name" validate:"omitnil,min=1,max=255"`
+	DOB  *string `json:"dob" validate:"omitnil,datetime=2006-01-02"`
+	// Timezone changes the user's time zone; an empty string removes it
+	Timezone *string `json:"timezone" validate:"omitnil,max=64"`
 }
 
 // CreateUserResponse represents the response for creating a user (without age)
 type CreateUserResponse struct {
-	ID   int32  `json:"id"`
-	Name string `json:"name"`
-	DOB  string `json:"dob"`
+	ID       int32  `json:"id"`
+	Name     string `json:"name"`
+	DOB      string `json:"dob"`
+	Timezone string `json:"timezone,omitempty"`
 }
 
 // UserResponse represents the response for user operations (with age)
@@ -35,14 +50,110 @@ type UserResponse struct {
 	Name string `json:"name"`
 	DOB  string `json:"dob"`
 	Age  int    `json:"age"`
+	// Timezone is the user's IANA time zone; absent when they have none
+	Timezone string `json:"timezone,omitempty"`
+
+	// Optional computed fields, present when requested with ?include=
+	AgeMonths         *int    `json:"age_months,omitempty"`
+	AgeDays           *int    `json:"age_days,omitempty"`
+	NextBirthday      *string `json:"next_birthday,omitempty"`
+	DaysUntilBirthday *int    `json:"days_until_birthday,omitempty"`
+	AgeBracket        *string `json:"age_bracket,omitempty"`
+
+	// fields limits the fields written; see Select
+	fields []string
+}
+
+// WithAge adds the age on the date of today, under policy, which the create
+// and update responses leave out; API version 2 returns it from every
+// operation
+func (r *CreateUserResponse) WithAge(today time.Time, policy LeapDayPolicy) *UserResponse {
+	resp := &UserResponse{ID: r.ID, Name: r.Name, DOB: r.DOB, Timezone: r.Timezone}
+	if dob, err := ParseDate(r.DOB); err == nil {
+		resp.Age = policy.Age(dob, today)
+	}
+	return resp
+}
+
+// AgeResponse represents a user's age on a date
+type AgeResponse struct {
+	ID  int32  `json:"id"`
+	DOB string `json:"dob"`
+	// At is the date the age is computed for
+	At  string `json:"at"`
+	Age int    `json:"age"`
+}
+
+// UserListResponse represents a page of users in API version 2
+type UserListResponse struct {
+	Data []UserResponse `json:"data"`
+	// NextOffset is the offset of the next page; it is absent on the last page
+	NextOffset *int32 `json:"next_offset,omitempty"`
 }
 
+// Rows returns the users, so CSV responses hold one row per user
+func (r UserListResponse) Rows() any {
+	return r.Data
+}
+
+// Error codes returned in ErrorResponse.Code
+const (
+	// ErrorCodeInvalidRequest marks malformed bodies and parameters
+	ErrorCodeInvalidRequest = "invalid_request"
+	// ErrorCodeValidationFailed marks well-formed requests with invalid values
+	ErrorCodeValidationFailed = "validation_failed"
+	ErrorCodeNotFound         = "not_found"
+	ErrorCodeUnauthorized     = "unauthorized"
+	ErrorCodeRateLimited      = "rate_limited"
+	ErrorCodeInternal         = "internal_error"
+	// ErrorCodeNotAcceptable marks Accept headers no supported format matches
+	ErrorCodeNotAcceptable = "not_acceptable"
+	// ErrorCodeUnsupportedMediaType marks bodies in an unsupported format
+	ErrorCodeUnsupportedMediaType = "unsupported_media_type"
+	// ErrorCodeConflict marks requests the resource's current state forbids
+	ErrorCodeConflict = "conflict"
+)
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error string `json:"error"`
+	Code  string `json:"code"`
 }
 
 // HealthResponse represents a health check response
 type HealthResponse struct {
 	Status string `json:"status"`
 }
+
+// ReadinessResponse represents the detailed readiness probe response
+type ReadinessResponse struct {
+	Status    string             `json:"status"`
+	CheckedAt time.Time          `json:"checked_at"`
+	Checks    []DependencyStatus `json:"checks"`
+}
+
+// DependencyStatus represents the result of a single dependency check
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// LogLevelRequest represents the request body for changing the log level
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelResponse represents the current log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// ConfigResponse represents the active configuration version
+type ConfigResponse struct {
+	Version  int64          `json:"version"`
+	LoadedAt time.Time      `json:"loaded_at"`
+	Checksum string         `json:"checksum"`
+	Config   map[string]any `json:"config"`
+}
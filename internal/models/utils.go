@@ -2,21 +2,25 @@ package models
 
 import "time"
 
-// CalculateAge calculates age from date of birth
+// CalculateAge calculates age from date of birth as of today, in the local time zone
 // Returns the age in years, accounting for whether the birthday has occurred this year
 func CalculateAge(dob time.Time) int {
-	now := time.Now()
-	
+	return AgeAt(dob, time.Now())
+}
+
+// AgeAt calculates age from date of birth on the date of at, in at's time zone
+// A February 29 birthday is reached on March 1 in common years
+func AgeAt(dob, at time.Time) int {
 	// Calculate years difference
-	age := now.Year() - dob.Year()
-	
+	age := at.Year() - dob.Year()
+
 	// Adjust if birthday hasn't occurred yet this year
 	// Check if current month is before birth month, or
 	// same month but current day is before birth day
-	if now.Month() < dob.Month() || (now.Month() == dob.Month() && now.Day() < dob.Day()) {
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
 		age--
 	}
-	
+
 	return age
 }
 
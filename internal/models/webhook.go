@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// CreateWebhookRequest represents the request body for creating a webhook
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"dive,oneof=user.created user.updated user.deleted"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=255"`
+}
+
+// UpdateWebhookRequest represents the request body for updating a webhook
+type UpdateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"dive,oneof=user.created user.updated user.deleted"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookResponse represents a webhook subscription. The secret is only
+// returned when the webhook is created.
+type WebhookResponse struct {
+	ID                  int64     `json:"id"`
+	URL                 string    `json:"url"`
+	Events              []string  `json:"events"`
+	Active              bool      `json:"active"`
+	DisabledReason      string    `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Secret              string    `json:"secret,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryResponse represents a delivery in a webhook's log
+type WebhookDeliveryResponse struct {
+	ID             int64                    `json:"id"`
+	EventID        int64                    `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	LastStatusCode int                      `json:"last_status_code,omitempty"`
+	LastError      string                   `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	Log            []WebhookAttemptResponse `json:"log,omitempty"`
+}
+
+// WebhookAttemptResponse represents a single delivery attempt
+type WebhookAttemptResponse struct {
+	AttemptedAt  time.Time `json:"attempted_at"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+}
@@ -0,0 +1,429 @@
+// Package openapi generates an OpenAPI 3.1 document from the Fiber route
+// table and the models each route exchanges, and validates traffic against
+// it.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.1.0"
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation describes one route. Bodies are given as a model value whose
+// JSON encoding is described by reflection, a *Schema, a Content for
+// non-JSON bodies, or nil for none.
+type Operation struct {
+	Method  string
+	Path    string // in Fiber syntax, e.g. /users/:id; path parameters are integer IDs
+	Summary string
+	Tag     string
+	Query   []Param
+	// Headers are documented but not validated
+	Headers []Param
+	Body    any
+	// Responses maps status codes to response bodies
+	Responses map[int]any
+	// Auth marks routes that require the admin bearer token
+	Auth bool
+	// Deprecated marks routes that clients should stop using
+	Deprecated bool
+	// MediaTypes lists other media types the JSON bodies are also offered
+	// in, with the same schema. Bodies in these types are not validated.
+	MediaTypes []string
+}
+
+// Param is a query or header parameter
+type Param struct {
+	Name        string
+	Description string
+	Schema      *Schema
+}
+
+// Content is a non-JSON body
+type Content struct {
+	Type   string
+	Schema *Schema
+}
+
+// AnyOf is a JSON body matching any of the given bodies
+type AnyOf []any
+
+// Sparse is a JSON body in which objects of Model's type may leave out any
+// of their fields, for routes with sparse fieldsets
+type Sparse struct {
+	Body  any
+	Model any
+}
+
+// Document is a generated OpenAPI document. It is empty until Generate is
+// called, which must happen before the server starts.
+type Document struct {
+	info       Info
+	components map[string]*Schema
+	routes     []*route
+	json       []byte
+}
+
+// route is a documented route compiled for matching requests
+type route struct {
+	method    string
+	segments  []string
+	query     map[string]*Schema
+	body      *Schema
+	responses map[int]*response
+	// mediaTypes are the non-JSON media types the route may respond with
+	mediaTypes []string
+}
+
+// response is the expected body of one status code; a nil schema means a
+// non-JSON or empty body that is not validated
+type response struct {
+	schema *Schema
+	empty  bool
+}
+
+// NewDocument creates an empty document
+func NewDocument(info Info) *Document {
+	return &Document{
+		info:       info,
+		components: make(map[string]*Schema),
+		json:       []byte("{}"),
+	}
+}
+
+// Generate documents every route in routes with the matching operation. It
+// returns the routes that have no operation and the operations that have no
+// route; both should be empty.
+func (d *Document) Generate(routes []fiber.Route, operations []Operation) (undocumented, unrouted []string) {
+	byKey := make(map[string]Operation, len(operations))
+	for _, op := range operations {
+		byKey[op.Method+" "+normalizePath(op.Path)] = op
+	}
+
+	paths := make(map[string]map[string]any)
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		if r.Method == fiber.MethodHead {
+			continue
+		}
+		key := r.Method + " " + normalizePath(r.Path)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		op, ok := byKey[key]
+		if !ok {
+			undocumented = append(undocumented, key)
+			continue
+		}
+
+		path := templatePath(op.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(op.Method)] = d.operation(op)
+	}
+	for key := range byKey {
+		if !seen[key] {
+			unrouted = append(unrouted, key)
+		}
+	}
+	sort.Strings(undocumented)
+	sort.Strings(unrouted)
+
+	doc := map[string]any{
+		"openapi": Version,
+		"info":    d.info,
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": d.components,
+			"securitySchemes": map[string]any{
+				"adminToken": map[string]string{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+	d.json, _ = json.MarshalIndent(doc, "", "  ")
+
+	return undocumented, unrouted
+}
+
+// JSON returns the encoded document
+func (d *Document) JSON() []byte {
+	return d.json
+}
+
+// operation builds the operation object for op and compiles its route
+func (d *Document) operation(op Operation) map[string]any {
+	r := &route{
+		method:     op.Method,
+		segments:   strings.Split(normalizePath(op.Path), "/"),
+		query:      make(map[string]*Schema),
+		responses:  make(map[int]*response),
+		mediaTypes: op.MediaTypes,
+	}
+	d.routes = append(d.routes, r)
+
+	obj := map[string]any{
+		"operationId": operationID(op),
+		"summary":     op.Summary,
+	}
+	if op.Tag != "" {
+		obj["tags"] = []string{op.Tag}
+	}
+	if op.Auth {
+		obj["security"] = []map[string][]string{{"adminToken": {}}}
+	}
+	if op.Deprecated {
+		obj["deprecated"] = true
+	}
+
+	var params []map[string]any
+	for _, segment := range r.segments {
+		if name, _, ok := paramSegment(segment); ok {
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true, "schema": Integer(1),
+			})
+		}
+	}
+	for _, p := range op.Query {
+		r.query[p.Name] = p.Schema
+		params = append(params, map[string]any{
+			"name": p.Name, "in": "query", "description": p.Description, "schema": p.Schema,
+		})
+	}
+	for _, p := range op.Headers {
+		params = append(params, map[string]any{
+			"name": p.Name, "in": "header", "description": p.Description, "schema": p.Schema,
+		})
+	}
+	if len(params) > 0 {
+		obj["parameters"] = params
+	}
+
+	if op.Body != nil {
+		mediaType, schema := d.content(op.Body)
+		if mediaType == fiber.MIMEApplicationJSON {
+			r.body = schema
+		}
+		obj["requestBody"] = map[string]any{
+			"required": true,
+			"content":  contentOf(mediaType, schema, op.MediaTypes),
+		}
+	}
+
+	responses := make(map[string]any)
+	for status, body := range op.Responses {
+		resp := map[string]any{"description": http.StatusText(status)}
+		if body == nil {
+			r.responses[status] = &response{empty: true}
+		} else {
+			mediaType, schema := d.content(body)
+			resp["content"] = contentOf(mediaType, schema, op.MediaTypes)
+			if mediaType == fiber.MIMEApplicationJSON {
+				r.responses[status] = &response{schema: schema}
+			} else {
+				r.responses[status] = &response{}
+			}
+		}
+		responses[strconv.Itoa(status)] = resp
+	}
+	obj["responses"] = responses
+
+	return obj
+}
+
+// contentOf builds a content object; JSON bodies are also listed under the
+// other media types
+func contentOf(mediaType string, schema *Schema, others []string) map[string]any {
+	content := map[string]any{mediaType: map[string]any{"schema": schema}}
+	if mediaType == fiber.MIMEApplicationJSON {
+		for _, other := range others {
+			content[other] = map[string]any{"schema": schema}
+		}
+	}
+	return content
+}
+
+// content returns the media type and schema of a body
+func (d *Document) content(body any) (string, *Schema) {
+	switch b := body.(type) {
+	case *Schema:
+		return fiber.MIMEApplicationJSON, b
+	case AnyOf:
+		schema := &Schema{}
+		for _, alt := range b {
+			_, altSchema := d.content(alt)
+			schema.AnyOf = append(schema.AnyOf, altSchema)
+		}
+		return fiber.MIMEApplicationJSON, schema
+	case Sparse:
+		_, schema := d.content(b.Body)
+		sparse, _ := d.sparse(schema, reflect.TypeOf(b.Model).Name())
+		return fiber.MIMEApplicationJSON, sparse
+	case Content:
+		schema := b.Schema
+		if schema == nil {
+			schema = &Schema{Type: "string"}
+		}
+		return b.Type, schema
+	}
+	return fiber.MIMEApplicationJSON, d.schemaOf(reflect.TypeOf(body))
+}
+
+// sparse returns s with no required fields in the component named model,
+// which is copied inline wherever s refers to it. It also reports whether s
+// refers to the component at all; schemas that do not are returned as is.
+func (d *Document) sparse(s *Schema, model string) (*Schema, bool) {
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		resolved := d.components[name]
+		if resolved == nil {
+			return s, false
+		}
+		if name == model {
+			copied := *resolved
+			copied.Required = nil
+			return &copied, true
+		}
+		if copied, changed := d.sparse(resolved, model); changed {
+			return copied, true
+		}
+		return s, false
+	}
+
+	copied, changed := *s, false
+	if s.Items != nil {
+		if items, ok := d.sparse(s.Items, model); ok {
+			copied.Items, changed = items, true
+		}
+	}
+	if len(s.AnyOf) > 0 {
+		copied.AnyOf = make([]*Schema, len(s.AnyOf))
+		for i, alt := range s.AnyOf {
+			var ok bool
+			copied.AnyOf[i], ok = d.sparse(alt, model)
+			changed = changed || ok
+		}
+	}
+	if len(s.Properties) > 0 {
+		copied.Properties = make(map[string]*Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			var ok bool
+			copied.Properties[name], ok = d.sparse(prop, model)
+			changed = changed || ok
+		}
+	}
+	if !changed {
+		return s, false
+	}
+	return &copied, true
+}
+
+// normalizePath drops the trailing slash Fiber keeps on group roots
+func normalizePath(path string) string {
+	if len(path) > 1 {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path
+}
+
+// templatePath converts a Fiber path to an OpenAPI path template
+func templatePath(path string) string {
+	segments := strings.Split(normalizePath(path), "/")
+	for i, segment := range segments {
+		if name, suffix, ok := paramSegment(segment); ok {
+			segments[i] = "{" + name + "}" + suffix
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// operationID derives a stable ID such as get_users_id from the route
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method)
+	for _, segment := range strings.Split(normalizePath(op.Path), "/") {
+		segment = strings.Trim(strings.TrimPrefix(segment, ":"), "-.")
+		if segment != "" {
+			id += "_" + strings.NewReplacer("-", "_", ".", "_").Replace(segment)
+		}
+	}
+	return id
+}
+
+// find returns the documented route matching a request, preferring static
+// segments over parameters
+func (d *Document) find(method, path string) *route {
+	segments := strings.Split(normalizePath(path), "/")
+
+	var best *route
+	bestParams := 2*len(segments) + 1
+	for _, r := range d.routes {
+		if r.method != method || len(r.segments) != len(segments) {
+			continue
+		}
+		if params, ok := matchSegments(r.segments, segments); ok && params < bestParams {
+			best, bestParams = r, params
+		}
+	}
+	return best
+}
+
+// matchSegments reports whether a request path matches a route's segments,
+// and how loosely: each parameter counts two, or one when a literal suffix
+// such as the .vcf of :id.vcf narrows it
+func matchSegments(pattern, segments []string) (params int, ok bool) {
+	for i, p := range pattern {
+		_, suffix, isParam := paramSegment(p)
+		switch {
+		case isParam && suffix == "":
+			params += 2
+		case isParam:
+			if len(segments[i]) <= len(suffix) || !strings.HasSuffix(segments[i], suffix) {
+				return 0, false
+			}
+			params++
+		case p != segments[i]:
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// paramSegment splits a parameter segment such as :id or :id.vcf into the
+// parameter's name and the literal suffix that follows it
+func paramSegment(segment string) (name, suffix string, ok bool) {
+	name, ok = strings.CutPrefix(segment, ":")
+	if !ok {
+		return "", "", false
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name, suffix = name[:i], name[i:]
+	}
+	return name, suffix, true
+}
+
+// pathParams returns the path parameters of a matched request
+func (r *route) pathParams(path string) map[string]string {
+	params := make(map[string]string)
+	for i, segment := range strings.Split(normalizePath(path), "/") {
+		if name, suffix, ok := paramSegment(r.segments[i]); ok {
+			params[name] = strings.TrimSuffix(segment, suffix)
+		}
+	}
+	return params
+}
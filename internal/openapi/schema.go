@@ -0,0 +1,216 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema 2020-12 the document uses
+type Schema struct {
+	Ref         string `json:"$ref,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is a type name, or a list of names for values that may be null
+	Type                 any                `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+}
+
+// Integer returns an integer schema with a lower bound
+func Integer(minimum float64) *Schema {
+	return &Schema{Type: "integer", Minimum: &minimum}
+}
+
+// String returns a string schema, optionally restricted to values
+func String(values ...string) *Schema {
+	s := &Schema{Type: "string"}
+	for _, v := range values {
+		s.Enum = append(s.Enum, v)
+	}
+	return s
+}
+
+// Boolean returns a boolean schema
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+// types returns the type names s accepts; empty means any type
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	}
+	return nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf derives the schema of t. Named structs are added to the
+// document's components and referenced.
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(d.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		if _, ok := d.components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			d.components[t.Name()] = nil
+			d.components[t.Name()] = d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+// structSchema describes the JSON encoding of a struct. Fields are required
+// when their validate tag says so, or, for fields without validation rules,
+// when encoding/json always emits them.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := d.schemaOf(field.Type)
+		rules := field.Tag.Get("validate")
+		applyRules(prop, rules, field.Type)
+		s.Properties[name] = prop
+
+		required := hasRule(rules, "required")
+		if rules == "" {
+			required = !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer
+		}
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// applyRules maps go-playground/validator rules onto s. Rules after "dive"
+// apply to the items of a slice.
+func applyRules(s *Schema, rules string, t reflect.Type) {
+	if rules == "" {
+		return
+	}
+
+	own, itemRules, dive := strings.Cut(rules, "dive")
+	if dive && s.Items != nil {
+		applyRules(s.Items, strings.Trim(itemRules, ","), t.Elem())
+	}
+
+	// Lower bounds do not hold for omitted values, which validator skips
+	omitEmpty := hasRule(own, "omitempty")
+	for _, rule := range strings.Split(own, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "min", "max":
+			if name == "min" && omitEmpty {
+				continue
+			}
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			f := float64(n)
+			switch {
+			case t.Kind() == reflect.String && name == "min":
+				s.MinLength = &n
+			case t.Kind() == reflect.String:
+				s.MaxLength = &n
+			case name == "min":
+				s.Minimum = &f
+			default:
+				s.Maximum = &f
+			}
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, v)
+			}
+		case "datetime":
+			if param == "2006-01-02" {
+				s.Format = "date"
+			}
+		case "url":
+			s.Format = "uri"
+		case "email":
+			s.Format = "email"
+		}
+	}
+}
+
+// hasRule reports whether rules contains rule before any dive
+func hasRule(rules, rule string) bool {
+	own, _, _ := strings.Cut(rules, "dive")
+	for _, r := range strings.Split(own, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable extends s to also accept null
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	if types := s.types(); len(types) > 0 {
+		copied := *s
+		copied.Type = append(types[:len(types):len(types)], "null")
+		return &copied
+	}
+	return s
+}
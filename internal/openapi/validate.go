@@ -0,0 +1,296 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// ValidatorOptions selects what Validator checks
+type ValidatorOptions struct {
+	// Requests rejects requests whose path parameters, query parameters or
+	// JSON body do not match the document with 400
+	Requests bool
+	// Responses replaces responses that do not match the document with 500.
+	// It buffers and decodes every JSON response, so it is meant for tests.
+	Responses bool
+}
+
+// Validator checks traffic on documented routes against the document.
+// Undocumented routes pass through unchecked.
+func (d *Document) Validator(opts ValidatorOptions, log *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		r := d.find(c.Method(), c.Path())
+		if r == nil {
+			return c.Next()
+		}
+
+		if opts.Requests {
+			if err := d.validateRequest(c, r); err != nil {
+				logger.FromContext(c.UserContext(), log).Warn("request does not match the API specification", zap.Error(err))
+				return c.Status(fiber.StatusBadRequest).JSON(models.ErrorResponse{
+					Error: fmt.Sprintf("invalid request: %v", err),
+					Code:  models.ErrorCodeValidationFailed,
+				})
+			}
+		}
+
+		if err := c.Next(); err != nil || !opts.Responses {
+			return err
+		}
+
+		if err := d.validateResponse(c, r); err != nil {
+			logger.FromContext(c.UserContext(), log).Error("response does not match the API specification",
+				zap.Int("status", c.Response().StatusCode()),
+				zap.Error(err),
+			)
+			c.Response().ResetBody()
+			return c.Status(fiber.StatusInternalServerError).JSON(models.ErrorResponse{
+				Error: fmt.Sprintf("response does not match the API specification: %v", err),
+				Code:  models.ErrorCodeInternal,
+			})
+		}
+		return nil
+	}
+}
+
+// validateRequest checks the parameters and body of a request
+func (d *Document) validateRequest(c *fiber.Ctx, r *route) error {
+	for name, value := range r.pathParams(c.Path()) {
+		if n, err := strconv.ParseInt(value, 10, 64); err != nil || n < 1 {
+			return fmt.Errorf("path parameter %s must be a positive integer", name)
+		}
+	}
+
+	names := make([]string, 0, len(r.query))
+	for name := range r.query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw := c.Query(name)
+		if raw == "" {
+			continue
+		}
+		if err := d.validate(r.query[name], queryValue(r.query[name], raw), "query parameter "+name); err != nil {
+			return err
+		}
+	}
+
+	if r.body == nil {
+		return nil
+	}
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEApplicationJSON) {
+		// Handlers also accept form bodies, which are not checked
+		return nil
+	}
+	var body any
+	if err := json.Unmarshal(c.Body(), &body); err != nil {
+		return fmt.Errorf("body is not valid JSON")
+	}
+	return d.validate(r.body, body, "body")
+}
+
+// validateResponse checks the status code and JSON body of a response
+func (d *Document) validateResponse(c *fiber.Ctx, r *route) error {
+	status := c.Response().StatusCode()
+	expected, ok := r.responses[status]
+	if !ok {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+
+	body := c.Response().Body()
+	if expected.empty {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d must not have a body", status)
+		}
+		return nil
+	}
+	if expected.schema == nil {
+		return nil
+	}
+
+	if contentType := string(c.Response().Header.ContentType()); !strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		mediaType, _, _ := strings.Cut(contentType, ";")
+		if slices.Contains(r.mediaTypes, strings.TrimSpace(mediaType)) {
+			// Negotiated formats are derived from the JSON body
+			return nil
+		}
+		return fmt.Errorf("content type %q is not JSON", contentType)
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("body is not valid JSON")
+	}
+	return d.validate(expected.schema, value, "body")
+}
+
+// queryValue converts a raw query parameter to the JSON type its schema
+// expects; unconvertible values are left as strings and fail validation
+func queryValue(s *Schema, raw string) any {
+	for _, t := range s.types() {
+		switch t {
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				return n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b
+			}
+		}
+	}
+	return raw
+}
+
+// validate checks a decoded JSON value against s
+func (d *Document) validate(s *Schema, value any, path string) error {
+	if s.Ref != "" {
+		resolved := d.components[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+		if resolved == nil {
+			return fmt.Errorf("%s: unknown schema %s", path, s.Ref)
+		}
+		return d.validate(resolved, value, path)
+	}
+
+	if len(s.AnyOf) > 0 {
+		var errs []string
+		for _, alt := range s.AnyOf {
+			err := d.validate(alt, value, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err.Error())
+		}
+		return fmt.Errorf("%s matches no alternative (%s)", path, strings.Join(errs, "; "))
+	}
+
+	if types := s.types(); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if hasType(value, t) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s must be of type %s", path, strings.Join(types, " or "))
+		}
+	}
+
+	if len(s.Enum) > 0 && value != nil {
+		found := false
+		for _, v := range s.Enum {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", path, s.Enum)
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return validateString(s, v, path)
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return fmt.Errorf("%s must be at least %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return fmt.Errorf("%s must be at most %v", path, *s.Maximum)
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			prop := s.Properties[key]
+			if prop == nil {
+				prop = s.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := d.validate(prop, v[key], path+"."+key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateString checks the length and format of a string
+func validateString(s *Schema, v, path string) error {
+	length := utf8.RuneCountInString(v)
+	if s.MinLength != nil && length < *s.MinLength {
+		return fmt.Errorf("%s must be at least %d characters", path, *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Errorf("%s must be at most %d characters", path, *s.MaxLength)
+	}
+
+	switch s.Format {
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("%s must be a date as YYYY-MM-DD", path)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 date-time", path)
+		}
+	case "uri":
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute URL", path)
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value has the JSON Schema type t
+func hasType(value any, t string) bool {
+	switch v := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package openapi
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func TestValidatorRejectsNonConformingResponses(t *testing.T) {
+	app := fiber.New()
+	doc := NewDocument(Info{Title: "test", Version: "1"})
+	app.Use(doc.Validator(ValidatorOptions{Responses: true}, zap.NewNop()))
+	app.Get("/users/:id", func(c *fiber.Ctx) error {
+		if c.Params("id") == "1" {
+			return c.JSON(models.UserResponse{ID: 1, Name: "Alice", DOB: "1990-05-15", Age: 35})
+		}
+		// Missing age and a malformed date
+		return c.JSON(fiber.Map{"id": 2, "name": "Bob", "dob": "yesterday"})
+	})
+	doc.Generate(app.GetRoutes(true), []Operation{{
+		Method:    fiber.MethodGet,
+		Path:      "/users/:id",
+		Responses: map[int]any{200: models.UserResponse{}},
+	}})
+
+	for path, want := range map[string]int{"/users/1": 200, "/users/2": 500} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d %s; want %d", path, resp.StatusCode, body, want)
+		}
+		if want == 500 && !strings.Contains(string(body), "body.age is required") {
+			t.Errorf("GET %s body = %s; want the missing field named", path, body)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"fmt"
+	"os"
+
+	"user-profile-api/config"
+)
+
+// NewSink builds the sink described by cfg, fanning out to every configured
+// sink. With no sinks configured, events are marked delivered right away.
+func NewSink(cfg config.OutboxConfig) (Sink, error) {
+	var sinks []Sink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case config.OutboxSinkStdout:
+			sinks = append(sinks, NewWriterSink(os.Stdout))
+		case config.OutboxSinkFile:
+			sink, err := NewFileSink(cfg.FilePath)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case config.OutboxSinkWebhook:
+			sinks = append(sinks, NewWebhookSink(cfg.WebhookURL, cfg.SinkTimeout))
+		case config.OutboxSinkNATS:
+			sink, err := NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix, cfg.SinkTimeout)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+	return Fanout(sinks...), nil
+}
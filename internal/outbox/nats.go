@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"user-profile-api/internal/events"
+)
+
+// NATSSink publishes events to a NATS server, or any local stand-in speaking
+// the NATS text protocol, on the subject <prefix>.<event type>. Each publish
+// is followed by a PING and waits for the PONG, so an event only counts as
+// delivered once the server has processed it.
+type NATSSink struct {
+	addr    string
+	prefix  string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSSink creates a sink for the server at rawURL, such as
+// nats://localhost:4222. The connection is opened on first use.
+func NewNATSSink(rawURL, subjectPrefix string, timeout time.Duration) (*NATSSink, error) {
+	addr := rawURL
+	if strings.Contains(rawURL, "://") {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NATS URL: %w", err)
+		}
+		if u.Scheme != "nats" {
+			return nil, fmt.Errorf("unsupported NATS URL scheme %q", u.Scheme)
+		}
+		addr = u.Host
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "4222")
+	}
+
+	return &NATSSink{
+		addr:    addr,
+		prefix:  subjectPrefix,
+		timeout: timeout,
+	}, nil
+}
+
+// Publish sends the event and waits for the server to acknowledge it
+func (s *NATSSink) Publish(ctx context.Context, event events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := event.Type
+	if s.prefix != "" {
+		subject = s.prefix + "." + subject
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.publish(ctx, subject, payload); err != nil {
+		s.closeLocked()
+		return fmt.Errorf("NATS publish failed: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked()
+	return nil
+}
+
+func (s *NATSSink) publish(ctx context.Context, subject string, payload []byte) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := s.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	return s.awaitPong()
+}
+
+// connect dials the server and completes the INFO/CONNECT handshake
+func (s *NATSSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	line, err := s.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	_, err = conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"name":"user-profile-api-outbox"}` + "\r\n"))
+	return err
+}
+
+// awaitPong reads until the server answers our PING, replying to its own
+func (s *NATSSink) awaitPong() error {
+	for {
+		line, err := s.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (s *NATSSink) readLine() (string, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (s *NATSSink) closeLocked() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.reader = nil
+	}
+}
@@ -0,0 +1,252 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/pgnotify"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// relayLockID is the session-level advisory lock that makes a single
+// instance relay at a time, which keeps per-user ordering across instances
+const relayLockID = 0x6f7574626f78 // "outbox"
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 5 * time.Minute
+)
+
+// relayMetrics is published under "outbox" at /admin/metrics
+var relayMetrics = expvar.NewMap("outbox")
+
+// Relay delivers outbox events to a sink. Events are delivered at least once
+// and, for a given user, in the order they were recorded: after a failure,
+// later events for the same user wait until the failed one gets through.
+type Relay struct {
+	pool         *pgxpool.Pool
+	sink         Sink
+	logger       *zap.Logger
+	batchSize    int
+	pollInterval time.Duration
+	retention    time.Duration
+}
+
+// Options configures a Relay
+type Options struct {
+	// BatchSize is the number of pending events examined per round
+	BatchSize int
+	// PollInterval bounds the delay before retries are picked up; new
+	// events are relayed as soon as their NOTIFY arrives
+	PollInterval time.Duration
+	// Retention is how long delivered events are kept; zero keeps them
+	Retention time.Duration
+}
+
+// NewRelay creates a relay reading the outbox through pool
+func NewRelay(pool *pgxpool.Pool, sink Sink, opts Options, logger *zap.Logger) *Relay {
+	return &Relay{
+		pool:         pool,
+		sink:         sink,
+		logger:       logger,
+		batchSize:    opts.BatchSize,
+		pollInterval: opts.PollInterval,
+		retention:    opts.Retention,
+	}
+}
+
+// Run relays events until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	go pgnotify.NewListener(r.pool, r.logger).Listen(ctx, events.Channel, func(string) { notify() }, notify)
+
+	var lastCleanup time.Time
+	for ctx.Err() == nil {
+		n, err := r.relayBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Warn("outbox relay failed", zap.Error(err))
+		}
+
+		if r.retention > 0 && time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			r.cleanup(ctx)
+		}
+
+		// Attempted events are delivered or scheduled for a retry, so
+		// another round only picks up events that are still due
+		if err == nil && n > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// pendingEvent is an undelivered outbox row whose user's oldest undelivered
+// event is due
+type pendingEvent struct {
+	event    events.Event
+	attempts int
+	// decodeErr is set when the stored data cannot be decoded
+	decodeErr error
+}
+
+// relayBatch delivers the oldest due events and returns how many it
+// attempted. It does nothing while another instance holds the relay lock.
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Release()
+
+	// A session lock on a dedicated connection keeps other instances out
+	// without holding a transaction open while the sinks publish
+	var leader bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, relayLockID).Scan(&leader); err != nil {
+		return 0, err
+	}
+	if !leader {
+		return 0, nil
+	}
+	defer func() {
+		unlockCtx := context.WithoutCancel(ctx)
+		if _, err := conn.Exec(unlockCtx, `SELECT pg_advisory_unlock($1)`, relayLockID); err != nil {
+			// Closing the session releases the lock
+			r.logger.Warn("failed to release outbox relay lock", zap.Error(err))
+			conn.Conn().Close(unlockCtx)
+		}
+	}()
+
+	pending, err := r.fetchPending(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	blocked := make(map[int32]bool)
+	for _, p := range pending {
+		event := p.event
+		if blocked[event.UserID] {
+			continue
+		}
+		attempted++
+
+		// Retrying cannot fix an event that does not decode, so it must
+		// not hold back the user's later events
+		if p.decodeErr != nil {
+			relayMetrics.Add("undeliverable", 1)
+			r.logger.Error("outbox event cannot be decoded", zap.Int64("event_id", event.ID), zap.Error(p.decodeErr))
+			_, err := conn.Exec(ctx, `UPDATE outbox_events SET failed_at = now(), last_error = $2 WHERE id = $1`,
+				event.ID, p.decodeErr.Error())
+			if err != nil {
+				return attempted, fmt.Errorf("failed to mark event failed: %w", err)
+			}
+			continue
+		}
+
+		if publishErr := r.sink.Publish(ctx, event); publishErr != nil {
+			blocked[event.UserID] = true
+			relayMetrics.Add("failures", 1)
+
+			delay := retryDelay(p.attempts + 1)
+			r.logger.Warn("outbox delivery failed",
+				zap.Int64("event_id", event.ID),
+				zap.String("type", event.Type),
+				zap.Int("attempts", p.attempts+1),
+				zap.Duration("retry_in", delay),
+				zap.Error(publishErr),
+			)
+			_, err := conn.Exec(ctx, `UPDATE outbox_events
+				SET attempts = attempts + 1, last_error = $2, next_attempt_at = now() + make_interval(secs => $3)
+				WHERE id = $1`, event.ID, publishErr.Error(), delay.Seconds())
+			if err != nil {
+				return attempted, fmt.Errorf("failed to record delivery failure: %w", err)
+			}
+			continue
+		}
+
+		if _, err := conn.Exec(ctx, `UPDATE outbox_events SET delivered_at = now() WHERE id = $1`, event.ID); err != nil {
+			return attempted, fmt.Errorf("failed to mark event delivered: %w", err)
+		}
+		relayMetrics.Add("delivered", 1)
+	}
+
+	return attempted, nil
+}
+
+// fetchPending loads, in recording order, the undelivered events of the
+// users whose oldest undelivered event is due
+func (r *Relay) fetchPending(ctx context.Context, conn *pgxpool.Conn) ([]pendingEvent, error) {
+	rows, err := conn.Query(ctx, `WITH heads AS (
+			SELECT DISTINCT ON (user_id) user_id, next_attempt_at
+			FROM outbox_events
+			WHERE delivered_at IS NULL AND failed_at IS NULL
+			ORDER BY user_id, id
+		)
+		SELECT e.id, e.event_type, e.user_id, e.data, COALESCE(e.request_id, ''), e.occurred_at, e.attempts
+		FROM outbox_events e
+		JOIN heads h ON h.user_id = e.user_id AND h.next_attempt_at <= now()
+		WHERE e.delivered_at IS NULL AND e.failed_at IS NULL
+		ORDER BY e.id
+		LIMIT $1`, r.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []pendingEvent
+	for rows.Next() {
+		var p pendingEvent
+		var data []byte
+		err := rows.Scan(&p.event.ID, &p.event.Type, &p.event.UserID, &data, &p.event.RequestID,
+			&p.event.OccurredAt, &p.attempts)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &p.event.Data); err != nil {
+			p.decodeErr = fmt.Errorf("failed to decode event %d: %w", p.event.ID, err)
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// cleanup deletes events delivered longer ago than the retention period
+func (r *Relay) cleanup(ctx context.Context) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM outbox_events WHERE delivered_at < now() - make_interval(secs => $1)`, r.retention.Seconds())
+	if err != nil {
+		r.logger.Warn("failed to clean up delivered outbox events", zap.Error(err))
+		return
+	}
+	if n := result.RowsAffected(); n > 0 {
+		r.logger.Debug("cleaned up delivered outbox events", zap.Int64("count", n))
+	}
+}
+
+// retryDelay doubles the delay with every failed attempt, up to maxRetryDelay
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,118 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/events"
+)
+
+// natsStandIn is a minimal NATS server that records published messages
+type natsStandIn struct {
+	listener net.Listener
+	messages chan string
+}
+
+func newNATSStandIn(t *testing.T) *natsStandIn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &natsStandIn{listener: listener, messages: make(chan string, 10)}
+	go s.serve()
+	return s
+}
+
+func (s *natsStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"stand-in\"}\r\n")
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && fields[0] == "PUB":
+			var size int
+			fmt.Sscan(fields[2], &size)
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			s.messages <- fields[1] + " " + string(payload[:size])
+		case len(fields) == 1 && fields[0] == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		}
+	}
+}
+
+func TestNATSSinkPublishes(t *testing.T) {
+	server := newNATSStandIn(t)
+	sink, err := NewNATSSink("nats://"+server.listener.Addr().String(), "users", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	for id := int64(1); id <= 2; id++ {
+		event := events.Event{ID: id, Type: events.UserCreated, UserID: 7}
+		if err := sink.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+
+		msg := <-server.messages
+		if !strings.HasPrefix(msg, "users.user.created {") || !strings.Contains(msg, fmt.Sprintf(`"id":%d`, id)) {
+			t.Errorf("server received %q", msg)
+		}
+	}
+}
+
+func TestNATSSinkFailsWithoutServer(t *testing.T) {
+	server := newNATSStandIn(t)
+	addr := server.listener.Addr().String()
+	server.listener.Close()
+
+	sink, err := NewNATSSink(addr, "users", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Publish(context.Background(), events.Event{Type: events.UserDeleted}); err == nil {
+		t.Error("Publish() error = nil; want connection failure")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := map[int]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		4:  8 * time.Second,
+		20: maxRetryDelay,
+	}
+	for attempts, want := range tests {
+		if got := retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v; want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package pgnotify
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// Handler receives notification payloads
+type Handler func(payload string)
+
+// Listener receives PostgreSQL LISTEN/NOTIFY messages on a dedicated
+// connection, reconnecting with backoff when it is lost
+type Listener struct {
+	connConfig *pgx.ConnConfig
+	logger     *zap.Logger
+}
+
+// NewListener creates a listener using the connection settings of pool. The
+// listening connection is separate so it does not occupy a pool slot.
+func NewListener(pool *pgxpool.Pool, logger *zap.Logger) *Listener {
+	return &Listener{
+		connConfig: pool.Config().ConnConfig.Copy(),
+		logger:     logger,
+	}
+}
+
+// Listen delivers notifications on channel to onNotify until ctx is done.
+// onReconnect, if set, is called after the connection has been re-established,
+// since notifications sent while disconnected are lost.
+func (l *Listener) Listen(ctx context.Context, channel string, onNotify Handler, onReconnect func()) {
+	log := l.logger.With(zap.String("channel", channel))
+	backoff := minBackoff
+
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		err := l.listenOnce(ctx, channel, onNotify, func() {
+			backoff = minBackoff
+			if attempt > 0 && onReconnect != nil {
+				onReconnect()
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warn("notification listener disconnected, reconnecting", zap.Duration("backoff", backoff), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// listenOnce connects, subscribes and dispatches notifications until an error
+func (l *Listener) listenOnce(ctx context.Context, channel string, onNotify Handler, onConnected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, l.connConfig)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	onConnected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		onNotify(notification.Payload)
+	}
+}
+
+// Execer is implemented by pgx pools, connections and transactions
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Notify publishes payload on channel. Inside a transaction, delivery happens
+// on commit.
+func Notify(ctx context.Context, db Execer, channel, payload string) error {
+	_, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload)
+	return err
+}
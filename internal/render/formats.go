@@ -0,0 +1,507 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tinylib/msgp/msgp"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a media type responses can be rendered in and request bodies
+// read from
+type Format struct {
+	// MediaType is sent in Content-Type and matched against Accept
+	MediaType string
+	// aliases are other media types accepted for the format
+	aliases []string
+	// contentType is sent in Content-Type, when it differs from MediaType
+	contentType string
+	// errorsAsJSON writes error responses as JSON, for formats that can
+	// only describe users
+	errorsAsJSON bool
+	encode       func(v any) ([]byte, error)
+	decode       func(body []byte) (any, error)
+}
+
+// Supported formats
+var (
+	JSON = &Format{
+		MediaType: "application/json",
+		encode:    json.Marshal,
+		decode: func(body []byte) (any, error) {
+			return json.RawMessage(body), nil
+		},
+	}
+	XML = &Format{
+		MediaType:   "application/xml",
+		aliases:     []string{"text/xml"},
+		contentType: "application/xml; charset=utf-8",
+		encode:      encodeXML,
+		decode:      decodeXML,
+	}
+	YAML = &Format{
+		MediaType: "application/yaml",
+		aliases:   []string{"application/x-yaml", "text/yaml", "text/x-yaml"},
+		encode:    encodeYAML,
+		decode:    decodeYAML,
+	}
+	CSV = &Format{
+		MediaType:   "text/csv",
+		contentType: "text/csv; charset=utf-8",
+		encode:      encodeCSV,
+		decode:      decodeCSV,
+	}
+	MessagePack = &Format{
+		MediaType: "application/msgpack",
+		aliases:   []string{"application/x-msgpack", "application/vnd.msgpack"},
+		encode:    encodeMessagePack,
+		decode:    decodeMessagePack,
+	}
+	// VCard writes users as contacts and cannot be read
+	VCard = &Format{
+		MediaType:    "text/vcard",
+		aliases:      []string{"text/x-vcard"},
+		contentType:  "text/vcard; charset=utf-8",
+		errorsAsJSON: true,
+		encode:       encodeVCard,
+	}
+)
+
+// formats lists every format that can be read, for request bodies
+var formats = []*Format{JSON, XML, YAML, CSV, MessagePack}
+
+// matches reports whether the format is known by mediaType
+func (f *Format) matches(mediaType string) bool {
+	if mediaType == f.MediaType {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if mediaType == alias {
+			return true
+		}
+	}
+	// Structured syntax suffixes, such as application/vnd.user-profile.v2+json
+	return f == JSON && strings.HasSuffix(mediaType, "+json")
+}
+
+// hasType reports whether the media type of the format, or of one of its
+// aliases, has the top-level type typ, so that text/* matches XML through
+// text/xml
+func (f *Format) hasType(typ string) bool {
+	if strings.HasPrefix(f.MediaType, typ+"/") {
+		return true
+	}
+	for _, alias := range f.aliases {
+		if strings.HasPrefix(alias, typ+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Tabular is implemented by values that wrap a list, such as a page with
+// its pagination details; CSV renders only the rows
+type Tabular interface {
+	Rows() any
+}
+
+// xmlRoot and xmlItem name the root element and the elements of lists
+const (
+	xmlRoot = "response"
+	xmlItem = "item"
+)
+
+// encodeXML writes v with one element per JSON field, in a <response> root
+func encodeXML(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := writeXML(enc, xmlRoot, tree); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeXML writes value as an element named name; null members are left out
+func writeXML(enc *xml.Encoder, name string, value any) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case object:
+		for _, m := range v {
+			if m.value == nil {
+				continue
+			}
+			if err := writeXML(enc, m.key, m.value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := writeXML(enc, xmlItem, item); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(scalarString(v))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// decodeXML reads the children of the root element as fields. Elements that
+// repeat become lists, and every value is a string.
+func decodeXML(body []byte) (any, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return readXML(dec, start)
+		}
+	}
+}
+
+// readXML reads the element opened by start
+func readXML(dec *xml.Decoder, start xml.StartElement) (any, error) {
+	var text strings.Builder
+	var obj object
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			value, err := readXML(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			obj = appendXMLChild(obj, t.Name.Local, value)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if obj != nil {
+				return obj.lists(), nil
+			}
+			return strings.TrimSpace(text.String()), nil
+		}
+	}
+}
+
+// xmlList collects the values of a repeated element
+type xmlList []any
+
+// appendXMLChild adds a child element, collecting repeated ones in a list
+func appendXMLChild(obj object, name string, value any) object {
+	for i, m := range obj {
+		if m.key != name {
+			continue
+		}
+		if list, ok := m.value.(xmlList); ok {
+			obj[i].value = append(list, value)
+		} else {
+			obj[i].value = xmlList{m.value, value}
+		}
+		return obj
+	}
+	return append(obj, member{key: name, value: value})
+}
+
+// lists turns the collected repeated elements into plain lists; an object
+// whose only member is a list of <item>s is the list itself
+func (o object) lists() any {
+	for i, m := range o {
+		if list, ok := m.value.(xmlList); ok {
+			o[i].value = []any(list)
+		}
+	}
+	if len(o) == 1 && o[0].key == xmlItem {
+		if list, ok := o[0].value.([]any); ok {
+			return list
+		}
+		return []any{o[0].value}
+	}
+	return o
+}
+
+// encodeYAML writes v as a YAML document
+func encodeYAML(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(tree)); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlNode converts a tree to a YAML node, keeping the member order
+func yamlNode(value any) *yaml.Node {
+	switch v := value.(type) {
+	case object:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, m := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: m.key}, yamlNode(m.value))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: scalarString(v)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+	// Tagging strings keeps values such as "1990-05-15" or "yes" strings
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: scalarString(value)}
+}
+
+// decodeYAML reads a YAML document. Dates and other scalars YAML would
+// resolve to special types are read as strings.
+func decodeYAML(body []byte) (any, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(body, &node); err != nil {
+		return nil, err
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) != 1 {
+		return nil, errors.New("expected a single YAML document")
+	}
+	return yamlValue(node.Content[0])
+}
+
+// yamlValue converts a YAML node to a tree
+func yamlValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		obj := object{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := yamlValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: node.Content[i].Value, value: value})
+		}
+		return obj, nil
+	case yaml.SequenceNode:
+		list := []any{}
+		for _, item := range node.Content {
+			value, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	}
+
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		return json.Number(node.Value), nil
+	case "!!bool":
+		var b bool
+		err := node.Decode(&b)
+		return b, err
+	case "!!null":
+		return nil, nil
+	}
+	return node.Value, nil
+}
+
+// encodeCSV writes a list of objects as CSV with a header row naming their
+// fields; a single object is written as one row. Nested values are written
+// as JSON.
+func encodeCSV(v any) ([]byte, error) {
+	if t, ok := v.(Tabular); ok {
+		v = t.Rows()
+	}
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []object
+	switch t := tree.(type) {
+	case object:
+		rows = []object{t}
+	case []any:
+		for _, item := range t {
+			row, ok := item.(object)
+			if !ok {
+				return nil, errors.New("CSV rows must be objects")
+			}
+			rows = append(rows, row)
+		}
+	default:
+		return nil, errors.New("only objects and lists of objects can be written as CSV")
+	}
+
+	// Columns follow the order fields first appear in
+	var columns []string
+	index := map[string]int{}
+	for _, row := range rows {
+		for _, m := range row {
+			if _, ok := index[m.key]; !ok {
+				index[m.key] = len(columns)
+				columns = append(columns, m.key)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for _, m := range row {
+			record[index[m.key]] = csvCell(m.value)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvCell formats a value as a CSV cell. A string that spreadsheets would
+// evaluate as a formula, such as a user named =HYPERLINK(...), is defused by
+// prefixing it with a quote; numbers keep their sign.
+func csvCell(value any) string {
+	s, ok := value.(string)
+	if !ok {
+		return scalarString(value)
+	}
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// decodeCSV reads a header row and one object per following row; a single
+// row is a single object. Every value is a string.
+func decodeCSV(body []byte) (any, error) {
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, errors.New("expected a header row and at least one record")
+	}
+
+	header := records[0]
+	rows := make([]any, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := object{}
+		for i, value := range record {
+			if i < len(header) {
+				row = append(row, member{key: header[i], value: value})
+			}
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 1 {
+		return rows[0], nil
+	}
+	return rows, nil
+}
+
+// encodeMessagePack writes v as MessagePack
+func encodeMessagePack(v any) ([]byte, error) {
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+	return appendMessagePack(nil, tree)
+}
+
+// appendMessagePack appends a tree to b
+func appendMessagePack(b []byte, value any) ([]byte, error) {
+	var err error
+	switch v := value.(type) {
+	case object:
+		b = msgp.AppendMapHeader(b, uint32(len(v)))
+		for _, m := range v {
+			b = msgp.AppendString(b, m.key)
+			if b, err = appendMessagePack(b, m.value); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		b = msgp.AppendArrayHeader(b, uint32(len(v)))
+		for _, item := range v {
+			if b, err = appendMessagePack(b, item); err != nil {
+				return nil, err
+			}
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return msgp.AppendInt64(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		b = msgp.AppendFloat64(b, f)
+	case string:
+		b = msgp.AppendString(b, v)
+	case bool:
+		b = msgp.AppendBool(b, v)
+	case nil:
+		b = msgp.AppendNil(b)
+	default:
+		return nil, fmt.Errorf("cannot write %T as MessagePack", value)
+	}
+	return b, nil
+}
+
+// decodeMessagePack reads a single MessagePack value
+func decodeMessagePack(body []byte) (any, error) {
+	value, rest, err := msgp.ReadIntfBytes(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("unexpected data after the MessagePack value")
+	}
+	return value, nil
+}
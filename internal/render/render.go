@@ -0,0 +1,179 @@
+// Package render writes response bodies in the format a client asks for
+// with Accept, and reads request bodies in the format named by
+// Content-Type. JSON is the canonical encoding: XML, YAML, CSV and
+// MessagePack are derived from it, so every format uses the JSON field names
+// and order. vCard maps the fields of users to contact properties.
+package render
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"user-profile-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrUnsupportedMediaType is returned by Bind for bodies in an unknown format
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// formatKey stores the negotiated format in the request locals
+const formatKey = "render_format"
+
+// Negotiate picks the response format from the Accept header among offered,
+// the first of which is used when the client accepts anything. Requests
+// that accept none of them get 406 before reaching the handler.
+func Negotiate(offered ...*Format) fiber.Handler {
+	supported := make([]string, len(offered))
+	for i, f := range offered {
+		supported[i] = f.MediaType
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAccept)
+
+		format := negotiate(c.Get(fiber.HeaderAccept), offered)
+		if format == nil {
+			return c.Status(fiber.StatusNotAcceptable).JSON(models.ErrorResponse{
+				Error: "none of the accepted media types can be produced; supported types are " + strings.Join(supported, ", "),
+				Code:  models.ErrorCodeNotAcceptable,
+			})
+		}
+
+		c.Locals(formatKey, format)
+		return c.Next()
+	}
+}
+
+// mediaRange is one entry of an Accept header
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiate returns the offered format the client prefers, or nil
+func negotiate(accept string, offered []*Format) *Format {
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	// Equal preferences keep the client's order
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, r := range ranges {
+		if r.mediaType == "*/*" {
+			return offered[0]
+		}
+		for _, f := range offered {
+			if f.matches(r.mediaType) {
+				return f
+			}
+			if typ, ok := strings.CutSuffix(r.mediaType, "/*"); ok && f.hasType(typ) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// As renders responses in format whatever the Accept header, for routes
+// whose path names the format, such as /users/1.vcf
+func As(format *Format) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(formatKey, format)
+		return c.Next()
+	}
+}
+
+// FormatOf returns the format chosen by Negotiate or As, or nil when
+// neither ran
+func FormatOf(c *fiber.Ctx) *Format {
+	format, _ := c.Locals(formatKey).(*Format)
+	return format
+}
+
+// Respond writes v with status in the format chosen by Negotiate or As, or
+// JSON when neither ran
+func Respond(c *fiber.Ctx, status int, v any) error {
+	format := FormatOf(c)
+	if format == nil || format == JSON || (format.errorsAsJSON && status >= fiber.StatusBadRequest) {
+		return c.Status(status).JSON(v)
+	}
+
+	body, err := format.encode(v)
+	if err != nil {
+		return fmt.Errorf("failed to render %s response: %w", format.MediaType, err)
+	}
+
+	contentType := format.contentType
+	if contentType == "" {
+		contentType = format.MediaType
+	}
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(status).Send(body)
+}
+
+// Bind decodes the request body into v according to Content-Type. Bodies
+// without a Content-Type are read as JSON, and form bodies are left to
+// Fiber's body parser. It returns ErrUnsupportedMediaType for other types.
+func Bind(c *fiber.Ctx, v any) error {
+	contentType := c.Get(fiber.HeaderContentType)
+	if contentType == "" {
+		return fromTree(decodeJSON(c.Body()), v)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ErrUnsupportedMediaType
+	}
+	if mediaType == fiber.MIMEApplicationForm || mediaType == fiber.MIMEMultipartForm {
+		return c.BodyParser(v)
+	}
+
+	for _, f := range formats {
+		if !f.matches(mediaType) {
+			continue
+		}
+		tree, err := f.decode(c.Body())
+		if err != nil {
+			return fmt.Errorf("invalid %s body: %w", f.MediaType, err)
+		}
+		return fromTree(tree, v)
+	}
+	return ErrUnsupportedMediaType
+}
+
+// decodeJSON returns a JSON body as a tree fromTree accepts
+func decodeJSON(body []byte) any {
+	tree, _ := JSON.decode(body)
+	return tree
+}
+
+// MediaTypes returns the media types of formats, for documentation
+func MediaTypes(formats ...*Format) []string {
+	types := make([]string, len(formats))
+	for i, f := range formats {
+		types[i] = f.MediaType
+	}
+	return types
+}
@@ -0,0 +1,118 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	offered := []*Format{JSON, XML, YAML, CSV}
+
+	tests := []struct {
+		accept string
+		want   *Format
+	}{
+		{"", JSON},
+		{"*/*", JSON},
+		{"application/xml", XML},
+		{"text/xml, application/json", XML},
+		{"application/json;q=0.5, application/x-yaml", YAML},
+		{"text/*", XML},
+		{"text/*;q=0.5, text/csv", CSV},
+		{"application/vnd.user-profile.v2+json", JSON},
+		{"text/csv;q=0, */*;q=0.1", JSON},
+		{"text/html", nil},
+		{"application/msgpack", nil},
+	}
+	for _, tt := range tests {
+		if got := negotiate(tt.accept, offered); got != tt.want {
+			t.Errorf("negotiate(%q) = %v; want %v", tt.accept, got, tt.want)
+		}
+	}
+}
+
+type testUser struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+	Note *string  `json:"note"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	users := []testUser{
+		{ID: 1, Name: "Alice", Tags: []string{"admin"}},
+		{ID: 2, Name: "Bob <&>", Tags: []string{}},
+	}
+
+	for _, f := range []*Format{JSON, YAML, MessagePack} {
+		body, err := f.encode(users)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", f.MediaType, err)
+		}
+		tree, err := f.decode(body)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", f.MediaType, err)
+		}
+		var got []testUser
+		if err := fromTree(tree, &got); err != nil {
+			t.Fatalf("%s: %v", f.MediaType, err)
+		}
+		if !reflect.DeepEqual(got, users) {
+			t.Errorf("%s round trip = %+v; want %+v", f.MediaType, got, users)
+		}
+	}
+}
+
+func TestTextFormats(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+		DOB  string `json:"dob"`
+	}
+	users := []user{{"Alice", "1990-05-15"}, {"Bob, Jr.", "1985-01-02"}}
+
+	// XML and CSV read every value as a string
+	for _, f := range []*Format{XML, CSV} {
+		body, err := f.encode(users)
+		if err != nil {
+			t.Fatalf("%s: encode: %v", f.MediaType, err)
+		}
+		tree, err := f.decode(body)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", f.MediaType, err)
+		}
+		var got []user
+		if err := fromTree(tree, &got); err != nil {
+			t.Fatalf("%s: %v", f.MediaType, err)
+		}
+		if !reflect.DeepEqual(got, users) {
+			t.Errorf("%s round trip = %+v; want %+v", f.MediaType, got, users)
+		}
+	}
+
+	body, _ := CSV.encode(users)
+	if want := "name,dob\nAlice,1990-05-15\n\"Bob, Jr.\",1985-01-02\n"; string(body) != want {
+		t.Errorf("CSV = %q; want %q", body, want)
+	}
+
+	body, _ = CSV.encode([]user{{"=1+2", "1990-05-15"}, {"@SUM(A1)", ""}, {"-cmd", ""}, {"+x", ""}, {"\tTab", ""}})
+	if want := "name,dob\n'=1+2,1990-05-15\n'@SUM(A1),\n'-cmd,\n'+x,\n'\tTab,\n"; string(body) != want {
+		t.Errorf("CSV with formulas = %q; want %q", body, want)
+	}
+
+	type reading struct {
+		Name  string  `json:"name"`
+		Delta int     `json:"delta"`
+		Ratio float64 `json:"ratio"`
+	}
+	body, _ = CSV.encode([]reading{{"-5", -5, -0.5}, {"+2", 2, 1e-7}})
+	if want := "name,delta,ratio\n'-5,-5,-0.5\n'+2,2,1e-7\n"; string(body) != want {
+		t.Errorf("CSV with numbers = %q; want %q", body, want)
+	}
+
+	body, _ = VCard.encode(users)
+	want := "BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Alice\r\nBDAY:19900515\r\nEND:VCARD\r\n" +
+		"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Bob\\, Jr.\r\nBDAY:19850102\r\nEND:VCARD\r\n"
+	if string(body) != want {
+		t.Errorf("vCard = %q; want %q", body, want)
+	}
+}
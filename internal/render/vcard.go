@@ -0,0 +1,53 @@
+package render
+
+import (
+	"errors"
+	"time"
+
+	"user-profile-api/internal/calendar"
+)
+
+// encodeVCard writes a user, or a list of users, as vCards. The name field
+// becomes FN, dob becomes BDAY and id the UID; fields left out by a sparse
+// fieldset are left out of the cards. FN is required, so handlers keep the
+// name in sparse fieldsets of vCards.
+func encodeVCard(v any) ([]byte, error) {
+	if t, ok := v.(Tabular); ok {
+		v = t.Rows()
+	}
+	tree, err := toTree(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []object
+	switch t := tree.(type) {
+	case object:
+		users = []object{t}
+	case []any:
+		for _, item := range t {
+			user, ok := item.(object)
+			if !ok {
+				return nil, errors.New("vCards can only be written for objects")
+			}
+			users = append(users, user)
+		}
+	default:
+		return nil, errors.New("only users and lists of users can be written as vCards")
+	}
+
+	cards := make([]calendar.Card, len(users))
+	for i, user := range users {
+		for _, m := range user {
+			switch m.key {
+			case "id":
+				cards[i].UID = "urn:user-profile-api:user:" + scalarString(m.value)
+			case "name":
+				cards[i].Name = scalarString(m.value)
+			case "dob":
+				cards[i].Birthday, _ = time.Parse(time.DateOnly, scalarString(m.value))
+			}
+		}
+	}
+	return calendar.EncodeCards(cards), nil
+}
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strconv"
+	"sync"
+	"time"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/pgnotify"
+	"user-profile-api/internal/requestctx"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheInvalidationChannel is the LISTEN/NOTIFY channel used to invalidate
+// cached users across instances
+const CacheInvalidationChannel = "user_cache_invalidation"
+
+// cacheMetrics is published under "user_cache" at /admin/metrics
+var cacheMetrics = expvar.NewMap("user_cache")
+
+// CacheOptions configures the caching repository
+type CacheOptions struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// CachedRepository decorates a Repository with an in-process LRU cache for
+// GetUserByID. Concurrent misses for the same ID share one query on the
+// primary, misses are cached briefly, and writes invalidate the entry locally
+// and, when a broadcast pool is set, on every other instance via NOTIFY.
+// Requests pinned to the primary bypass the cache.
+type CachedRepository struct {
+	next      Repository
+	cache     *lruCache
+	opts      CacheOptions
+	group     singleflight.Group
+	broadcast *pgxpool.Pool
+	logger    *zap.Logger
+
+	// mu orders loads against invalidations of the same ID
+	mu    sync.Mutex
+	loads map[int32]*loading
+}
+
+// loading tracks the loads of one ID in flight. Invalidations bump the
+// generation so that a load started before them does not cache what it read.
+type loading struct {
+	generation uint64
+	count      int
+}
+
+// NewCachedRepository creates a caching decorator around next
+func NewCachedRepository(next Repository, opts CacheOptions, logger *zap.Logger) *CachedRepository {
+	return &CachedRepository{
+		next:   next,
+		cache:  newLRUCache(opts.Size),
+		opts:   opts,
+		logger: logger,
+		loads:  make(map[int32]*loading),
+	}
+}
+
+// EnableBroadcast publishes invalidations through pool and applies those
+// published by other instances until ctx is done
+func (r *CachedRepository) EnableBroadcast(ctx context.Context, pool *pgxpool.Pool) {
+	r.broadcast = pool
+
+	listener := pgnotify.NewListener(pool, r.logger)
+	go listener.Listen(ctx, CacheInvalidationChannel,
+		func(payload string) {
+			id, err := strconv.ParseInt(payload, 10, 32)
+			if err != nil {
+				r.logger.Warn("invalid cache invalidation payload", zap.String("payload", payload))
+				return
+			}
+			r.forget(int32(id))
+			cacheMetrics.Add("remote_invalidations", 1)
+		},
+		// Invalidations may have been missed while disconnected
+		r.forgetAll,
+	)
+}
+
+// CreateUser creates a user and drops any cached miss for its ID
+func (r *CachedRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	user, err := r.next.CreateUser(ctx, name, dob, timezone, updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	r.invalidate(ctx, user.ID)
+	return user, nil
+}
+
+// GetUserByID serves the user from the cache, loading it on a miss
+func (r *CachedRepository) GetUserByID(ctx context.Context, id int32) (*User, error) {
+	if requestctx.PrimaryPinned(ctx) {
+		cacheMetrics.Add("bypassed", 1)
+		return r.next.GetUserByID(ctx, id)
+	}
+
+	if user, ok := r.cache.get(id); ok {
+		if user == nil {
+			cacheMetrics.Add("negative_hits", 1)
+			return nil, ErrUserNotFound
+		}
+		cacheMetrics.Add("hits", 1)
+		copied := *user
+		return &copied, nil
+	}
+	cacheMetrics.Add("misses", 1)
+
+	value, err, shared := r.group.Do(strconv.Itoa(int(id)), func() (any, error) {
+		generation := r.beginLoad(id)
+
+		// The load is shared, so one caller going away must not fail the
+		// others. It reads the primary, since a lagging replica would fill
+		// the cache with a row older than the last invalidation.
+		user, err := r.next.GetUserByID(requestctx.WithPrimaryPin(context.WithoutCancel(ctx)), id)
+		switch {
+		case err == nil:
+			r.endLoad(id, generation, user, r.opts.TTL)
+		case errors.Is(err, ErrUserNotFound):
+			r.endLoad(id, generation, nil, r.opts.NegativeTTL)
+		default:
+			r.endLoad(id, generation, nil, 0)
+		}
+		return user, err
+	})
+	if shared {
+		cacheMetrics.Add("coalesced", 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *value.(*User)
+	return &copied, nil
+}
+
+// GetUsersByIDs serves cached users and loads the rest in one query
+func (r *CachedRepository) GetUsersByIDs(ctx context.Context, ids []int32) ([]User, error) {
+	if requestctx.PrimaryPinned(ctx) {
+		cacheMetrics.Add("bypassed", 1)
+		return r.next.GetUsersByIDs(ctx, ids)
+	}
+
+	users := make([]User, 0, len(ids))
+	var missing []int32
+	for _, id := range ids {
+		user, ok := r.cache.get(id)
+		switch {
+		case !ok:
+			missing = append(missing, id)
+		case user == nil:
+			cacheMetrics.Add("negative_hits", 1)
+		default:
+			cacheMetrics.Add("hits", 1)
+			users = append(users, *user)
+		}
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+	cacheMetrics.Add("misses", int64(len(missing)))
+
+	generations := make([]uint64, len(missing))
+	for i, id := range missing {
+		generations[i] = r.beginLoad(id)
+	}
+
+	loaded, err := r.next.GetUsersByIDs(requestctx.WithPrimaryPin(ctx), missing)
+	if err != nil {
+		for i, id := range missing {
+			r.endLoad(id, generations[i], nil, 0)
+		}
+		return nil, err
+	}
+
+	found := make(map[int32]*User, len(loaded))
+	for i := range loaded {
+		copied := loaded[i]
+		found[copied.ID] = &copied
+	}
+	for i, id := range missing {
+		if user := found[id]; user != nil {
+			r.endLoad(id, generations[i], user, r.opts.TTL)
+		} else {
+			r.endLoad(id, generations[i], nil, r.opts.NegativeTTL)
+		}
+	}
+
+	return append(users, loaded...), nil
+}
+
+// UpdateUser updates a user and invalidates its cache entry
+func (r *CachedRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	user, err := r.next.UpdateUser(ctx, id, name, dob, timezone, updatedAt)
+	r.invalidate(ctx, id)
+	return user, err
+}
+
+// DeleteUser deletes a user and invalidates its cache entry
+func (r *CachedRepository) DeleteUser(ctx context.Context, id int32) error {
+	err := r.next.DeleteUser(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+// ListUsers is not cached
+func (r *CachedRepository) ListUsers(ctx context.Context, limit, offset int32) ([]User, error) {
+	return r.next.ListUsers(ctx, limit, offset)
+}
+
+// SearchUsers is not cached
+func (r *CachedRepository) SearchUsers(ctx context.Context, filter UserFilter, afterID, limit int32) ([]User, error) {
+	return r.next.SearchUsers(ctx, filter, afterID, limit)
+}
+
+// ListBirthdays is not cached
+func (r *CachedRepository) ListBirthdays(ctx context.Context, rng BirthdayRange, limit, offset int32) ([]User, error) {
+	return r.next.ListBirthdays(ctx, rng, limit, offset)
+}
+
+// CountUsers is not cached
+func (r *CachedRepository) CountUsers(ctx context.Context) (int64, error) {
+	return r.next.CountUsers(ctx)
+}
+
+// RecordEvent is not cached
+func (r *CachedRepository) RecordEvent(ctx context.Context, event *events.Event) error {
+	return r.next.RecordEvent(ctx, event)
+}
+
+// WithTx runs fn in a transaction on the underlying repository. Calls made
+// through the transaction bypass the cache, and the users it writes are
+// invalidated once it commits.
+func (r *CachedRepository) WithTx(ctx context.Context, fn func(Repository) error, opts ...TxOption) error {
+	var written []int32
+	err := r.next.WithTx(ctx, func(tx Repository) error {
+		// A retried transaction starts over
+		written = written[:0]
+		return fn(&txCachedRepository{Repository: tx, written: &written})
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range written {
+		r.invalidate(ctx, id)
+	}
+	return nil
+}
+
+// txCachedRepository records the users written inside a transaction so the
+// cache can be invalidated after commit. Reads pass straight through, since
+// the transaction must see its own uncommitted writes.
+type txCachedRepository struct {
+	Repository
+	written *[]int32
+}
+
+func (r *txCachedRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	user, err := r.Repository.CreateUser(ctx, name, dob, timezone, updatedAt)
+	if err == nil {
+		*r.written = append(*r.written, user.ID)
+	}
+	return user, err
+}
+
+func (r *txCachedRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	*r.written = append(*r.written, id)
+	return r.Repository.UpdateUser(ctx, id, name, dob, timezone, updatedAt)
+}
+
+func (r *txCachedRepository) DeleteUser(ctx context.Context, id int32) error {
+	*r.written = append(*r.written, id)
+	return r.Repository.DeleteUser(ctx, id)
+}
+
+// WithTx opens a savepoint; its writes are invalidated with the outer
+// transaction's even if it rolls back, which is harmless
+func (r *txCachedRepository) WithTx(ctx context.Context, fn func(Repository) error, opts ...TxOption) error {
+	return r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(&txCachedRepository{Repository: tx, written: r.written})
+	}, opts...)
+}
+
+// store caches user (or a miss) for ttl; a zero ttl disables caching
+func (r *CachedRepository) store(id int32, user *User, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	if evicted := r.cache.set(id, user, ttl); evicted {
+		cacheMetrics.Add("evictions", 1)
+	}
+}
+
+// beginLoad registers a load of id and returns the generation it started at
+func (r *CachedRepository) beginLoad(id int32) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.loads[id]
+	if l == nil {
+		l = &loading{}
+		r.loads[id] = l
+	}
+	l.count++
+	return l.generation
+}
+
+// endLoad unregisters a load of id and caches its result for ttl, unless id
+// was invalidated after the load began
+func (r *CachedRepository) endLoad(id int32, generation uint64, user *User, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.loads[id]
+	if l.generation == generation {
+		r.store(id, user, ttl)
+	} else if ttl > 0 {
+		cacheMetrics.Add("stale_loads", 1)
+	}
+
+	if l.count--; l.count == 0 {
+		delete(r.loads, id)
+	}
+}
+
+// forget drops id from the cache and from any load in flight
+func (r *CachedRepository) forget(id int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if l := r.loads[id]; l != nil {
+		l.generation++
+	}
+	r.cache.delete(id)
+}
+
+// forgetAll empties the cache and keeps loads in flight out of it
+func (r *CachedRepository) forgetAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range r.loads {
+		l.generation++
+	}
+	r.cache.purge()
+}
+
+// invalidate drops id locally and tells other instances to do the same
+func (r *CachedRepository) invalidate(ctx context.Context, id int32) {
+	r.forget(id)
+	cacheMetrics.Add("invalidations", 1)
+
+	if r.broadcast == nil {
+		return
+	}
+	if err := pgnotify.Notify(ctx, r.broadcast, CacheInvalidationChannel, strconv.Itoa(int(id))); err != nil {
+		logger.FromContext(ctx, r.logger).Warn("failed to broadcast cache invalidation", zap.Int32("id", id), zap.Error(err))
+	}
+}
@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// countingRepository serves a fixed set of users and counts lookups
+type countingRepository struct {
+	Repository
+	mu    sync.Mutex
+	users map[int32]User
+	gets  atomic.Int32
+	delay time.Duration
+	// hold, when set, receives a value once a lookup has read the user and
+	// must send one back before the lookup returns
+	hold chan struct{}
+	// pinned counts the lookups pinned to the primary
+	pinned atomic.Int32
+}
+
+func (r *countingRepository) GetUserByID(ctx context.Context, id int32) (*User, error) {
+	r.gets.Add(1)
+	if requestctx.PrimaryPinned(ctx) {
+		r.pinned.Add(1)
+	}
+	time.Sleep(r.delay)
+
+	r.mu.Lock()
+	user, ok := r.users[id]
+	r.mu.Unlock()
+
+	if r.hold != nil {
+		r.hold <- struct{}{}
+		<-r.hold
+	}
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *countingRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := User{ID: id, Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users[id] = user
+	return &user, nil
+}
+
+func (r *countingRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	return r.UpdateUser(ctx, int32(len(r.users)+1), name, dob, timezone, updatedAt)
+}
+
+// WithTx runs fn directly; the stub has no real transactions
+func (r *countingRepository) WithTx(ctx context.Context, fn func(Repository) error, opts ...TxOption) error {
+	return fn(r)
+}
+
+func newTestCache(next Repository) *CachedRepository {
+	return NewCachedRepository(next, CacheOptions{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute}, zap.NewNop())
+}
+
+func TestCachedRepositoryServesHits(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1, Name: "Alice"}}}
+	repo := newTestCache(next)
+
+	for i := 0; i < 3; i++ {
+		user, err := repo.GetUserByID(context.Background(), 1)
+		if err != nil || user.Name != "Alice" {
+			t.Fatalf("GetUserByID() = %v, %v; want Alice", user, err)
+		}
+	}
+
+	if got := next.gets.Load(); got != 1 {
+		t.Errorf("underlying lookups = %d; want 1", got)
+	}
+}
+
+func TestCachedRepositoryInvalidatesOnWrite(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1, Name: "Alice"}}}
+	repo := newTestCache(next)
+	ctx := context.Background()
+
+	if _, err := repo.GetUserByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.UpdateUser(ctx, 1, "Alicia", time.Time{}, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := repo.GetUserByID(ctx, 1)
+	if err != nil || user.Name != "Alicia" {
+		t.Errorf("GetUserByID() after update = %v, %v; want Alicia", user, err)
+	}
+}
+
+func TestCachedRepositoryInvalidatesAfterCommit(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1, Name: "Alice"}}}
+	repo := newTestCache(next)
+	ctx := context.Background()
+
+	if _, err := repo.GetUserByID(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed transaction leaves the cache alone
+	err := repo.WithTx(ctx, func(tx Repository) error {
+		if _, err := tx.UpdateUser(ctx, 1, "Alicia", time.Time{}, "", time.Time{}); err != nil {
+			return err
+		}
+		return fmt.Errorf("rollback")
+	})
+	if err == nil {
+		t.Fatal("WithTx() error = nil; want rollback")
+	}
+	if user, _ := repo.GetUserByID(ctx, 1); user.Name != "Alice" {
+		t.Errorf("cached name after rollback = %q; want Alice", user.Name)
+	}
+
+	err = repo.WithTx(ctx, func(tx Repository) error {
+		_, err := tx.UpdateUser(ctx, 1, "Alicia", time.Time{}, "", time.Time{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, _ := repo.GetUserByID(ctx, 1); user.Name != "Alicia" {
+		t.Errorf("cached name after commit = %q; want Alicia", user.Name)
+	}
+}
+
+func TestCachedRepositoryCachesMisses(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{}}
+	repo := newTestCache(next)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetUserByID(ctx, 1); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("GetUserByID() error = %v; want user not found", err)
+		}
+	}
+	if got := next.gets.Load(); got != 1 {
+		t.Errorf("underlying lookups = %d; want 1", got)
+	}
+
+	// Creating the user clears the cached miss
+	if _, err := repo.CreateUser(ctx, "Bob", time.Time{}, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetUserByID(ctx, 1); err != nil {
+		t.Errorf("GetUserByID() after create error = %v", err)
+	}
+}
+
+func TestCachedRepositoryCoalescesMisses(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1}}, delay: 50 * time.Millisecond}
+	repo := newTestCache(next)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.GetUserByID(context.Background(), 1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := next.gets.Load(); got != 1 {
+		t.Errorf("underlying lookups = %d; want 1", got)
+	}
+}
+
+func TestCachedRepositoryDropsLoadsRacingInvalidation(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1, Name: "Alice"}}, hold: make(chan struct{})}
+	repo := newTestCache(next)
+	ctx := context.Background()
+
+	done := make(chan *User)
+	go func() {
+		user, err := repo.GetUserByID(ctx, 1)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- user
+	}()
+
+	// The load has read Alice; the update lands before it returns
+	<-next.hold
+	if _, err := repo.UpdateUser(ctx, 1, "Alicia", time.Time{}, "", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	next.hold <- struct{}{}
+	<-done
+	next.hold = nil
+
+	user, err := repo.GetUserByID(ctx, 1)
+	if err != nil || user.Name != "Alicia" {
+		t.Errorf("GetUserByID() after racing update = %v, %v; want Alicia", user, err)
+	}
+}
+
+func TestCachedRepositoryReadsPrimary(t *testing.T) {
+	next := &countingRepository{users: map[int32]User{1: {ID: 1, Name: "Alice"}}}
+	repo := newTestCache(next)
+
+	// Misses load from the primary
+	if _, err := repo.GetUserByID(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := next.pinned.Load(); got != 1 {
+		t.Errorf("pinned lookups = %d; want 1", got)
+	}
+
+	// Pinned requests bypass the cache
+	pinned := requestctx.WithPrimaryPin(context.Background())
+	for i := 0; i < 2; i++ {
+		if _, err := repo.GetUserByID(pinned, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := next.gets.Load(); got != 3 {
+		t.Errorf("underlying lookups = %d; want 3", got)
+	}
+}
+
+func TestLRUCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.set(1, &User{ID: 1}, time.Minute)
+	cache.set(2, &User{ID: 2}, time.Minute)
+	cache.get(1)
+
+	if evicted := cache.set(3, &User{ID: 3}, time.Minute); !evicted {
+		t.Error("set() evicted = false; want true")
+	}
+	if _, ok := cache.get(2); ok {
+		t.Error("entry 2 still cached; want evicted")
+	}
+	if _, ok := cache.get(1); !ok {
+		t.Error("entry 1 evicted; want kept as recently used")
+	}
+}
+
+func TestLRUCacheExpiresEntries(t *testing.T) {
+	now := time.Now()
+	cache := newLRUCache(2)
+	cache.now = func() time.Time { return now }
+	cache.set(1, &User{ID: 1}, time.Second)
+
+	now = now.Add(2 * time.Second)
+	if _, ok := cache.get(1); ok {
+		t.Error("expired entry returned")
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/pgnotify"
+	"user-profile-api/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// RecordEvent appends event to the outbox and publishes it on events.Channel.
+// Called inside WithTx, the event is only stored and published if the
+// transaction commits. The ID, time and request ID are filled in.
+func (r *PostgresRepository) RecordEvent(ctx context.Context, event *events.Event) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+	event.RequestID = requestctx.RequestID(ctx)
+
+	query := `INSERT INTO outbox_events (event_type, user_id, data, request_id)
+		VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, occurred_at`
+
+	err = r.db().QueryRow(ctx, r.annotate(ctx, query), event.Type, event.UserID, data, event.RequestID).
+		Scan(&event.ID, &event.OccurredAt)
+	if err != nil {
+		r.log(ctx).Error("failed to record event", zap.Error(err), zap.String("type", event.Type))
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+
+	payload, err := event.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if err := pgnotify.Notify(ctx, r.db(), events.Channel, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruEntry is a cached user; a nil user records a known miss
+type lruEntry struct {
+	id      int32
+	user    *User
+	expires time.Time
+}
+
+// lruCache is a size-bounded, TTL-aware LRU cache of users keyed by ID
+type lruCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is most recently used
+	entries map[int32]*list.Element
+	now     func() time.Time
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int32]*list.Element, size),
+		now:     time.Now,
+	}
+}
+
+// get returns the cached entry for id. ok is false when the entry is absent
+// or expired.
+func (c *lruCache) get(id int32) (user *User, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[id]
+	if !found {
+		return nil, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if c.now().After(entry.expires) {
+		c.removeElement(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.user, true
+}
+
+// set stores user (or a miss when nil) for ttl and reports whether an entry
+// was evicted to make room
+func (c *lruCache) set(id int32, user *User, ttl time.Duration) (evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if elem, found := c.entries[id]; found {
+		entry := elem.Value.(*lruEntry)
+		entry.user = user
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return false
+	}
+
+	c.entries[id] = c.order.PushFront(&lruEntry{id: id, user: user, expires: expires})
+	if c.order.Len() > c.size {
+		c.removeElement(c.order.Back())
+		return true
+	}
+	return false
+}
+
+// delete removes id from the cache
+func (c *lruCache) delete(id int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.entries[id]; found {
+		c.removeElement(elem)
+	}
+}
+
+// purge removes every entry
+func (c *lruCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.entries = make(map[int32]*list.Element, c.size)
+}
+
+func (c *lruCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).id)
+}
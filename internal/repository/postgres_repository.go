@@ -2,119 +2,266 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"user-profile-api/internal/logger"
+	"user-profile-api/internal/requestctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+// querier is the subset of pgx shared by pools, connections and transactions
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// primaryPool is the subset of pgxpool.Pool used for the primary database
+type primaryPool interface {
+	querier
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
 // PostgresRepository implements Repository interface using PostgreSQL
 type PostgresRepository struct {
-	pool   *pgxpool.Pool
-	logger *zap.Logger
+	pool          primaryPool
+	tx            pgx.Tx
+	replicas      *ReplicaSet
+	logger        *zap.Logger
+	queryComments bool
+	queryTimeout  time.Duration
+}
+
+// Option configures a PostgresRepository
+type Option func(*PostgresRepository)
+
+// WithQueryComments appends the request and trace IDs to every statement as
+// an SQL comment, so entries in pg_stat_activity and the slow query log can
+// be traced back to the originating request
+func WithQueryComments(enabled bool) Option {
+	return func(r *PostgresRepository) {
+		r.queryComments = enabled
+	}
+}
+
+// WithQueryTimeout bounds every repository call by the given deadline.
+// Zero leaves the caller's context untouched.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(r *PostgresRepository) {
+		r.queryTimeout = timeout
+	}
+}
+
+// WithReplicas serves reads from healthy replicas in the set, falling back
+// to the primary pool
+func WithReplicas(replicas *ReplicaSet) Option {
+	return func(r *PostgresRepository) {
+		r.replicas = replicas
+	}
 }
 
 // NewPostgresRepository creates a new PostgreSQL repository
-func NewPostgresRepository(pool *pgxpool.Pool, logger *zap.Logger) *PostgresRepository {
-	return &PostgresRepository{
+func NewPostgresRepository(pool *pgxpool.Pool, logger *zap.Logger, opts ...Option) *PostgresRepository {
+	r := &PostgresRepository{
 		pool:   pool,
 		logger: logger,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// log returns the request-scoped logger
+func (r *PostgresRepository) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, r.logger)
+}
+
+// withTimeout applies the per-query deadline to ctx
+func (r *PostgresRepository) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.queryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, r.queryTimeout)
+}
+
+// db returns the transaction the repository is bound to, or the primary pool
+func (r *PostgresRepository) db() querier {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.pool
+}
+
+// read runs a read-only query on a healthy replica, unless the request is
+// pinned to the primary or runs in a transaction. If the replica fails, it
+// is taken out of rotation and the query is retried on the primary.
+func (r *PostgresRepository) read(ctx context.Context, fn func(db querier) error) error {
+	if r.tx == nil && !requestctx.PrimaryPinned(ctx) {
+		if replica := r.replicas.pick(); replica != nil {
+			err := fn(replica.pool)
+			if err == nil || errors.Is(err, pgx.ErrNoRows) || ctx.Err() != nil {
+				return err
+			}
+
+			r.replicas.markUnhealthy(replica, err)
+			r.log(ctx).Warn("replica read failed, falling back to primary", zap.String("replica", replica.name), zap.Error(err))
+		}
+	}
+
+	return fn(r.db())
+}
+
+// annotate tags the query with the request context when query comments are enabled
+func (r *PostgresRepository) annotate(ctx context.Context, query string) string {
+	if !r.queryComments {
+		return query
+	}
+	return query + queryComment(ctx)
 }
 
-// CreateUser creates a new user in the database
-func (r *PostgresRepository) CreateUser(ctx context.Context, name string, dob time.Time) (*User, error) {
-	query := `INSERT INTO users (name, dob) VALUES ($1, $2) RETURNING id, name, dob`
+// userColumns are the columns scanned into a User. The time zone needs
+// db/migrations/007_add_users_timezone.sql, and updated_at
+// db/migrations/008_add_users_updated_at.sql.
+const userColumns = `id, name, dob, COALESCE(timezone, ''), updated_at`
+
+// CreateUser creates a new user in the database, updated at updatedAt. An
+// empty timezone is stored as NULL.
+func (r *PostgresRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO users (name, dob, timezone, updated_at) VALUES ($1, $2, NULLIF($3, ''), $4) RETURNING ` + userColumns
 	
 	var user User
-	err := r.pool.QueryRow(ctx, query, name, dob).Scan(&user.ID, &user.Name, &user.DOB)
+	err := r.db().QueryRow(ctx, r.annotate(ctx, query), name, dob, timezone, updatedAt).Scan(&user.ID, &user.Name, &user.DOB, &user.Timezone, &user.UpdatedAt)
 	if err != nil {
-		r.logger.Error("failed to create user", zap.Error(err), zap.String("name", name))
+		r.log(ctx).Error("failed to create user", zap.Error(err), zap.String("name", name))
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	r.logger.Info("user created", zap.Int32("id", user.ID), zap.String("name", user.Name))
+	r.log(ctx).Info("user created", zap.Int32("id", user.ID), zap.String("name", user.Name))
 	return &user, nil
 }
 
 // GetUserByID retrieves a user by ID
 func (r *PostgresRepository) GetUserByID(ctx context.Context, id int32) (*User, error) {
-	query := `SELECT id, name, dob FROM users WHERE id = $1`
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
 	
 	var user User
-	err := r.pool.QueryRow(ctx, query, id).Scan(&user.ID, &user.Name, &user.DOB)
+	err := r.read(ctx, func(db querier) error {
+		return db.QueryRow(ctx, r.annotate(ctx, query), id).Scan(&user.ID, &user.Name, &user.DOB, &user.Timezone, &user.UpdatedAt)
+	})
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, fmt.Errorf("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
-		r.logger.Error("failed to get user", zap.Error(err), zap.Int32("id", id))
+		r.log(ctx).Error("failed to get user", zap.Error(err), zap.Int32("id", id))
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	return &user, nil
 }
 
-// UpdateUser updates an existing user
-func (r *PostgresRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time) (*User, error) {
-	query := `UPDATE users SET name = $1, dob = $2 WHERE id = $3 RETURNING id, name, dob`
+// GetUsersByIDs retrieves the users with the given IDs, in no particular
+// order. Unknown IDs are skipped.
+func (r *PostgresRepository) GetUsersByIDs(ctx context.Context, ids []int32) ([]User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ANY($1)`
+
+	users, err := r.queryUsers(ctx, query, ids)
+	if err != nil {
+		r.log(ctx).Error("failed to get users", zap.Error(err), zap.Int("count", len(ids)))
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	return users, nil
+}
+
+// UpdateUser updates an existing user, at updatedAt. An empty timezone is
+// stored as NULL.
+func (r *PostgresRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE users SET name = $1, dob = $2, timezone = NULLIF($3, ''), updated_at = $5 WHERE id = $4 RETURNING ` + userColumns
 	
 	var user User
-	err := r.pool.QueryRow(ctx, query, name, dob, id).Scan(&user.ID, &user.Name, &user.DOB)
+	err := r.db().QueryRow(ctx, r.annotate(ctx, query), name, dob, timezone, id, updatedAt).Scan(&user.ID, &user.Name, &user.DOB, &user.Timezone, &user.UpdatedAt)
 	if err != nil {
-		if err.Error() == "no rows in result set" {
-			return nil, fmt.Errorf("user not found")
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
-		r.logger.Error("failed to update user", zap.Error(err), zap.Int32("id", id))
+		r.log(ctx).Error("failed to update user", zap.Error(err), zap.Int32("id", id))
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
-	r.logger.Info("user updated", zap.Int32("id", user.ID), zap.String("name", user.Name))
+	r.log(ctx).Info("user updated", zap.Int32("id", user.ID), zap.String("name", user.Name))
 	return &user, nil
 }
 
 // DeleteUser deletes a user by ID
 func (r *PostgresRepository) DeleteUser(ctx context.Context, id int32) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM users WHERE id = $1`
 	
-	result, err := r.pool.Exec(ctx, query, id)
+	result, err := r.db().Exec(ctx, r.annotate(ctx, query), id)
 	if err != nil {
-		r.logger.Error("failed to delete user", zap.Error(err), zap.Int32("id", id))
+		r.log(ctx).Error("failed to delete user", zap.Error(err), zap.Int32("id", id))
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("user not found")
+		return ErrUserNotFound
 	}
 
-	r.logger.Info("user deleted", zap.Int32("id", id))
+	r.log(ctx).Info("user deleted", zap.Int32("id", id))
 	return nil
 }
 
 // ListUsers retrieves a list of users with pagination
 func (r *PostgresRepository) ListUsers(ctx context.Context, limit, offset int32) ([]User, error) {
-	query := `SELECT id, name, dob FROM users ORDER BY id LIMIT $1 OFFSET $2`
-	
-	rows, err := r.pool.Query(ctx, query, limit, offset)
-	if err != nil {
-		r.logger.Error("failed to list users", zap.Error(err))
-		return nil, fmt.Errorf("failed to list users: %w", err)
-	}
-	defer rows.Close()
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY id LIMIT $1 OFFSET $2`
+	
 	var users []User
-	for rows.Next() {
-		var user User
-		if err := rows.Scan(&user.ID, &user.Name, &user.DOB); err != nil {
-			r.logger.Error("failed to scan user", zap.Error(err))
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+	err := r.read(ctx, func(db querier) error {
+		users = nil
+
+		rows, err := db.Query(ctx, r.annotate(ctx, query), limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user User
+			if err := rows.Scan(&user.ID, &user.Name, &user.DOB, &user.Timezone, &user.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, user)
 		}
-		users = append(users, user)
-	}
 
-	if err := rows.Err(); err != nil {
-		r.logger.Error("error iterating users", zap.Error(err))
-		return nil, fmt.Errorf("error iterating users: %w", err)
+		return rows.Err()
+	})
+	if err != nil {
+		r.log(ctx).Error("failed to list users", zap.Error(err))
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
 	// Return empty slice instead of nil if no users found
@@ -125,14 +272,122 @@ func (r *PostgresRepository) ListUsers(ctx context.Context, limit, offset int32)
 	return users, nil
 }
 
+// SearchUsers retrieves up to limit users matching filter with IDs greater
+// than afterID, ordered by ID
+func (r *PostgresRepository) SearchUsers(ctx context.Context, filter UserFilter, afterID, limit int32) ([]User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE id > $1`
+	args := []any{afterID}
+	if filter.NameContains != "" {
+		args = append(args, filter.NameContains)
+		query += fmt.Sprintf(` AND strpos(lower(name), lower($%d)) > 0`, len(args))
+	}
+	if !filter.BornAfter.IsZero() {
+		args = append(args, filter.BornAfter)
+		query += fmt.Sprintf(` AND dob > $%d`, len(args))
+	}
+	if !filter.BornBefore.IsZero() {
+		args = append(args, filter.BornBefore)
+		query += fmt.Sprintf(` AND dob < $%d`, len(args))
+	}
+	if rng := filter.Birthdays; rng != nil {
+		args = append(args, rng.From, rng.To)
+		query += ` AND ` + birthdayMatch(*rng, fmt.Sprintf(`$%d`, len(args)-1), fmt.Sprintf(`$%d`, len(args)))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY id LIMIT $%d`, len(args))
+
+	users, err := r.queryUsers(ctx, query, args...)
+	if err != nil {
+		r.log(ctx).Error("failed to search users", zap.Error(err))
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return users, nil
+}
+
+// monthDay is the indexed birthday expression of idx_users_birthday
+const monthDay = `(EXTRACT(MONTH FROM dob)::int * 100 + EXTRACT(DAY FROM dob)::int)`
+
+// ListBirthdays retrieves a page of users with birthdays in rng, ordered by
+// the date they are observed from the start of the range, then by ID
+func (r *PostgresRepository) ListBirthdays(ctx context.Context, rng BirthdayRange, limit, offset int32) ([]User, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	match := birthdayMatch(rng, `$1`, `$2`)
+	leapDay := rng.LeapDay
+	if leapDay == 0 {
+		leapDay = 229
+	}
+	observed := `(CASE WHEN ` + monthDay + ` = 229 THEN $3 ELSE ` + monthDay + ` END)`
+
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + match +
+		` ORDER BY ` + observed + ` < $1, ` + observed + `, id LIMIT $4 OFFSET $5`
+
+	users, err := r.queryUsers(ctx, query, rng.From, rng.To, leapDay, limit, offset)
+	if err != nil {
+		r.log(ctx).Error("failed to list birthdays", zap.Error(err))
+		return nil, fmt.Errorf("failed to list birthdays: %w", err)
+	}
+
+	return users, nil
+}
+
+// birthdayMatch returns the condition matching the birthdays in rng, whose
+// From and To are bound to the placeholders from and to
+func birthdayMatch(rng BirthdayRange, from, to string) string {
+	match := monthDay + ` BETWEEN ` + from + ` AND ` + to
+	if rng.From > rng.To {
+		match = `(` + monthDay + ` >= ` + from + ` OR ` + monthDay + ` <= ` + to + `)`
+	}
+	if rng.LeapDay != 0 {
+		match = `(` + match + ` OR ` + monthDay + ` = 229)`
+	}
+	return match
+}
+
+// queryUsers runs a read query returning userColumns rows. The result is
+// never nil.
+func (r *PostgresRepository) queryUsers(ctx context.Context, query string, args ...any) ([]User, error) {
+	users := []User{}
+	err := r.read(ctx, func(db querier) error {
+		users = users[:0]
+
+		rows, err := db.Query(ctx, r.annotate(ctx, query), args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user User
+			if err := rows.Scan(&user.ID, &user.Name, &user.DOB, &user.Timezone, &user.UpdatedAt); err != nil {
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+	return users, err
+}
+
 // CountUsers returns the total number of users
 func (r *PostgresRepository) CountUsers(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT COUNT(*) FROM users`
 	
 	var count int64
-	err := r.pool.QueryRow(ctx, query).Scan(&count)
+	err := r.read(ctx, func(db querier) error {
+		return db.QueryRow(ctx, r.annotate(ctx, query)).Scan(&count)
+	})
 	if err != nil {
-		r.logger.Error("failed to count users", zap.Error(err))
+		r.log(ctx).Error("failed to count users", zap.Error(err))
 		return 0, fmt.Errorf("failed to count users: %w", err)
 	}
 
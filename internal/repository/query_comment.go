@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"user-profile-api/internal/requestctx"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryComment renders the request context as an sqlcommenter-style trailing
+// comment. Values are URL-encoded so client-supplied IDs cannot close the comment.
+func queryComment(ctx context.Context) string {
+	var tags []string
+
+	if requestID := requestctx.RequestID(ctx); requestID != "" {
+		tags = append(tags, "request_id='"+url.QueryEscape(requestID)+"'")
+	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		tags = append(tags, "trace_id='"+spanCtx.TraceID().String()+"'")
+	}
+
+	if len(tags) == 0 {
+		return ""
+	}
+	return " /*" + strings.Join(tags, ",") + "*/"
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// replicaLagQuery reports replication lag in seconds. A replica that has
+// replayed everything it received is considered current even if the primary
+// has been idle.
+const replicaLagQuery = `
+SELECT CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// replicaPool is the subset of pgxpool.Pool used for a replica
+type replicaPool interface {
+	querier
+	Close()
+}
+
+// replica is a read-only pool with its last observed health
+type replica struct {
+	name    string
+	pool    replicaPool
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanoseconds
+}
+
+// ReplicaSet routes reads across healthy read replicas
+type ReplicaSet struct {
+	replicas    []*replica
+	next        atomic.Uint64
+	maxLag      time.Duration
+	checkPeriod time.Duration
+	logger      *zap.Logger
+}
+
+// NewReplicaSet creates a replica set. Replicas start unhealthy until the
+// first lag check in Start succeeds.
+func NewReplicaSet(pools []*pgxpool.Pool, maxLag, checkPeriod time.Duration, logger *zap.Logger) *ReplicaSet {
+	set := &ReplicaSet{
+		maxLag:      maxLag,
+		checkPeriod: checkPeriod,
+		logger:      logger,
+	}
+	for _, pool := range pools {
+		cfg := pool.Config().ConnConfig
+		set.replicas = append(set.replicas, &replica{
+			name: cfg.Host,
+			pool: pool,
+		})
+	}
+	return set
+}
+
+// Start checks replica health immediately and then periodically until ctx is done
+func (s *ReplicaSet) Start(ctx context.Context) {
+	s.checkAll(ctx)
+
+	go func() {
+		ticker := time.NewTicker(s.checkPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.checkAll(ctx)
+			}
+		}
+	}()
+}
+
+// Close closes all replica pools
+func (s *ReplicaSet) Close() {
+	for _, r := range s.replicas {
+		r.pool.Close()
+	}
+}
+
+// pick returns the next healthy replica in round-robin order, or nil
+func (s *ReplicaSet) pick() *replica {
+	if s == nil {
+		return nil
+	}
+
+	n := len(s.replicas)
+	start := s.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := s.replicas[(start+uint64(i))%uint64(n)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// markUnhealthy takes a replica out of rotation until its next successful check
+func (s *ReplicaSet) markUnhealthy(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		s.logger.Warn("replica marked unhealthy", zap.String("replica", r.name), zap.Error(err))
+	}
+}
+
+// checkAll measures the lag of every replica and updates its health
+func (s *ReplicaSet) checkAll(ctx context.Context) {
+	for _, r := range s.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, s.checkPeriod)
+		var lagSeconds float64
+		err := r.pool.QueryRow(checkCtx, replicaLagQuery).Scan(&lagSeconds)
+		cancel()
+
+		if err != nil {
+			s.markUnhealthy(r, err)
+			continue
+		}
+
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		r.lag.Store(int64(lag))
+
+		healthy := lag <= s.maxLag
+		if was := r.healthy.Swap(healthy); was != healthy {
+			s.logger.Info("replica health changed",
+				zap.String("replica", r.name),
+				zap.Bool("healthy", healthy),
+				zap.Duration("lag", lag),
+			)
+		}
+	}
+}
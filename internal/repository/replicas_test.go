@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"user-profile-api/internal/requestctx"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// fakeQuerier answers every query with err and counts the calls
+type fakeQuerier struct {
+	err   error
+	calls int
+}
+
+func (f *fakeQuerier) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	f.calls++
+	return pgconn.CommandTag{}, f.err
+}
+
+func (f *fakeQuerier) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	f.calls++
+	return nil, f.err
+}
+
+func (f *fakeQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	f.calls++
+	return fakeRow{err: f.err}
+}
+
+func (f *fakeQuerier) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	return nil, errors.New("fakeQuerier does not begin transactions")
+}
+
+func (f *fakeQuerier) Close() {}
+
+// fakeRow is the result of a fakeQuerier query
+type fakeRow struct {
+	err error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	return r.err
+}
+
+// newReplicaSet returns a set of fake replicas with the given health
+func newReplicaSet(healthy ...bool) (*ReplicaSet, []*fakeQuerier) {
+	set := &ReplicaSet{logger: zap.NewNop()}
+	var fakes []*fakeQuerier
+	for i, ok := range healthy {
+		fake := &fakeQuerier{}
+		r := &replica{name: string(rune('a' + i)), pool: fake}
+		r.healthy.Store(ok)
+		set.replicas = append(set.replicas, r)
+		fakes = append(fakes, fake)
+	}
+	return set, fakes
+}
+
+// readOnce runs one read and returns the querier that served it
+func readOnce(t *testing.T, ctx context.Context, r *PostgresRepository) (querier, error) {
+	t.Helper()
+	var served querier
+	err := r.read(ctx, func(db querier) error {
+		served = db
+		return db.QueryRow(ctx, "SELECT 1").Scan()
+	})
+	return served, err
+}
+
+func TestReadRoundRobin(t *testing.T) {
+	primary := &fakeQuerier{}
+	replicas, fakes := newReplicaSet(true, false, true)
+	r := &PostgresRepository{pool: primary, replicas: replicas, logger: zap.NewNop()}
+
+	for i := 0; i < 4; i++ {
+		if _, err := readOnce(t, context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fakes[0].calls == 0 || fakes[1].calls != 0 || fakes[2].calls == 0 || primary.calls != 0 {
+		t.Errorf("calls = %d, %d, %d and %d on the primary; want only the healthy replicas to serve reads",
+			fakes[0].calls, fakes[1].calls, fakes[2].calls, primary.calls)
+	}
+}
+
+func TestReadFallsBackToPrimary(t *testing.T) {
+	tests := []struct {
+		name        string
+		replicaErr  error
+		healthy     bool
+		pinned      bool
+		wantPrimary bool
+		wantHealthy bool
+	}{
+		{"healthy replica", nil, true, false, false, true},
+		{"no rows is an answer", pgx.ErrNoRows, true, false, false, true},
+		{"failed replica", errors.New("connection refused"), true, false, true, false},
+		{"unhealthy replica", nil, false, false, true, false},
+		{"pinned to the primary", nil, true, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &fakeQuerier{}
+			replicas, fakes := newReplicaSet(tt.healthy)
+			fakes[0].err = tt.replicaErr
+			r := &PostgresRepository{pool: primary, replicas: replicas, logger: zap.NewNop()}
+
+			ctx := context.Background()
+			if tt.pinned {
+				ctx = requestctx.WithPrimaryPin(ctx)
+			}
+			served, err := readOnce(t, ctx, r)
+
+			if gotPrimary := served == primary; gotPrimary != tt.wantPrimary {
+				t.Errorf("served by the primary = %v; want %v", gotPrimary, tt.wantPrimary)
+			}
+			if !tt.wantPrimary && !errors.Is(err, tt.replicaErr) {
+				t.Errorf("error = %v; want %v", err, tt.replicaErr)
+			}
+			if tt.wantPrimary && err != nil {
+				t.Errorf("error = %v; want the primary's answer", err)
+			}
+			if got := replicas.replicas[0].healthy.Load(); got != tt.wantHealthy {
+				t.Errorf("replica healthy = %v; want %v", got, tt.wantHealthy)
+			}
+		})
+	}
+}
+
+func TestReadWithoutReplicas(t *testing.T) {
+	primary := &fakeQuerier{}
+	r := &PostgresRepository{pool: primary, logger: zap.NewNop()}
+
+	if served, err := readOnce(t, context.Background(), r); err != nil || served != primary {
+		t.Errorf("read() served by %v, error %v; want the primary", served, err)
+	}
+}
+
+func TestReadSkipsReplicasAfterCancel(t *testing.T) {
+	primary := &fakeQuerier{}
+	replicas, fakes := newReplicaSet(true)
+	fakes[0].err = context.Canceled
+	r := &PostgresRepository{pool: primary, replicas: replicas, logger: zap.NewNop()}
+
+	// A cancelled request is not the replica's fault
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	if _, err := readOnce(t, ctx, r); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v; want the replica's error", err)
+	}
+	if primary.calls != 0 || !replicas.replicas[0].healthy.Load() {
+		t.Errorf("primary calls = %d, replica healthy = %v; want no fallback", primary.calls, replicas.replicas[0].healthy.Load())
+	}
+}
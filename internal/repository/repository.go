@@ -2,17 +2,32 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"user-profile-api/internal/events"
 )
 
+// ErrUserNotFound is returned when no user has the requested ID
+var ErrUserNotFound = errors.New("user not found")
+
 // Repository defines the interface for user data access
 type Repository interface {
-	CreateUser(ctx context.Context, name string, dob time.Time) (*User, error)
+	CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error)
 	GetUserByID(ctx context.Context, id int32) (*User, error)
-	UpdateUser(ctx context.Context, id int32, name string, dob time.Time) (*User, error)
+	GetUsersByIDs(ctx context.Context, ids []int32) ([]User, error)
+	UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*User, error)
 	DeleteUser(ctx context.Context, id int32) error
 	ListUsers(ctx context.Context, limit, offset int32) ([]User, error)
+	SearchUsers(ctx context.Context, filter UserFilter, afterID, limit int32) ([]User, error)
+	ListBirthdays(ctx context.Context, r BirthdayRange, limit, offset int32) ([]User, error)
 	CountUsers(ctx context.Context) (int64, error)
+
+	// RecordEvent appends a domain event to the outbox
+	RecordEvent(ctx context.Context, event *events.Event) error
+
+	// WithTx runs fn atomically; see PostgresRepository.WithTx
+	WithTx(ctx context.Context, fn func(Repository) error, opts ...TxOption) error
 }
 
 // User represents a user from the database
@@ -20,4 +35,37 @@ type User struct {
 	ID   int32
 	Name string
 	DOB  time.Time
+	// Timezone is the IANA name of the user's time zone, or empty for the
+	// default
+	Timezone string
+	// UpdatedAt is when the user was created or last updated
+	UpdatedAt time.Time
+}
+
+// UserFilter restricts SearchUsers; zero fields match every user
+type UserFilter struct {
+	// NameContains matches names containing the string, ignoring case
+	NameContains string
+	// BornAfter and BornBefore bound the date of birth, exclusively
+	BornAfter  time.Time
+	BornBefore time.Time
+	// Birthdays, when set, matches the users whose birthdays fall in the
+	// range
+	Birthdays *BirthdayRange
+}
+
+// BirthdayRange selects users by the month and day of their birth, written
+// as month * 100 + day like MonthDay
+type BirthdayRange struct {
+	// From and To bound the month and day inclusively; a From after To wraps
+	// around the end of the year
+	From, To int
+	// LeapDay is the month and day February 29 birthdays are observed on
+	// within the range, or 0 when they fall outside it or on February 29
+	LeapDay int
+}
+
+// MonthDay returns the month and day of t as month * 100 + day
+func MonthDay(t time.Time) int {
+	return int(t.Month())*100 + t.Day()
 }
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// IsolationLevel is a transaction isolation level
+type IsolationLevel string
+
+// Supported isolation levels
+const (
+	ReadCommitted  IsolationLevel = IsolationLevel(pgx.ReadCommitted)
+	RepeatableRead IsolationLevel = IsolationLevel(pgx.RepeatableRead)
+	Serializable   IsolationLevel = IsolationLevel(pgx.Serializable)
+)
+
+// SQLSTATE codes for errors that are safe to retry from the beginning
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// defaultTxMaxRetries is how often a transaction is re-run after a
+// serialization failure or deadlock
+const defaultTxMaxRetries = 3
+
+// txOptions holds the settings for WithTx
+type txOptions struct {
+	isolation  IsolationLevel
+	maxRetries int
+}
+
+// TxOption configures a transaction started with WithTx
+type TxOption func(*txOptions)
+
+// WithIsolation sets the isolation level of the transaction
+func WithIsolation(level IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.isolation = level
+	}
+}
+
+// WithMaxRetries sets how many times the transaction is retried after a
+// serialization failure or deadlock
+func WithMaxRetries(n int) TxOption {
+	return func(o *txOptions) {
+		o.maxRetries = n
+	}
+}
+
+func newTxOptions(opts []TxOption) txOptions {
+	o := txOptions{
+		isolation:  ReadCommitted,
+		maxRetries: defaultTxMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTx runs fn in a transaction and commits if it returns nil. The
+// Repository passed to fn routes every call through the transaction.
+//
+// Serialization failures and deadlocks re-run fn from the start, so fn must
+// not have side effects outside the repository. Calling WithTx on the
+// transactional Repository creates a savepoint instead: its failure rolls
+// back only the nested work, and the options are ignored.
+func (r *PostgresRepository) WithTx(ctx context.Context, fn func(Repository) error, opts ...TxOption) error {
+	if r.tx != nil {
+		return r.savepoint(ctx, fn)
+	}
+
+	o := newTxOptions(opts)
+	for attempt := 0; ; attempt++ {
+		err := r.runTx(ctx, o, fn)
+		if err == nil || !isRetryable(err) || attempt >= o.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		backoff := time.Duration(attempt+1)*10*time.Millisecond + time.Duration(rand.Int63n(int64(10*time.Millisecond)))
+		r.log(ctx).Debug("retrying transaction",
+			zap.Int("attempt", attempt+1),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runTx executes one attempt of a top-level transaction
+func (r *PostgresRepository) runTx(ctx context.Context, o txOptions, fn func(Repository) error) (err error) {
+	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(o.isolation)})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if err := fn(r.withTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// savepoint runs fn in a nested transaction backed by a savepoint
+func (r *PostgresRepository) savepoint(ctx context.Context, fn func(Repository) error) (err error) {
+	nested, err := r.tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = nested.Rollback(context.WithoutCancel(ctx))
+		}
+	}()
+
+	if err := fn(r.withTx(nested)); err != nil {
+		return err
+	}
+	return nested.Commit(ctx)
+}
+
+// withTx returns a copy of the repository bound to tx
+func (r *PostgresRepository) withTx(tx pgx.Tx) *PostgresRepository {
+	clone := *r
+	clone.tx = tx
+	return &clone
+}
+
+// isRetryable reports whether err aborted the transaction in a way that
+// re-running it may succeed
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
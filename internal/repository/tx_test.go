@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{fmt.Errorf("update: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{ErrUserNotFound, false},
+	}
+
+	for _, tt := range tests {
+		if got := isRetryable(tt.err); got != tt.want {
+			t.Errorf("isRetryable(%v) = %v; want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// fakeTx records how a transaction or savepoint ended
+type fakeTx struct {
+	pgx.Tx
+	committed  bool
+	rolledBack bool
+	savepoints []*fakeTx
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	savepoint := &fakeTx{}
+	tx.savepoints = append(tx.savepoints, savepoint)
+	return savepoint, nil
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	tx.rolledBack = true
+	return nil
+}
+
+// fakePool begins fake transactions
+type fakePool struct {
+	fakeQuerier
+	txs []*fakeTx
+}
+
+func (p *fakePool) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx := &fakeTx{}
+	p.txs = append(p.txs, tx)
+	return tx, nil
+}
+
+func TestWithTxRetries(t *testing.T) {
+	pool := &fakePool{}
+	r := &PostgresRepository{pool: pool, logger: zap.NewNop()}
+
+	attempts := 0
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		attempts++
+		if attempts == 1 {
+			return fmt.Errorf("update: %w", &pgconn.PgError{Code: sqlStateSerializationFailure})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v; want success on retry", err)
+	}
+
+	if attempts != 2 || len(pool.txs) != 2 {
+		t.Fatalf("attempts = %d in %d transactions; want 2", attempts, len(pool.txs))
+	}
+	if first := pool.txs[0]; !first.rolledBack || first.committed {
+		t.Errorf("first attempt rolled back = %v, committed = %v; want rolled back", first.rolledBack, first.committed)
+	}
+	if second := pool.txs[1]; !second.committed || second.rolledBack {
+		t.Errorf("second attempt committed = %v, rolled back = %v; want committed", second.committed, second.rolledBack)
+	}
+}
+
+func TestWithTxGivesUp(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: sqlStateDeadlockDetected}
+	tests := []struct {
+		name         string
+		err          error
+		opts         []TxOption
+		wantAttempts int
+	}{
+		{"not retryable", ErrUserNotFound, nil, 1},
+		{"retries exhausted", deadlock, []TxOption{WithMaxRetries(1)}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &PostgresRepository{pool: &fakePool{}, logger: zap.NewNop()}
+
+			attempts := 0
+			err := r.WithTx(context.Background(), func(tx Repository) error {
+				attempts++
+				return tt.err
+			}, tt.opts...)
+			if !errors.Is(err, tt.err) || attempts != tt.wantAttempts {
+				t.Errorf("WithTx() = %v after %d attempts; want %v after %d", err, attempts, tt.err, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWithTxNestedRollback(t *testing.T) {
+	pool := &fakePool{}
+	r := &PostgresRepository{pool: pool, logger: zap.NewNop()}
+	failed := errors.New("nested failure")
+
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		// A failed savepoint rolls back only the nested work
+		if err := tx.WithTx(context.Background(), func(Repository) error { return failed }); !errors.Is(err, failed) {
+			t.Errorf("nested WithTx() error = %v; want %v", err, failed)
+		}
+		return tx.WithTx(context.Background(), func(Repository) error { return nil })
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	if len(pool.txs) != 1 {
+		t.Fatalf("began %d transactions; want 1", len(pool.txs))
+	}
+	outer := pool.txs[0]
+	if !outer.committed || outer.rolledBack {
+		t.Errorf("outer committed = %v, rolled back = %v; want committed", outer.committed, outer.rolledBack)
+	}
+	if len(outer.savepoints) != 2 {
+		t.Fatalf("created %d savepoints; want 2", len(outer.savepoints))
+	}
+	if sp := outer.savepoints[0]; !sp.rolledBack || sp.committed {
+		t.Errorf("failed savepoint rolled back = %v, committed = %v; want rolled back", sp.rolledBack, sp.committed)
+	}
+	if sp := outer.savepoints[1]; !sp.committed || sp.rolledBack {
+		t.Errorf("second savepoint committed = %v, rolled back = %v; want committed", sp.committed, sp.rolledBack)
+	}
+}
@@ -0,0 +1,94 @@
+package requestctx
+
+import (
+	"context"
+	"time"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	userIDKey
+	primaryPinKey
+	apiVersionKey
+	locationKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// ValidRequestID reports whether a client-supplied ID is short and printable,
+// since it ends up in logs and SQL comments
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// WithUserID returns a copy of ctx carrying the ID of the user being operated on
+func WithUserID(ctx context.Context, userID int32) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the user ID stored in ctx and whether it was set
+func UserID(ctx context.Context) (int32, bool) {
+	userID, ok := ctx.Value(userIDKey).(int32)
+	return userID, ok
+}
+
+// WithPrimaryPin marks ctx so reads are served by the primary database,
+// giving the client read-your-writes consistency
+func WithPrimaryPin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryPinKey, true)
+}
+
+// PrimaryPinned reports whether reads for ctx must go to the primary
+func PrimaryPinned(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryPinKey).(bool)
+	return pinned
+}
+
+// WithAPIVersion returns a copy of ctx carrying the REST API version the
+// request is served with
+func WithAPIVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, apiVersionKey, version)
+}
+
+// APIVersion returns the REST API version stored in ctx, or 1 when none was
+// negotiated
+func APIVersion(ctx context.Context) int {
+	if version, ok := ctx.Value(apiVersionKey).(int); ok {
+		return version
+	}
+	return 1
+}
+
+// WithLocation returns a copy of ctx carrying the time zone the request
+// asked for dates such as today to be taken in
+func WithLocation(ctx context.Context, location *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey, location)
+}
+
+// Location returns the time zone stored in ctx and whether it was set
+func Location(ctx context.Context) (*time.Location, bool) {
+	location, ok := ctx.Value(locationKey).(*time.Location)
+	return location, ok
+}
@@ -0,0 +1,245 @@
+package routes
+
+import (
+	"slices"
+	"strings"
+
+	"user-profile-api/internal/middleware"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/openapi"
+	"user-profile-api/internal/render"
+	"user-profile-api/internal/webhook"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Info describes the API in the OpenAPI document
+var Info = openapi.Info{
+	Title:       "User Profile API",
+	Version:     "2.0.0",
+	Description: "Manage user profiles; ages are computed from the date of birth on every read.",
+}
+
+// Operations documents every route Setup can register. Routes that are not
+// registered, for example because a feature is disabled, are left out of
+// the generated document.
+func Operations() []openapi.Operation {
+	pagination := []openapi.Param{
+		{Name: "limit", Description: "Page size; capped by the server", Schema: openapi.Integer(0)},
+		{Name: "offset", Description: "Number of items to skip", Schema: openapi.Integer(0)},
+	}
+	graphqlResult := &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"data":   {},
+			"errors": {Type: "array", Items: &openapi.Schema{Type: "object"}},
+		},
+	}
+	html := openapi.Content{Type: fiber.MIMETextHTML}
+
+	operations := []openapi.Operation{
+		// Health
+		{Method: fiber.MethodGet, Path: "/", Tag: "health", Summary: "Report that the server is up",
+			Responses: map[int]any{200: models.HealthResponse{}}},
+		{Method: fiber.MethodGet, Path: "/health", Tag: "health", Summary: "Check every dependency",
+			Responses: map[int]any{200: models.ReadinessResponse{}, 503: models.ReadinessResponse{}}},
+		{Method: fiber.MethodGet, Path: "/livez", Tag: "health", Summary: "Liveness probe",
+			Responses: map[int]any{200: models.HealthResponse{}}},
+		{Method: fiber.MethodGet, Path: "/readyz", Tag: "health", Summary: "Readiness probe",
+			Query: []openapi.Param{{Name: "verbose", Description: "Include per-dependency results", Schema: openapi.Boolean()}},
+			Responses: map[int]any{
+				200: openapi.AnyOf{models.HealthResponse{}, models.ReadinessResponse{}},
+				503: openapi.AnyOf{models.HealthResponse{}, models.ReadinessResponse{}},
+			}},
+		{Method: fiber.MethodGet, Path: "/startupz", Tag: "health", Summary: "Startup probe",
+			Responses: map[int]any{200: models.HealthResponse{}, 503: models.HealthResponse{}}},
+
+		// GraphQL
+		{Method: fiber.MethodPost, Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query or mutation",
+			Body: &openapi.Schema{
+				Type:     "object",
+				Required: []string{"query"},
+				Properties: map[string]*openapi.Schema{
+					"query":         {Type: "string"},
+					"operationName": {Type: []string{"string", "null"}},
+					"variables":     {Type: []string{"object", "null"}},
+				},
+			},
+			Responses: withErrors(map[int]any{200: graphqlResult, 400: graphqlResult}, 429)},
+		{Method: fiber.MethodGet, Path: "/graphql", Tag: "graphql", Summary: "Open GraphiQL (development only)",
+			Responses: withErrors(map[int]any{200: html, 405: graphqlResult}, 429)},
+
+		// Documentation
+		{Method: fiber.MethodGet, Path: "/openapi.json", Tag: "docs", Summary: "This document",
+			Responses: map[int]any{200: &openapi.Schema{Type: "object"}}},
+		{Method: fiber.MethodGet, Path: "/docs", Tag: "docs", Summary: "Browse this document",
+			Responses: map[int]any{200: html}},
+
+		// Admin
+		{Method: fiber.MethodGet, Path: "/admin/log-level", Tag: "admin", Summary: "Get the log level", Auth: true,
+			Responses: withErrors(map[int]any{200: models.LogLevelResponse{}}, 401)},
+		{Method: fiber.MethodPut, Path: "/admin/log-level", Tag: "admin", Summary: "Change the log level", Auth: true,
+			Body:      models.LogLevelRequest{},
+			Responses: withErrors(map[int]any{200: models.LogLevelResponse{}}, 400, 401)},
+		{Method: fiber.MethodGet, Path: "/admin/config", Tag: "admin", Summary: "Get the active configuration with secrets redacted", Auth: true,
+			Responses: withErrors(map[int]any{200: models.ConfigResponse{}}, 401)},
+		{Method: fiber.MethodGet, Path: "/admin/metrics", Tag: "admin", Summary: "Get expvar metrics", Auth: true,
+			Responses: withErrors(map[int]any{200: &openapi.Schema{Type: "object"}}, 401)},
+
+		// Webhooks
+		{Method: fiber.MethodPost, Path: "/webhooks", Tag: "webhooks", Summary: "Subscribe a URL to user events", Auth: true,
+			Body:      models.CreateWebhookRequest{},
+			Responses: withErrors(map[int]any{201: models.WebhookResponse{}}, 400, 401)},
+		{Method: fiber.MethodGet, Path: "/webhooks", Tag: "webhooks", Summary: "List webhook subscriptions", Auth: true,
+			Responses: withErrors(map[int]any{200: []models.WebhookResponse{}}, 401)},
+		{Method: fiber.MethodGet, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Get a webhook subscription", Auth: true,
+			Responses: withErrors(map[int]any{200: models.WebhookResponse{}}, 400, 401, 404)},
+		{Method: fiber.MethodPut, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Replace a webhook subscription", Auth: true,
+			Body:      models.UpdateWebhookRequest{},
+			Responses: withErrors(map[int]any{200: models.WebhookResponse{}}, 400, 401, 404)},
+		{Method: fiber.MethodDelete, Path: "/webhooks/:id", Tag: "webhooks", Summary: "Delete a webhook subscription", Auth: true,
+			Responses: withErrors(map[int]any{204: nil}, 400, 401, 404)},
+		{Method: fiber.MethodGet, Path: "/webhooks/:id/deliveries", Tag: "webhooks", Summary: "List a webhook's deliveries, newest first", Auth: true,
+			Query: append([]openapi.Param{
+				{Name: "status", Description: "Only deliveries in this state", Schema: openapi.String(webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusDead)},
+			}, pagination...),
+			Responses: withErrors(map[int]any{200: []models.WebhookDeliveryResponse{}}, 400, 401, 404)},
+		{Method: fiber.MethodGet, Path: "/webhooks/:id/deliveries/:deliveryID", Tag: "webhooks", Summary: "Get a delivery with every attempt", Auth: true,
+			Responses: withErrors(map[int]any{200: models.WebhookDeliveryResponse{}}, 400, 401, 404)},
+		{Method: fiber.MethodPost, Path: "/webhooks/:id/deliveries/:deliveryID/redeliver", Tag: "webhooks", Summary: "Send a delivery again", Auth: true,
+			Responses: withErrors(map[int]any{202: models.WebhookDeliveryResponse{}}, 400, 401, 404, 409)},
+	}
+
+	for _, prefix := range []string{"", "/v1", "/v2"} {
+		operations = append(operations, userOperations(prefix, pagination)...)
+	}
+
+	// Any handler error is rendered as a 500 by the error handler
+	for _, op := range operations {
+		if _, ok := op.Responses[500]; !ok {
+			op.Responses[500] = models.ErrorResponse{}
+		}
+	}
+	return operations
+}
+
+// userOperations documents the user routes under prefix, which is /v1, /v2
+// or empty for the routes whose version is negotiated with headers
+func userOperations(prefix string, pagination []openapi.Param) []openapi.Operation {
+	var (
+		written   any = models.CreateUserResponse{}
+		page      any = []models.UserResponse{}
+		birthdays any = []models.BirthdayResponse{}
+		tag           = "users " + strings.TrimPrefix(prefix, "/")
+		headers   []openapi.Param
+		// Unversioned requests may ask for an unsupported version
+		badVersion []int
+	)
+	switch prefix {
+	case "/v2":
+		written, page = models.UserResponse{}, models.UserListResponse{}
+		birthdays = models.BirthdayListResponse{}
+	case "":
+		written = openapi.AnyOf{models.CreateUserResponse{}, models.UserResponse{}}
+		page = openapi.AnyOf{[]models.UserResponse{}, models.UserListResponse{}}
+		birthdays = openapi.AnyOf{[]models.BirthdayResponse{}, models.BirthdayListResponse{}}
+		tag = "users"
+		headers = []openapi.Param{{
+			Name:        middleware.APIVersionHeader,
+			Description: "API version to serve, 1 or 2; also accepted as Accept: application/vnd.user-profile.v2+json",
+			Schema:      openapi.String(),
+		}}
+		badVersion = []int{400}
+	}
+
+	path := prefix + "/users"
+	// Every format after JSON shares its schema
+	bodyTypes := render.MediaTypes(userFormats[1:]...)
+	readTypes := render.MediaTypes(userReadFormats[1:]...)
+	listTypes := render.MediaTypes(userListFormats[1:]...)
+	date := &openapi.Schema{Type: "string", Format: "date"}
+	selection := []openapi.Param{
+		{Name: "fields", Description: "Comma-separated fields to return, e.g. id,name; all by default", Schema: openapi.String()},
+		{Name: "include", Description: "Comma-separated optional fields to compute: age_months, age_days, next_birthday, days_until_birthday, age_bracket", Schema: openapi.String()},
+	}
+	operations := []openapi.Operation{
+		{Method: fiber.MethodPost, Path: path, Summary: "Create a user",
+			Body:       models.CreateUserRequest{},
+			MediaTypes: bodyTypes,
+			Responses:  withErrors(map[int]any{201: written}, 400, 406, 415, 429)},
+		{Method: fiber.MethodGet, Path: path, Summary: "List users ordered by ID",
+			Query:      append(slices.Clip(pagination), selection...),
+			MediaTypes: listTypes,
+			Responses: withErrors(map[int]any{200: openapi.Sparse{Body: page, Model: models.UserResponse{}}},
+				append(badVersion, 400, 406, 429)...)},
+		{Method: fiber.MethodGet, Path: path + "/events", Summary: "Stream user changes as Server-Sent Events",
+			Query: []openapi.Param{
+				{Name: "user_id", Description: "Comma-separated user IDs to stream", Schema: openapi.String()},
+				{Name: "last_event_id", Description: "Resume after this event, like the Last-Event-ID header", Schema: openapi.Integer(0)},
+			},
+			Responses: withErrors(map[int]any{200: openapi.Content{Type: "text/event-stream"}}, 400, 429)},
+		{Method: fiber.MethodGet, Path: path + "/birthdays", Summary: "List birthdays in a date range, in birthday order",
+			Query: append([]openapi.Param{
+				{Name: "from", Description: "First date of the range; today by default", Schema: date},
+				{Name: "to", Description: "Last date of the range, less than a year after from; six days after from by default", Schema: date},
+			}, pagination...),
+			MediaTypes: listTypes,
+			Responses:  withErrors(map[int]any{200: birthdays}, 400, 406, 429)},
+		{Method: fiber.MethodGet, Path: path + "/birthdays.ics", Summary: "Subscribe to users' birthdays as yearly iCalendar events",
+			Query: []openapi.Param{
+				{Name: "name", Description: "Only users whose names contain this, ignoring case", Schema: openapi.String()},
+				{Name: "born_after", Description: "Only users born after this date", Schema: date},
+				{Name: "born_before", Description: "Only users born before this date", Schema: date},
+				{Name: "from", Description: "Only birthdays from this date, as in the birthday listing", Schema: date},
+				{Name: "to", Description: "Only birthdays up to this date, as in the birthday listing", Schema: date},
+			},
+			Responses: withErrors(map[int]any{200: openapi.Content{Type: "text/calendar"}, 304: nil}, 400, 429)},
+		{Method: fiber.MethodGet, Path: path + "/birthdays/today", Summary: "List today's birthdays",
+			Query:      pagination,
+			MediaTypes: listTypes,
+			Responses:  withErrors(map[int]any{200: birthdays}, append(badVersion, 406, 429)...)},
+		{Method: fiber.MethodGet, Path: path + "/:id.vcf", Summary: "Get a user as a vCard",
+			Query:     selection,
+			Responses: withErrors(map[int]any{200: openapi.Content{Type: render.VCard.MediaType}}, 400, 404, 429)},
+		{Method: fiber.MethodGet, Path: path + "/:id", Summary: "Get a user with their age",
+			Query:      selection,
+			MediaTypes: readTypes,
+			Responses: withErrors(map[int]any{200: openapi.Sparse{Body: models.UserResponse{}, Model: models.UserResponse{}}},
+				400, 404, 406, 429)},
+		{Method: fiber.MethodGet, Path: path + "/:id/age", Summary: "Get a user's age on a date",
+			Query:      []openapi.Param{{Name: "at", Description: "Date to compute the age on; today in the user's time zone by default", Schema: date}},
+			MediaTypes: bodyTypes,
+			Responses:  withErrors(map[int]any{200: models.AgeResponse{}}, 400, 404, 406, 429)},
+		{Method: fiber.MethodPut, Path: path + "/:id", Summary: "Replace a user's name and date of birth",
+			Body:       models.UpdateUserRequest{},
+			MediaTypes: bodyTypes,
+			Responses:  withErrors(map[int]any{200: written}, 400, 404, 406, 415, 429)},
+		{Method: fiber.MethodPatch, Path: path + "/:id", Summary: "Change only the given fields of a user",
+			Body:       models.PatchUserRequest{},
+			MediaTypes: bodyTypes,
+			Responses:  withErrors(map[int]any{200: written}, 400, 404, 406, 415, 429)},
+		{Method: fiber.MethodDelete, Path: path + "/:id", Summary: "Delete a user",
+			MediaTypes: bodyTypes,
+			Responses:  withErrors(map[int]any{204: nil}, 400, 404, 406, 429)},
+	}
+	// Every user route takes the time zone of today's date, and rejects
+	// unknown ones
+	timeZone := "IANA time zone, such as Europe/Paris, that today's date and ages are taken in; overrides the user's time zone"
+	headers = append(headers, openapi.Param{Name: middleware.TimeZoneHeader, Description: timeZone, Schema: openapi.String()})
+	for i := range operations {
+		operations[i].Tag = tag
+		operations[i].Headers = headers
+		operations[i].Query = append(slices.Clip(operations[i].Query), openapi.Param{Name: "tz", Description: timeZone + "; takes precedence over the TZ header", Schema: openapi.String()})
+		operations[i].Responses[400] = models.ErrorResponse{}
+		operations[i].Deprecated = prefix == "/v1"
+	}
+	return operations
+}
+
+// withErrors adds an error response for each status
+func withErrors(responses map[int]any, statuses ...int) map[int]any {
+	for _, status := range statuses {
+		responses[status] = models.ErrorResponse{}
+	}
+	return responses
+}
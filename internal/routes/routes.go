@@ -1,34 +1,198 @@
 package routes
 
 import (
+	"expvar"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"user-profile-api/config"
 	"user-profile-api/internal/handler"
 	"user-profile-api/internal/middleware"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/openapi"
+	"user-profile-api/internal/render"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"go.uber.org/zap"
 )
 
 // Setup configures all application routes and middleware
-func Setup(app *fiber.App, userHandler *handler.UserHandler, healthHandler *handler.HealthHandler, logger *zap.Logger) {
+func Setup(app *fiber.App, store *config.Store, userHandler *handler.UserHandler, healthHandler *handler.HealthHandler, adminHandler *handler.AdminHandler, webhookHandler *handler.WebhookHandler, eventHandler *handler.EventHandler, graphqlHandler *handler.GraphQLHandler, logger *zap.Logger) {
+	cfg := store.Current()
+
+	// CORS and rate limiting follow configuration reloads
+	corsMiddleware := middleware.NewSwappable(newCORS(cfg))
+	rateLimiter := middleware.NewSwappable(newRateLimiter(cfg))
+	store.Subscribe(func(old, new *config.Config) {
+		if !reflect.DeepEqual(old.CORS, new.CORS) {
+			corsMiddleware.Swap(newCORS(new))
+			logger.Info("CORS settings reloaded", zap.Strings("allow_origins", new.CORS.AllowOrigins))
+		}
+		if old.Limits.RateLimit != new.Limits.RateLimit || old.Limits.RateLimitWindow != new.Limits.RateLimitWindow {
+			rateLimiter.Swap(newRateLimiter(new))
+			logger.Info("rate limit reloaded",
+				zap.Int("rate_limit", new.Limits.RateLimit),
+				zap.Duration("window", new.Limits.RateLimitWindow),
+			)
+		}
+	})
+
 	app.Use(recover.New())
-	app.Use(cors.New())
+	app.Use(corsMiddleware.Handler())
 	app.Use(middleware.RequestID())
+	app.Use(middleware.Tracing())
 	app.Use(middleware.Logger(logger))
 	app.Use(middleware.ErrorHandler(logger))
 
+	// The document is generated from the route table once every route is
+	// registered, below
+	doc := openapi.NewDocument(Info)
+	if cfg.OpenAPI.ValidateRequests || cfg.OpenAPI.ValidateResponses {
+		app.Use(doc.Validator(openapi.ValidatorOptions{
+			Requests:  cfg.OpenAPI.ValidateRequests,
+			Responses: cfg.OpenAPI.ValidateResponses,
+		}, logger))
+	}
+	docsHandler := handler.NewDocsHandler(doc, cfg.OpenAPI.RedocIntegrity)
+	app.Get("/openapi.json", docsHandler.Spec)
+	app.Get("/docs", docsHandler.UI)
+
 	// Health check endpoint
 	app.Get("/", healthHandler.Default)
 	app.Get("/health", healthHandler.Check)
+	app.Get("/livez", healthHandler.Live)
+	app.Get("/readyz", healthHandler.Ready)
+	app.Get("/startupz", healthHandler.Startup)
+
+	// API routes, under /v1 and /v2 and unversioned for clients that pick a
+	// version with headers
+	versions := versionOptions(cfg)
+	negotiate := render.Negotiate(userFormats...)
+	negotiateRead := render.Negotiate(userReadFormats...)
+	negotiateList := render.Negotiate(userListFormats...)
+	for _, prefix := range []string{"", "/v1", "/v2"} {
+		version, _ := strconv.Atoi(strings.TrimPrefix(prefix, "/v"))
+		api := app.Group(prefix+"/users", rateLimiter.Handler(), middleware.APIVersion(version, versions), middleware.TimeZone())
+		if len(cfg.Database.ReplicaURLs) > 0 {
+			api.Use(middleware.ReadYourWrites(cfg.Database.PrimaryPinWindow))
+		}
+		{
+			api.Post("/", negotiate, userHandler.CreateUser)
+			api.Get("/", negotiateList, userHandler.ListUsers)
+			if eventHandler != nil {
+				api.Get("/events", eventHandler.Stream)
+			}
+			api.Get("/birthdays", negotiateList, userHandler.ListBirthdays)
+			api.Get("/birthdays.ics", userHandler.BirthdayCalendar)
+			api.Get("/birthdays/today", negotiateList, userHandler.BirthdaysToday)
+			api.Get("/:id.vcf", render.As(render.VCard), userHandler.GetUser)
+			api.Get("/:id", negotiateRead, userHandler.GetUser)
+			api.Get("/:id/age", negotiate, userHandler.GetAge)
+			api.Put("/:id", negotiate, userHandler.UpdateUser)
+			api.Patch("/:id", negotiate, userHandler.PatchUser)
+			api.Delete("/:id", negotiate, userHandler.DeleteUser)
+		}
+	}
 
-	// API routes
-	api := app.Group("/users")
-	{
-		api.Post("/", userHandler.CreateUser)
-		api.Get("/", userHandler.ListUsers)
-		api.Get("/:id", userHandler.GetUser)
-		api.Put("/:id", userHandler.UpdateUser)
-		api.Delete("/:id", userHandler.DeleteUser)
+	// GraphQL shares the REST API's rate limit and read-your-writes pinning.
+	// Every request is a POST, so the handler pins after mutations itself.
+	if graphqlHandler != nil {
+		graphql := app.Group("/graphql", rateLimiter.Handler())
+		if len(cfg.Database.ReplicaURLs) > 0 {
+			graphql.Use(middleware.PrimaryPin())
+		}
+		graphql.Post("/", graphqlHandler.Execute)
+		graphql.Get("/", graphqlHandler.GraphiQL)
 	}
+
+	// Admin and webhook routes are only exposed when a token is configured
+	if cfg.Admin.Token != "" {
+		admin := app.Group("/admin", middleware.AdminAuth(cfg.Admin.Token))
+		{
+			admin.Get("/log-level", adminHandler.GetLogLevel)
+			admin.Put("/log-level", adminHandler.SetLogLevel)
+			admin.Get("/config", adminHandler.GetConfig)
+			admin.Get("/metrics", adaptor.HTTPHandler(expvar.Handler()))
+		}
+
+		// Webhook subscriptions can make the server call arbitrary URLs, so
+		// they share the admin token
+		if webhookHandler != nil {
+			webhooks := app.Group("/webhooks", middleware.AdminAuth(cfg.Admin.Token))
+			{
+				webhooks.Post("/", webhookHandler.CreateWebhook)
+				webhooks.Get("/", webhookHandler.ListWebhooks)
+				webhooks.Get("/:id", webhookHandler.GetWebhook)
+				webhooks.Put("/:id", webhookHandler.UpdateWebhook)
+				webhooks.Delete("/:id", webhookHandler.DeleteWebhook)
+				webhooks.Get("/:id/deliveries", webhookHandler.ListDeliveries)
+				webhooks.Get("/:id/deliveries/:deliveryID", webhookHandler.GetDelivery)
+				webhooks.Post("/:id/deliveries/:deliveryID/redeliver", webhookHandler.Redeliver)
+			}
+		}
+	}
+
+	// Operations of disabled features have no route and are simply left out
+	if undocumented, _ := doc.Generate(app.GetRoutes(true), Operations()); len(undocumented) > 0 {
+		logger.Warn("routes missing from the OpenAPI document", zap.Strings("routes", undocumented))
+	}
+}
+
+// Formats the user routes respond in, chosen with Accept; users can also be
+// read as vCards, and lists downloaded as CSV
+var (
+	userFormats     = []*render.Format{render.JSON, render.XML, render.YAML, render.MessagePack}
+	userReadFormats = []*render.Format{render.JSON, render.XML, render.YAML, render.MessagePack, render.VCard}
+	userListFormats = []*render.Format{render.JSON, render.XML, render.YAML, render.CSV, render.MessagePack, render.VCard}
+)
+
+// versionOptions describes the REST API versions; v1 is deprecated in
+// favour of v2 once cfg gives a date
+func versionOptions(cfg *config.Config) middleware.VersionOptions {
+	opts := middleware.VersionOptions{
+		Supported:  []int{1, 2},
+		Default:    cfg.Versions.Default,
+		Deprecated: map[int]middleware.Deprecation{},
+	}
+
+	// The dates were checked when the configuration was loaded
+	if since, err := time.Parse(time.DateOnly, cfg.Versions.V1Deprecated); err == nil {
+		sunset, _ := time.Parse(time.DateOnly, cfg.Versions.V1Sunset)
+		opts.Deprecated[1] = middleware.Deprecation{Since: since, Sunset: sunset, Successor: "/v2/users"}
+	}
+	return opts
+}
+
+// newCORS builds the CORS middleware for cfg
+func newCORS(cfg *config.Config) fiber.Handler {
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(cfg.CORS.AllowOrigins, ","),
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           int(cfg.CORS.MaxAge.Seconds()),
+	})
+}
+
+// newRateLimiter builds the per-IP rate limiter for cfg. Swapping in a new
+// limiter starts counting from zero.
+func newRateLimiter(cfg *config.Config) fiber.Handler {
+	if cfg.Limits.RateLimit == 0 {
+		return func(c *fiber.Ctx) error { return c.Next() }
+	}
+
+	return limiter.New(limiter.Config{
+		Max:        cfg.Limits.RateLimit,
+		Expiration: cfg.Limits.RateLimitWindow,
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(models.ErrorResponse{
+				Error: "too many requests",
+				Code:  models.ErrorCodeRateLimited,
+			})
+		},
+	})
 }
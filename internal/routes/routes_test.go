@@ -0,0 +1,815 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"user-profile-api/config"
+	"user-profile-api/internal/graphqlapi"
+	"user-profile-api/internal/handler"
+	"user-profile-api/internal/health"
+	"user-profile-api/internal/middleware"
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/openapi"
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// memoryRepository keeps users in memory
+type memoryRepository struct {
+	repository.Repository
+	users map[int32]repository.User
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	user := repository.User{ID: int32(len(r.users) + 1), Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users[user.ID] = user
+	return &user, nil
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id int32) (*repository.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	if _, ok := r.users[id]; !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	r.users[id] = repository.User{ID: id, Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	return r.GetUserByID(ctx, id)
+}
+
+func (r *memoryRepository) ListUsers(ctx context.Context, limit, offset int32) ([]repository.User, error) {
+	users := []repository.User{}
+	for id := offset + 1; id <= offset+limit; id++ {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// observed mirrors the matching and ordering of birthdays in the Postgres
+// queries: it returns the month and day the user's birthday is observed on
+// and whether it lies in rng
+func observed(rng repository.BirthdayRange, user repository.User) (int, bool) {
+	md := repository.MonthDay(user.DOB)
+	if md == 229 && rng.LeapDay != 0 {
+		return rng.LeapDay, true
+	}
+	if rng.From <= rng.To {
+		return md, md >= rng.From && md <= rng.To
+	}
+	return md, md >= rng.From || md <= rng.To
+}
+
+func (r *memoryRepository) ListBirthdays(ctx context.Context, rng repository.BirthdayRange, limit, offset int32) ([]repository.User, error) {
+
+	users := []repository.User{}
+	for _, user := range r.users {
+		if _, ok := observed(rng, user); ok {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		a, _ := observed(rng, users[i])
+		b, _ := observed(rng, users[j])
+		if (a < rng.From) != (b < rng.From) {
+			return b < rng.From
+		}
+		if a != b {
+			return a < b
+		}
+		return users[i].ID < users[j].ID
+	})
+
+	users = users[min(int(offset), len(users)):]
+	return users[:min(int(limit), len(users))], nil
+}
+
+func (r *memoryRepository) SearchUsers(ctx context.Context, filter repository.UserFilter, afterID, limit int32) ([]repository.User, error) {
+	matches := func(user repository.User) bool {
+		if !strings.Contains(strings.ToLower(user.Name), strings.ToLower(filter.NameContains)) {
+			return false
+		}
+		if !filter.BornAfter.IsZero() && !user.DOB.After(filter.BornAfter) {
+			return false
+		}
+		if !filter.BornBefore.IsZero() && !user.DOB.Before(filter.BornBefore) {
+			return false
+		}
+		if filter.Birthdays != nil {
+			_, ok := observed(*filter.Birthdays, user)
+			return ok
+		}
+		return true
+	}
+
+	users := []repository.User{}
+	for id := afterID + 1; int(id) <= len(r.users) && len(users) < int(limit); id++ {
+		if user, ok := r.users[id]; ok && matches(user) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryRepository) WithTx(ctx context.Context, fn func(repository.Repository) error, opts ...repository.TxOption) error {
+	return fn(r)
+}
+
+// newTestApp registers every route, with OpenAPI validation of requests and
+// responses
+func newTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return newTestAppWith(t, &memoryRepository{users: map[int32]repository.User{}})
+}
+
+// newTestAppWith is newTestApp serving the users in repo
+func newTestAppWith(t *testing.T, repo *memoryRepository, opts ...service.Option) *fiber.App {
+	t.Helper()
+	return newTestAppConfig(t, config.Default(), repo, opts...)
+}
+
+// newTestAppConfig is newTestAppWith configured by cfg
+func newTestAppConfig(t *testing.T, cfg *config.Config, repo *memoryRepository, opts ...service.Option) *fiber.App {
+	t.Helper()
+
+	cfg.Admin.Token = "secret"
+	cfg.OpenAPI.ValidateRequests = true
+	cfg.OpenAPI.ValidateResponses = true
+	log := zap.NewNop()
+
+	users := service.NewUserService(repo, log, opts...)
+	executor, err := graphqlapi.New(users, graphqlapi.Options{}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker := health.NewChecker(time.Second, 0)
+	checker.MarkStarted()
+
+	app := fiber.New()
+	Setup(app, config.NewStore("", cfg, log),
+		handler.NewUserHandler(users, log),
+		handler.NewHealthHandler(checker),
+		handler.NewAdminHandler(zap.NewAtomicLevel(), config.NewStore("", cfg, log), log),
+		handler.NewWebhookHandler(nil, log),
+		handler.NewEventHandler(nil, time.Second, log),
+		handler.NewGraphQLHandler(executor, true, time.Minute, log),
+		log,
+	)
+	return app
+}
+
+func TestOperationsMatchRoutes(t *testing.T) {
+	app := newTestApp(t)
+
+	undocumented, unrouted := openapi.NewDocument(Info).Generate(app.GetRoutes(true), Operations())
+	for _, route := range undocumented {
+		t.Errorf("route %s has no operation in Operations()", route)
+	}
+	for _, op := range unrouted {
+		t.Errorf("operation %s has no route", op)
+	}
+}
+
+func TestResponsesMatchDocument(t *testing.T) {
+	app := newTestApp(t)
+
+	tests := []struct {
+		method, path, body string
+		wantStatus         int
+	}{
+		{"POST", "/users", `{"name":"Alice","dob":"1990-05-15"}`, 201},
+		{"POST", "/users", `{"name":"Alice","dob":"15/05/1990"}`, 400},
+		{"POST", "/users", `{"dob":"1990-05-15"}`, 400},
+		{"GET", "/users/1", "", 200},
+		{"GET", "/users/2", "", 404},
+		{"GET", "/users/abc", "", 400},
+		{"GET", "/users?limit=5", "", 200},
+		{"GET", "/users?limit=-1", "", 400},
+		{"PUT", "/users/1", `{"name":"Alicia","dob":"1990-05-15"}`, 200},
+		{"POST", "/v2/users", `{"name":"Bob","dob":"1985-01-01"}`, 201},
+		{"GET", "/v2/users?limit=1", "", 200},
+		{"PATCH", "/v1/users/1", `{"name":"Alice"}`, 200},
+		{"GET", "/readyz?verbose=true", "", 200},
+		{"GET", "/openapi.json", "", 200},
+		{"POST", "/graphql", `{"query":"{ user(id: 1) { name age } }"}`, 200},
+		{"GET", "/admin/log-level", "", 401},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s %s = %d %s; want %d", tt.method, tt.path, resp.StatusCode, body, tt.wantStatus)
+		}
+	}
+}
+
+func TestDocumentDescribesModels(t *testing.T) {
+	resp, err := newTestApp(t).Test(httptest.NewRequest("GET", "/openapi.json", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]openapi.Schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.OpenAPI != openapi.Version {
+		t.Errorf("openapi = %q; want %q", doc.OpenAPI, openapi.Version)
+	}
+	create := doc.Components.Schemas["CreateUserRequest"]
+	if got := strings.Join(create.Required, ","); got != "name,dob" {
+		t.Errorf("CreateUserRequest required = %q; want name,dob", got)
+	}
+	if dob := create.Properties["dob"]; dob == nil || dob.Format != "date" {
+		t.Errorf("CreateUserRequest.dob = %+v; want format date", dob)
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	cfg := config.Default()
+	cfg.Versions.V1Deprecated = "2026-10-19"
+	app := newTestAppConfig(t, cfg, &memoryRepository{users: map[int32]repository.User{}})
+	for _, name := range []string{"Alice", "Bob"} {
+		req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(`{"name":"`+name+`","dob":"1990-05-15"}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		header     http.Header
+		wantStatus int
+		// wantBody is a prefix of the expected body
+		wantBody       string
+		wantVersion    string
+		wantDeprecated bool
+	}{
+		{"v1 path", "/v1/users?limit=1", nil, 200, `[{"id":1`, "1", true},
+		{"v2 path", "/v2/users?limit=1", nil, 200, `{"data":[{"id":1`, "2", false},
+		{"default", "/users?limit=1", nil, 200, `[{"id":1`, "1", true},
+		{"header", "/users?limit=1", http.Header{"Api-Version": {"2"}}, 200, `{"data"`, "2", false},
+		{"accept", "/users?limit=1", http.Header{"Accept": {"application/vnd.user-profile.v2+json"}}, 200, `{"data"`, "2", false},
+		{"unsupported", "/users", http.Header{"Api-Version": {"3"}}, 400, `{"error":"unsupported API version`, "", false},
+		{"path wins", "/v1/users/1", http.Header{"Api-Version": {"2"}}, 200, `{"id":1`, "1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+				t.Errorf("GET %s = %d %s; want %d %s...", tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+			if got := resp.Header.Get("API-Version"); got != tt.wantVersion {
+				t.Errorf("API-Version = %q; want %q", got, tt.wantVersion)
+			}
+			deprecated := resp.Header.Get("Deprecation") != ""
+			if deprecated != tt.wantDeprecated {
+				t.Errorf("Deprecation = %q; want deprecated %v", resp.Header.Get("Deprecation"), tt.wantDeprecated)
+			}
+			if deprecated && !strings.Contains(resp.Header.Get("Link"), `</v2/users>; rel="successor-version"`) {
+				t.Errorf("Link = %q; want the v2 successor", resp.Header.Get("Link"))
+			}
+		})
+	}
+}
+
+func TestAPIVersionsNotDeprecatedByDefault(t *testing.T) {
+	resp, err := newTestApp(t).Test(httptest.NewRequest("GET", "/users?limit=1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Deprecation"); got != "" || resp.Header.Get("API-Version") != "1" {
+		t.Errorf("Deprecation = %q on a v1 response; want none until versions.v1_deprecated is set", got)
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	app := newTestApp(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		accept      string
+		wantStatus  int
+		// wantBody is a prefix of the expected body
+		wantBody string
+	}{
+		{"yaml body", "POST", "/v1/users", "application/yaml", "name: Alice\ndob: \"1990-05-15\"\n", "", 201, `{"id":1,"name":"Alice"`},
+		{"xml body", "POST", "/v1/users", "application/xml", "<user><name>Bob</name><dob>1985-01-02</dob></user>", "application/xml", 201, "<?xml"},
+		{"unsupported body", "POST", "/v1/users", "text/plain", "Carol", "", 415, `{"error":"unsupported content type`},
+		{"xml", "GET", "/v1/users/1", "", "", "application/xml", 200, xml.Header + "<response><id>1</id><name>Alice</name>"},
+		{"yaml", "GET", "/v1/users/1", "", "", "text/yaml", 200, "id: 1\nname: Alice\ndob: \"1990-05-15\"\n"},
+		{"csv", "GET", "/v2/users", "", "", "text/csv;q=0.9, application/json;q=0.5", 200, "id,name,dob,age\n1,Alice,1990-05-15,"},
+		{"csv only on lists", "GET", "/v1/users/1", "", "", "text/csv", 406, `{"error":"none of the accepted media types`},
+		{"error as yaml", "GET", "/v1/users/9", "", "", "application/yaml", 404, "error: user not found\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set(fiber.HeaderContentType, tt.contentType)
+			}
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+				t.Errorf("%s %s = %d %s; want %d %s...", tt.method, tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestSparseFieldsets(t *testing.T) {
+	app := newTestApp(t)
+	req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(`{"name":"Alice","dob":"1990-05-15"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		// wantBody is a prefix of the expected body
+		wantBody string
+		// bracket is whether the body has an age_bracket field
+		bracket bool
+	}{
+		{"/v1/users/1?fields=name,id", 200, `{"id":1,"name":"Alice"}`, false},
+		{"/v2/users?fields=id", 200, `{"data":[{"id":1}]}`, false},
+		{"/v1/users/1", 200, `{"id":1,"name":"Alice","dob":"1990-05-15","age":`, false},
+		{"/v1/users/1?include=age_bracket", 200, `{"id":1,"name":"Alice","dob":"1990-05-15","age":`, true},
+		{"/v1/users?fields=id,age_days", 200, `[{"id":1,"age_days":`, false},
+		{"/v1/users/1?fields=email", 400, `{"error":"unknown field \"email\"`, false},
+		{"/v1/users?include=zodiac", 400, `{"error":"unknown include \"zodiac\"`, false},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+			t.Errorf("GET %s = %d %s; want %d %s...", tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+		}
+		if got := strings.Contains(string(body), `"age_bracket":"`); got != tt.bracket {
+			t.Errorf("GET %s = %s; has age_bracket = %v, want %v", tt.path, body, got, tt.bracket)
+		}
+	}
+}
+
+func TestBirthdays(t *testing.T) {
+	app := newTestApp(t)
+	for _, user := range []string{`{"name":"Alice","dob":"1990-12-30"}`, `{"name":"Bob","dob":"2000-01-02"}`, `{"name":"Carol","dob":"2004-02-29"}`, `{"name":"Dave","dob":"1985-06-01"}`} {
+		req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(user))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		// wantBody is a prefix of the expected body
+		wantBody string
+	}{
+		{"year wrap", "/v1/users/birthdays?from=2026-12-28&to=2027-01-05", 200,
+			`[{"id":1,"name":"Alice","dob":"1990-12-30","birthday":"2026-12-30","turns":36},{"id":2,"name":"Bob","dob":"2000-01-02","birthday":"2027-01-02","turns":27}]`},
+		{"paginated", "/v2/users/birthdays?from=2026-12-28&to=2027-01-05&limit=1", 200, `{"data":[{"id":1,`},
+		{"leap day on march 1", "/v1/users/birthdays?from=2027-03-01&to=2027-03-01", 200, `[{"id":3,"name":"Carol","dob":"2004-02-29","birthday":"2027-03-01","turns":23}]`},
+		{"not on february 28", "/v1/users/birthdays?from=2027-02-28&to=2027-02-28", 200, `[]`},
+		{"leap year", "/v1/users/birthdays?from=2028-02-20&to=2028-03-01", 200, `[{"id":3,"name":"Carol","dob":"2004-02-29","birthday":"2028-02-29","turns":24}]`},
+		{"today", "/v1/users/birthdays/today", 200, `[`},
+		{"reversed", "/v1/users/birthdays?from=2027-01-05&to=2026-12-28", 400, `{"error":"birthday range must end on or after its start`},
+		{"a year", "/v1/users/birthdays?from=2026-01-01&to=2027-01-01", 400, `{"error":"birthday range`},
+		{"bad date", "/v1/users/birthdays?from=tomorrow", 400, `{"error":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+				t.Errorf("GET %s = %d %s; want %d %s...", tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+			if tt.name == "paginated" && !strings.HasSuffix(string(body), `"next_offset":1}`) {
+				t.Errorf("GET %s = %s; want next_offset 1", tt.path, body)
+			}
+		})
+	}
+}
+
+func TestDefaultPageSize(t *testing.T) {
+	repo := &memoryRepository{users: map[int32]repository.User{}}
+	for id, dob := range []string{"1990-05-01", "1990-05-02", "1990-05-03"} {
+		date, _ := time.Parse("2006-01-02", dob)
+		repo.users[int32(id+1)] = repository.User{ID: int32(id + 1), Name: "User", DOB: date}
+	}
+	app := newTestAppWith(t, repo, service.WithPageSizes(2, 100))
+
+	// Without a limit the service's default page size applies
+	for _, path := range []string{"/v1/users", "/v1/users/birthdays?from=2026-05-01&to=2026-05-31"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page []map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page) != 2 {
+			t.Errorf("GET %s returned %d items; want the default page size of 2", path, len(page))
+		}
+	}
+}
+
+func TestGraphQLPinsAfterMutations(t *testing.T) {
+	app := newTestApp(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		wantPin bool
+	}{
+		{"mutation", `{"query":"mutation { createUser(input: {name: \"Ada\", dob: \"1990-05-01\"}) { id } }"}`, true},
+		{"query", `{"query":"{ user(id: 1) { name } }"}`, false},
+		{"invalid mutation", `{"query":"mutation { deleteUser }"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/graphql", strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pinned := resp.Header.Get(middleware.ReadPrimaryUntilHeader) != ""; pinned != tt.wantPin {
+				t.Errorf("pinned = %v; want %v", pinned, tt.wantPin)
+			}
+		})
+	}
+}
+
+func TestCalendarExports(t *testing.T) {
+	app := newTestApp(t)
+	for _, user := range []string{`{"name":"Alice","dob":"1990-12-30"}`, `{"name":"Carol, Jr.","dob":"2004-02-29"}`} {
+		req := httptest.NewRequest("POST", "/v1/users", strings.NewReader(user))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if _, err := app.Test(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		wantStatus  int
+		contentType string
+		// wantLines must all appear in the body
+		wantLines []string
+	}{
+		{"calendar", "/users/birthdays.ics", "", 200, "text/calendar", []string{
+			"BEGIN:VCALENDAR\r\n",
+			"UID:user-1-birthday@user-profile-api\r\nDTSTAMP:",
+			"DTSTART;VALUE=DATE:19901230\r\nRRULE:FREQ=YEARLY\r\nSUMMARY:Alice's birthday\r\n",
+			"DTSTART;VALUE=DATE:20040229\r\nRRULE:FREQ=YEARLY;BYYEARDAY=60\r\nSUMMARY:Carol\\, Jr.'s birthday\r\n",
+		}},
+		{"vcard", "/v1/users/2.vcf", "application/json", 200, "text/vcard", []string{
+			"BEGIN:VCARD\r\nVERSION:4.0\r\nUID:urn:user-profile-api:user:2\r\nFN:Carol\\, Jr.\r\nBDAY:20040229\r\nEND:VCARD\r\n",
+		}},
+		{"negotiated vcard", "/v2/users/1", "text/vcard", 200, "text/vcard", []string{"FN:Alice\r\nBDAY:19901230\r\n"}},
+		{"bulk vcards", "/v2/users?limit=2&fields=name", "text/vcard", 200, "text/vcard", []string{
+			"BEGIN:VCARD\r\nVERSION:4.0\r\nFN:Alice\r\nEND:VCARD\r\nBEGIN:VCARD\r\nVERSION:4.0\r\nFN:Carol\\, Jr.\r\nEND:VCARD\r\n",
+		}},
+		{"vcards of IDs", "/v2/users?limit=2&fields=id", "text/vcard", 200, "text/vcard", []string{
+			"UID:urn:user-profile-api:user:1\r\nFN:Alice\r\nEND:VCARD\r\n",
+		}},
+		{"vcard of an ID", "/v1/users/1.vcf?fields=id", "", 200, "text/vcard", []string{"FN:Alice\r\n"}},
+		{"missing vcard", "/v1/users/9.vcf", "", 404, fiber.MIMEApplicationJSON, []string{`"code":"not_found"`}},
+		{"invalid id", "/v1/users/abc.vcf", "", 400, fiber.MIMEApplicationJSON, []string{`"error":`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(resp.Header.Get(fiber.HeaderContentType), tt.contentType) {
+				t.Fatalf("GET %s = %d %s %s; want %d %s", tt.path, resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), body, tt.wantStatus, tt.contentType)
+			}
+			for _, line := range tt.wantLines {
+				if !strings.Contains(string(body), line) {
+					t.Errorf("GET %s is missing %q:\n%s", tt.path, line, body)
+				}
+			}
+		})
+	}
+}
+
+func TestAgesFollowTimeZones(t *testing.T) {
+	// It is already May 17 in Tokyo, but still May 16 in UTC and Los Angeles
+	now := time.Date(2026, 5, 16, 23, 30, 0, 0, time.UTC)
+	dob := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	repo := &memoryRepository{users: map[int32]repository.User{
+		1: {ID: 1, Name: "Alice", DOB: dob},
+		2: {ID: 2, Name: "Bob", DOB: dob, Timezone: "Asia/Tokyo"},
+	}}
+	app := newTestAppWith(t, repo, service.WithClock(service.ClockFunc(func() time.Time { return now })))
+
+	tests := []struct {
+		name       string
+		path       string
+		tz         string
+		wantStatus int
+		// wantBody is a prefix of the expected body
+		wantBody string
+	}{
+		{"default time zone", "/v1/users/1", "", 200, `{"id":1,"name":"Alice","dob":"1990-05-17","age":35}`},
+		{"user time zone", "/v1/users/2", "", 200, `{"id":2,"name":"Bob","dob":"1990-05-17","age":36,"timezone":"Asia/Tokyo"}`},
+		{"request time zone", "/v1/users/1?tz=Asia/Tokyo", "", 200, `{"id":1,"name":"Alice","dob":"1990-05-17","age":36}`},
+		{"request overrides user", "/v1/users/2", "America/Los_Angeles", 200, `{"id":2,"name":"Bob","dob":"1990-05-17","age":35,"timezone":"Asia/Tokyo"}`},
+		{"computed fields", "/v1/users/2?fields=days_until_birthday", "", 200, `{"days_until_birthday":0}`},
+		{"age today", "/v1/users/2/age", "", 200, `{"id":2,"dob":"1990-05-17","at":"2026-05-17","age":36}`},
+		{"age on a date", "/v2/users/2/age?at=2000-05-16", "", 200, `{"id":2,"dob":"1990-05-17","at":"2000-05-16","age":9}`},
+		{"age before birth", "/v1/users/1/age?at=1990-05-16", "", 400, `{"error":"date must not be before the date of birth"`},
+		{"age of a missing user", "/v1/users/9/age", "", 404, `{"error":"user not found"`},
+		{"bad date", "/v1/users/1/age?at=yesterday", "", 400, `{"error":"invalid request: query parameter at`},
+		{"birthdays today", "/v1/users/birthdays/today", "Asia/Tokyo", 200, `[{"id":1,`},
+		{"unknown time zone", "/v1/users/1?tz=Mars/Olympus", "", 400, `{"error":"invalid time zone \"Mars/Olympus\"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.tz != "" {
+				req.Header.Set("TZ", tt.tz)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+				t.Errorf("GET %s = %d %s; want %d %s...", tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestLeapDayPolicyApplies(t *testing.T) {
+	now := time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)
+	repo := &memoryRepository{users: map[int32]repository.User{
+		1: {ID: 1, Name: "Leap", DOB: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}}
+	clock := service.WithClock(service.ClockFunc(func() time.Time { return now }))
+
+	tests := []struct {
+		policy   models.LeapDayPolicy
+		path     string
+		wantBody string
+	}{
+		{models.LeapDayFeb28, "/v1/users/1?include=next_birthday,days_until_birthday", `{"id":1,"name":"Leap","dob":"2000-02-29","age":26,"next_birthday":"2026-02-28","days_until_birthday":0}`},
+		{models.LeapDayMar1, "/v1/users/1?include=next_birthday,days_until_birthday", `{"id":1,"name":"Leap","dob":"2000-02-29","age":25,"next_birthday":"2026-03-01","days_until_birthday":1}`},
+		{models.LeapDayFeb28, "/v1/users/1/age", `{"id":1,"dob":"2000-02-29","at":"2026-02-28","age":26}`},
+		{models.LeapDayMar1, "/v1/users/1/age", `{"id":1,"dob":"2000-02-29","at":"2026-02-28","age":25}`},
+	}
+	for _, tt := range tests {
+		app := newTestAppWith(t, repo, clock, service.WithLeapDayPolicy(tt.policy))
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 || strings.TrimSpace(string(body)) != tt.wantBody {
+			t.Errorf("%s: GET %s = %d %s; want %s", tt.policy, tt.path, resp.StatusCode, body, tt.wantBody)
+		}
+	}
+}
+
+func TestBirthdayCalendarFilters(t *testing.T) {
+	now := time.Date(2026, 12, 29, 12, 0, 0, 0, time.UTC)
+	repo := &memoryRepository{users: map[int32]repository.User{
+		1: {ID: 1, Name: "Alice", DOB: time.Date(1990, 12, 30, 0, 0, 0, 0, time.UTC)},
+		2: {ID: 2, Name: "Carol", DOB: time.Date(2004, 2, 29, 0, 0, 0, 0, time.UTC)},
+		3: {ID: 3, Name: "Malik", DOB: time.Date(1985, 7, 4, 0, 0, 0, 0, time.UTC)},
+	}}
+	clock := service.WithClock(service.ClockFunc(func() time.Time { return now }))
+	app := newTestAppWith(t, repo, clock, service.WithCalendarLimit(2))
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		// wantUsers are the IDs of the users with events
+		wantUsers []int
+	}{
+		{"over the limit", "/users/birthdays.ics", 400, nil},
+		{"name", "/users/birthdays.ics?name=LI", 200, []int{1, 3}},
+		{"born after", "/users/birthdays.ics?born_after=1990-12-30", 200, []int{2}},
+		{"born before", "/users/birthdays.ics?born_before=1990-12-30", 200, []int{3}},
+		{"default range", "/users/birthdays.ics?from=2026-12-29", 200, []int{1}},
+		{"leap day", "/users/birthdays.ics?from=2027-02-27&to=2027-03-01", 200, []int{2}},
+		{"no match", "/users/birthdays.ics?name=zed", 200, []int{}},
+		{"bad date", "/users/birthdays.ics?born_after=yesterday", 400, nil},
+		{"bad range", "/users/birthdays.ics?from=2027-01-02&to=2027-01-01", 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("GET %s = %d %s; want %d", tt.path, resp.StatusCode, body, tt.wantStatus)
+			}
+			if tt.wantUsers == nil {
+				return
+			}
+			if got := strings.Count(string(body), "BEGIN:VEVENT"); got != len(tt.wantUsers) {
+				t.Errorf("GET %s has %d events; want %d:\n%s", tt.path, got, len(tt.wantUsers), body)
+			}
+			for _, id := range tt.wantUsers {
+				if uid := fmt.Sprintf("UID:user-%d-birthday@", id); !strings.Contains(string(body), uid) {
+					t.Errorf("GET %s is missing %s", tt.path, uid)
+				}
+			}
+		})
+	}
+}
+
+func TestBirthdayCalendarValidators(t *testing.T) {
+	updated := time.Date(2026, 5, 1, 12, 30, 15, 500, time.UTC)
+	now := time.Date(2026, 5, 17, 9, 0, 0, 0, time.UTC)
+	repo := &memoryRepository{users: map[int32]repository.User{
+		1: {ID: 1, Name: "Alice", DOB: time.Date(1990, 12, 30, 0, 0, 0, 0, time.UTC), UpdatedAt: updated},
+	}}
+	clock := service.WithClock(service.ClockFunc(func() time.Time { return now }))
+	app := newTestAppWith(t, repo, clock)
+
+	get := func(app *fiber.App, header, value string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/users/birthdays.ics", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := get(app, "", "")
+	etag, modified := first.Header.Get(fiber.HeaderETag), first.Header.Get(fiber.HeaderLastModified)
+	if first.StatusCode != 200 || etag == "" || modified != "Fri, 01 May 2026 12:30:15 GMT" {
+		t.Fatalf("GET = %d with ETag %q and Last-Modified %q; want the time Alice was updated", first.StatusCode, etag, modified)
+	}
+	body, _ := io.ReadAll(first.Body)
+	if !strings.Contains(string(body), "DTSTAMP:20260501T123015Z") {
+		t.Errorf("events are not stamped with the time Alice was updated:\n%s", body)
+	}
+
+	// Another instance serves the same version
+	replica := get(newTestAppWith(t, repo, clock), "", "")
+	replicaBody, _ := io.ReadAll(replica.Body)
+	if replica.Header.Get(fiber.HeaderETag) != etag || replica.Header.Get(fiber.HeaderLastModified) != modified || string(replicaBody) != string(body) {
+		t.Errorf("another instance served ETag %s, Last-Modified %s; want %s, %s and the same body",
+			replica.Header.Get(fiber.HeaderETag), replica.Header.Get(fiber.HeaderLastModified), etag, modified)
+	}
+
+	if resp := get(app, fiber.HeaderIfNoneMatch, etag); resp.StatusCode != 304 {
+		t.Errorf("GET with a matching If-None-Match = %d; want 304", resp.StatusCode)
+	}
+	if resp := get(app, fiber.HeaderIfModifiedSince, modified); resp.StatusCode != 304 {
+		t.Errorf("GET with a current If-Modified-Since = %d; want 304", resp.StatusCode)
+	}
+	if resp := get(app, fiber.HeaderIfNoneMatch, `"stale"`); resp.StatusCode != 200 {
+		t.Errorf("GET with a stale If-None-Match = %d; want 200", resp.StatusCode)
+	}
+
+	// An update is stamped by the service clock and gives a new version
+	req := httptest.NewRequest("PUT", "/v1/users/1", strings.NewReader(`{"name":"Alicia","dob":"1990-12-30"}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if _, err := app.Test(req); err != nil {
+		t.Fatal(err)
+	}
+	resp := get(app, fiber.HeaderIfModifiedSince, modified)
+	if resp.StatusCode != 200 || resp.Header.Get(fiber.HeaderETag) == etag || resp.Header.Get(fiber.HeaderLastModified) != now.Format(http.TimeFormat) {
+		t.Errorf("GET after an update = %d with ETag %s and Last-Modified %s; want 200 with a new ETag, modified %s",
+			resp.StatusCode, resp.Header.Get(fiber.HeaderETag), resp.Header.Get(fiber.HeaderLastModified), now.Format(http.TimeFormat))
+	}
+
+	// An empty calendar has no date
+	delete(repo.users, 1)
+	if resp := get(app, fiber.HeaderIfModifiedSince, modified); resp.StatusCode != 200 || resp.Header.Get(fiber.HeaderLastModified) != "" {
+		t.Errorf("GET of an empty calendar = %d with Last-Modified %q; want 200 without one", resp.StatusCode, resp.Header.Get(fiber.HeaderLastModified))
+	}
+}
+
+func TestWriteTimezones(t *testing.T) {
+	// It is already May 17 in Tokyo, but still May 16 in UTC
+	now := time.Date(2026, 5, 16, 23, 30, 0, 0, time.UTC)
+	repo := &memoryRepository{users: map[int32]repository.User{}}
+	app := newTestAppWith(t, repo, service.WithClock(service.ClockFunc(func() time.Time { return now })))
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		// wantBody is a prefix of the expected body
+		wantBody string
+	}{
+		{"create", "POST", "/v2/users", `{"name":"Ada","dob":"1990-05-17","timezone":"Asia/Tokyo"}`, 201,
+			`{"id":1,"name":"Ada","dob":"1990-05-17","age":36,"timezone":"Asia/Tokyo"}`},
+		{"create without", "POST", "/v1/users", `{"name":"Bob","dob":"1990-05-17"}`, 201, `{"id":2,"name":"Bob","dob":"1990-05-17"}`},
+		{"invalid", "POST", "/v1/users", `{"name":"Eve","dob":"1990-05-17","timezone":"Mars/Olympus"}`, 400, `{"error":"time zone must be an IANA name`},
+		{"local", "POST", "/v1/users", `{"name":"Eve","dob":"1990-05-17","timezone":"Local"}`, 400, `{"error":"time zone must be an IANA name`},
+		{"born today in Tokyo", "POST", "/v1/users", `{"name":"Kai","dob":"2026-05-17","timezone":"Asia/Tokyo"}`, 201, `{"id":3,`},
+		{"born tomorrow in UTC", "POST", "/v1/users", `{"name":"Kai","dob":"2026-05-17"}`, 400, `{"error":"date of birth cannot be in the future"`},
+		{"patch", "PATCH", "/v1/users/2", `{"timezone":"Europe/Paris"}`, 200, `{"id":2,"name":"Bob","dob":"1990-05-17","timezone":"Europe/Paris"}`},
+		{"patch invalid", "PATCH", "/v1/users/2", `{"timezone":"Europe/Nowhere"}`, 400, `{"error":"time zone must be an IANA name`},
+		{"patch dob in the user's zone", "PATCH", "/v1/users/1", `{"dob":"2026-05-17"}`, 200, `{"id":1,"name":"Ada","dob":"2026-05-17","timezone":"Asia/Tokyo"}`},
+		{"patch dob and zone", "PATCH", "/v1/users/1", `{"dob":"2026-05-17","timezone":"UTC"}`, 400, `{"error":"date of birth cannot be in the future"`},
+		{"patch clears", "PATCH", "/v1/users/2", `{"timezone":""}`, 200, `{"id":2,"name":"Bob","dob":"1990-05-17"}`},
+		{"update", "PUT", "/v2/users/2", `{"name":"Bob","dob":"1990-05-17","timezone":"Asia/Tokyo"}`, 200,
+			`{"id":2,"name":"Bob","dob":"1990-05-17","age":36,"timezone":"Asia/Tokyo"}`},
+		{"update clears", "PUT", "/v1/users/2", `{"name":"Bob","dob":"1990-05-17"}`, 200, `{"id":2,"name":"Bob","dob":"1990-05-17"}`},
+		{"read", "GET", "/v1/users/1?fields=id,timezone", "", 200, `{"id":1,"timezone":"Asia/Tokyo"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus || !strings.HasPrefix(string(body), tt.wantBody) {
+				t.Errorf("%s %s = %d %s; want %d %s...", tt.method, tt.path, resp.StatusCode, body, tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"user-profile-api/internal/models"
+	"user-profile-api/internal/requestctx"
+
+	"go.uber.org/zap"
+)
+
+// Clock tells the current time. Ages depend on it, so tests substitute a
+// fixed clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock
+type ClockFunc func() time.Time
+
+// Now calls f
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock reads the system time, in the server's time zone
+var SystemClock Clock = ClockFunc(time.Now)
+
+// Today returns the current time in the time zone the request asked for, or
+// in the clock's time zone
+func (s *UserService) Today(ctx context.Context) time.Time {
+	return s.today(ctx, "")
+}
+
+// WithAge adds the age as of today in the user's time zone, under the leap
+// day policy, to the response of a create or update
+func (s *UserService) WithAge(ctx context.Context, user *models.CreateUserResponse) *models.UserResponse {
+	return user.WithAge(s.today(ctx, user.Timezone), s.leapDay)
+}
+
+// today returns the current time in the time zone of the request, then of
+// the user named by timezone, then of the clock. Ages and other dates are
+// taken from its calendar date, so users near midnight get the age of
+// their own day.
+func (s *UserService) today(ctx context.Context, timezone string) time.Time {
+	now := s.clock.Now()
+	if location, ok := requestctx.Location(ctx); ok {
+		return now.In(location)
+	}
+	if timezone == "" {
+		return now
+	}
+
+	location, err := models.LoadTimezone(timezone)
+	if err != nil {
+		s.log(ctx).Debug("ignoring invalid user time zone", zap.String("timezone", timezone))
+		return now
+	}
+	return now.In(location)
+}
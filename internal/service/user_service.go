@@ -2,44 +2,151 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"user-profile-api/internal/calendar"
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/logger"
 	"user-profile-api/internal/models"
 	"user-profile-api/internal/repository"
+	"user-profile-api/internal/tracing"
 
+	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("user-profile-api/internal/service")
+
+// Errors returned by UserService
+var (
+	// ErrUserNotFound is returned when no user has the requested ID
+	ErrUserNotFound = repository.ErrUserNotFound
+	// ErrInvalidDate is returned when a date of birth is not YYYY-MM-DD
+	ErrInvalidDate = errors.New("invalid date format")
+	// ErrFutureDOB is returned when a date of birth lies in the future
+	ErrFutureDOB = errors.New("date of birth cannot be in the future")
+	// ErrInvalidTimezone is returned when a time zone is not an IANA name
+	ErrInvalidTimezone = models.ErrInvalidTimezone
+	// ErrInvalidRange is returned when a birthday range ends before it
+	// starts or spans a year or more
+	ErrInvalidRange = errors.New("birthday range must end on or after its start and span less than a year")
+	// ErrBeforeBirth is returned when an age is asked for at a date before
+	// the date of birth
+	ErrBeforeBirth = errors.New("date must not be before the date of birth")
+	// ErrCalendarTooLarge is returned when a birthday calendar would hold
+	// more events than the calendar limit
+	ErrCalendarTooLarge = errors.New("birthday calendar has too many events; narrow it with name, born_after, born_before, from or to")
+)
+
 // UserService handles business logic for user operations
 type UserService struct {
-	repo   repository.Repository
-	logger *zap.Logger
+	repo            repository.Repository
+	logger          *zap.Logger
+	events          bool
+	defaultPageSize atomic.Int32
+	maxPageSize     atomic.Int32
+	leapDay         models.LeapDayPolicy
+	calendarLimit   int
+	clock           Clock
+}
+
+// Option configures a UserService
+type Option func(*UserService)
+
+// WithPageSizes sets the default and maximum page sizes for listings
+func WithPageSizes(defaultSize, maxSize int) Option {
+	return func(s *UserService) {
+		s.SetPageSizes(defaultSize, maxSize)
+	}
+}
+
+// WithEvents records a domain event in the outbox for every change, in the
+// same transaction as the change
+func WithEvents(enabled bool) Option {
+	return func(s *UserService) {
+		s.events = enabled
+	}
+}
+
+// WithLeapDayPolicy sets when February 29 birthdays are observed in common
+// years; the default is March 1
+func WithLeapDayPolicy(policy models.LeapDayPolicy) Option {
+	return func(s *UserService) {
+		s.leapDay = policy
+	}
+}
+
+// WithCalendarLimit sets the most events a birthday calendar may hold; the
+// default is 10000
+func WithCalendarLimit(limit int) Option {
+	return func(s *UserService) {
+		s.calendarLimit = limit
+	}
+}
+
+// WithClock sets the clock ages and today's date are computed from; the
+// default is SystemClock
+func WithClock(clock Clock) Option {
+	return func(s *UserService) {
+		s.clock = clock
+	}
 }
 
 // NewUserService creates a new user service
-func NewUserService(repo repository.Repository, logger *zap.Logger) *UserService {
-	return &UserService{
-		repo:   repo,
-		logger: logger,
+func NewUserService(repo repository.Repository, logger *zap.Logger, opts ...Option) *UserService {
+	s := &UserService{
+		repo:          repo,
+		logger:        logger,
+		leapDay:       models.LeapDayMar1,
+		calendarLimit: 10000,
+		clock:         SystemClock,
 	}
+	s.SetPageSizes(10, 100)
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// SetPageSizes changes the default and maximum page sizes at runtime
+func (s *UserService) SetPageSizes(defaultSize, maxSize int) {
+	s.defaultPageSize.Store(int32(defaultSize))
+	s.maxPageSize.Store(int32(maxSize))
 }
 
 // CreateUser creates a new user
-func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.CreateUserResponse, error) {
+func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequest) (_ *models.CreateUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
 	// Parse and validate DOB
 	dob, err := models.ParseDate(req.DOB)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+
+	if err := checkTimezone(req.Timezone); err != nil {
+		return nil, err
 	}
 
 	// Validate DOB is not in the future
-	if dob.After(time.Now()) {
-		return nil, fmt.Errorf("date of birth cannot be in the future")
+	if err := s.checkDOB(ctx, dob, req.Timezone); err != nil {
+		return nil, err
 	}
 
 	// Create user in repository
-	user, err := s.repo.CreateUser(ctx, req.Name, dob)
+	var user *repository.User
+	err = s.write(ctx, func(tx repository.Repository) error {
+		created, err := tx.CreateUser(ctx, req.Name, dob, req.Timezone, s.clock.Now())
+		if err != nil {
+			return err
+		}
+		user = created
+		return s.record(ctx, tx, events.UserCreated, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -48,31 +155,105 @@ func (s *UserService) CreateUser(ctx context.Context, req *models.CreateUserRequ
 	return s.toCreateUserResponse(user), nil
 }
 
-// GetUserByID retrieves a user by ID
-func (s *UserService) GetUserByID(ctx context.Context, id int32) (*models.UserResponse, error) {
+// GetUserByID retrieves a user by ID, with the optional computed fields in
+// include
+func (s *UserService) GetUserByID(ctx context.Context, id int32, include ...models.Include) (_ *models.UserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByID")
+	defer func() { tracing.End(span, err) }()
+
 	user, err := s.repo.GetUserByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.toUserResponse(user), nil
+	return s.toUserResponse(ctx, user, include...), nil
+}
+
+// GetAge returns the age of a user on the date at, or today in the user's
+// time zone when at is zero
+func (s *UserService) GetAge(ctx context.Context, id int32, at time.Time) (_ *models.AgeResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetAge")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if at.IsZero() {
+		at = s.today(ctx, user.Timezone)
+	}
+	age := s.leapDay.Age(user.DOB, at)
+	if age < 0 {
+		return nil, ErrBeforeBirth
+	}
+
+	return &models.AgeResponse{
+		ID:  user.ID,
+		DOB: models.FormatDate(user.DOB),
+		At:  models.FormatDate(at),
+		Age: age,
+	}, nil
+}
+
+// GetUsersByIDs retrieves the users with the given IDs in one lookup, keyed
+// by ID. Unknown IDs are absent from the result.
+func (s *UserService) GetUsersByIDs(ctx context.Context, ids []int32) (_ map[int32]*models.UserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsersByIDs")
+	defer func() { tracing.End(span, err) }()
+
+	users, err := s.repo.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make(map[int32]*models.UserResponse, len(users))
+	for i := range users {
+		responses[users[i].ID] = s.toUserResponse(ctx, &users[i])
+	}
+
+	return responses, nil
 }
 
 // UpdateUser updates an existing user
-func (s *UserService) UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (*models.CreateUserResponse, error) {
+func (s *UserService) UpdateUser(ctx context.Context, id int32, req *models.UpdateUserRequest) (_ *models.CreateUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser")
+	defer func() { tracing.End(span, err) }()
+
 	// Parse and validate DOB
 	dob, err := models.ParseDate(req.DOB)
 	if err != nil {
-		return nil, fmt.Errorf("invalid date format: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+	}
+
+	if err := checkTimezone(req.Timezone); err != nil {
+		return nil, err
 	}
 
 	// Validate DOB is not in the future
-	if dob.After(time.Now()) {
-		return nil, fmt.Errorf("date of birth cannot be in the future")
+	if err := s.checkDOB(ctx, dob, req.Timezone); err != nil {
+		return nil, err
 	}
 
-	// Update user in repository
-	user, err := s.repo.UpdateUser(ctx, id, req.Name, dob)
+	// Update user in repository; repeatable read makes a concurrent update
+	// fail and retry rather than leave a stale "before" in the event
+	var user *repository.User
+	err = s.write(ctx, func(tx repository.Repository) error {
+		var before *repository.User
+		if s.events {
+			var err error
+			if before, err = tx.GetUserByID(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		updated, err := tx.UpdateUser(ctx, id, req.Name, dob, req.Timezone, s.clock.Now())
+		if err != nil {
+			return err
+		}
+		user = updated
+		return s.record(ctx, tx, events.UserUpdated, before, updated)
+	}, repository.WithIsolation(repository.RepeatableRead))
 	if err != nil {
 		return nil, err
 	}
@@ -80,24 +261,89 @@ func (s *UserService) UpdateUser(ctx context.Context, id int32, req *models.Upda
 	return s.toCreateUserResponse(user), nil
 }
 
-// DeleteUser deletes a user by ID
-func (s *UserService) DeleteUser(ctx context.Context, id int32) error {
-	return s.repo.DeleteUser(ctx, id)
-}
+// PatchUser changes the fields of a user present in req. The user is read
+// and written in one repeatable-read transaction, so concurrent patches to
+// different fields do not undo each other.
+func (s *UserService) PatchUser(ctx context.Context, id int32, req *models.PatchUserRequest) (_ *models.CreateUserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.PatchUser")
+	defer func() { tracing.End(span, err) }()
 
-// ListUsers retrieves a paginated list of users
-func (s *UserService) ListUsers(ctx context.Context, limit, offset int32) ([]models.UserResponse, error) {
-	// Set default limit if not provided
-	if limit <= 0 {
-		limit = 10
+	var dob time.Time
+	if req.DOB != nil {
+		if dob, err = models.ParseDate(*req.DOB); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidDate, err)
+		}
 	}
-	
-	// Cap maximum limit to prevent abuse
-	if limit > 100 {
-		limit = 100
+	if req.Timezone != nil {
+		if err := checkTimezone(*req.Timezone); err != nil {
+			return nil, err
+		}
 	}
 
-	users, err := s.repo.ListUsers(ctx, limit, offset)
+	var user *repository.User
+	err = s.repo.WithTx(ctx, func(tx repository.Repository) error {
+		before, err := tx.GetUserByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		name, newDOB, timezone := before.Name, before.DOB, before.Timezone
+		if req.Name != nil {
+			name = *req.Name
+		}
+		if req.Timezone != nil {
+			timezone = *req.Timezone
+		}
+		// Today depends on the time zone the user ends up with
+		if req.DOB != nil {
+			if err := s.checkDOB(ctx, dob, timezone); err != nil {
+				return err
+			}
+			newDOB = dob
+		}
+
+		updated, err := tx.UpdateUser(ctx, id, name, newDOB, timezone, s.clock.Now())
+		if err != nil {
+			return err
+		}
+		user = updated
+		return s.record(ctx, tx, events.UserUpdated, before, updated)
+	}, repository.WithIsolation(repository.RepeatableRead))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toCreateUserResponse(user), nil
+}
+
+// DeleteUser deletes a user by ID
+func (s *UserService) DeleteUser(ctx context.Context, id int32) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer func() { tracing.End(span, err) }()
+
+	return s.write(ctx, func(tx repository.Repository) error {
+		var before *repository.User
+		if s.events {
+			var err error
+			if before, err = tx.GetUserByID(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return s.record(ctx, tx, events.UserDeleted, before, nil)
+	}, repository.WithIsolation(repository.RepeatableRead))
+}
+
+// ListUsers retrieves a paginated list of users, with the optional computed
+// fields in include
+func (s *UserService) ListUsers(ctx context.Context, limit, offset int32, include ...models.Include) (_ []models.UserResponse, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListUsers")
+	defer func() { tracing.End(span, err) }()
+
+	users, err := s.repo.ListUsers(ctx, s.pageSize(limit), offset)
 	if err != nil {
 		return nil, err
 	}
@@ -105,27 +351,299 @@ func (s *UserService) ListUsers(ctx context.Context, limit, offset int32) ([]mod
 	// Convert to response with calculated ages
 	responses := make([]models.UserResponse, len(users))
 	for i, user := range users {
-		responses[i] = *s.toUserResponse(&user)
+		responses[i] = *s.toUserResponse(ctx, &user, include...)
 	}
 
 	return responses, nil
 }
 
+// ListUsersPage retrieves a page of users like ListUsers, and reports
+// whether more follow
+func (s *UserService) ListUsersPage(ctx context.Context, limit, offset int32, include ...models.Include) (_ []models.UserResponse, hasMore bool, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListUsersPage")
+	defer func() { tracing.End(span, err) }()
+
+	// Fetch one extra row to learn whether another page exists
+	limit = s.pageSize(limit)
+	users, err := s.repo.ListUsers(ctx, limit+1, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(users) > int(limit) {
+		users, hasMore = users[:limit], true
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i := range users {
+		responses[i] = *s.toUserResponse(ctx, &users[i], include...)
+	}
+
+	return responses, hasMore, nil
+}
+
+// SearchUsers retrieves up to first users matching filter with IDs greater
+// than afterID, ordered by ID, and reports whether more follow. first is
+// defaulted and capped like the ListUsers limit.
+func (s *UserService) SearchUsers(ctx context.Context, filter repository.UserFilter, first, afterID int32) (_ []models.UserResponse, hasMore bool, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.SearchUsers")
+	defer func() { tracing.End(span, err) }()
+
+	first = s.pageSize(first)
+
+	// Fetch one extra row to learn whether another page exists
+	users, err := s.repo.SearchUsers(ctx, filter, afterID, first+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(users) > int(first) {
+		users, hasMore = users[:first], true
+	}
+
+	responses := make([]models.UserResponse, len(users))
+	for i := range users {
+		responses[i] = *s.toUserResponse(ctx, &users[i])
+	}
+
+	return responses, hasMore, nil
+}
+
+// ListBirthdays retrieves a page of users whose birthdays fall between from
+// and to, inclusive, ordered by birthday then ID, and reports whether more
+// follow. The range wraps around the end of a year but must be shorter than
+// a year.
+func (s *UserService) ListBirthdays(ctx context.Context, from, to time.Time, limit, offset int32) (_ []models.BirthdayResponse, hasMore bool, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.ListBirthdays")
+	defer func() { tracing.End(span, err) }()
+
+	rng, err := s.birthdayRange(from, to)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Fetch one extra row to learn whether another page exists
+	limit = s.pageSize(limit)
+	users, err := s.repo.ListBirthdays(ctx, rng, limit+1, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(users) > int(limit) {
+		users, hasMore = users[:limit], true
+	}
+
+	responses := make([]models.BirthdayResponse, len(users))
+	for i, user := range users {
+		responses[i] = models.BirthdayResponse{
+			ID:   user.ID,
+			Name: user.Name,
+			DOB:  models.FormatDate(user.DOB),
+		}
+		for year := from.Year(); year <= to.Year(); year++ {
+			if birthday := s.leapDay.Birthday(user.DOB, year); inRange(birthday, from, to) {
+				responses[i].Birthday = models.FormatDate(birthday)
+				responses[i].Turns = year - user.DOB.Year()
+				break
+			}
+		}
+	}
+
+	return responses, hasMore, nil
+}
+
+// birthdayRange returns the month and day range of the birthdays from from
+// to to, inclusive, under the leap day policy
+func (s *UserService) birthdayRange(from, to time.Time) (repository.BirthdayRange, error) {
+	if to.Before(from) || !to.Before(from.AddDate(1, 0, 0)) {
+		return repository.BirthdayRange{}, ErrInvalidRange
+	}
+
+	rng := repository.BirthdayRange{From: repository.MonthDay(from), To: repository.MonthDay(to)}
+	leapDay := time.Date(2000, time.February, 29, 0, 0, 0, 0, time.UTC)
+	for year := from.Year(); year <= to.Year(); year++ {
+		if observed := s.leapDay.Birthday(leapDay, year); !models.IsLeapYear(year) && inRange(observed, from, to) {
+			rng.LeapDay = repository.MonthDay(observed)
+		}
+	}
+	return rng, nil
+}
+
+// BirthdayCalendar returns a yearly event for the birthday of every user
+// matching filter, ordered by user ID. Unless from and to are zero, only
+// the users whose birthdays fall between them, inclusive, are kept.
+// February 29 birthdays recur according to the leap day policy. Calendars
+// over the calendar limit return ErrCalendarTooLarge. modified is the
+// latest time one of the users was created or updated, truncated to the
+// second, or zero without users.
+func (s *UserService) BirthdayCalendar(ctx context.Context, filter repository.UserFilter, from, to time.Time) (_ []calendar.Event, modified time.Time, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.BirthdayCalendar")
+	defer func() { tracing.End(span, err) }()
+
+	if !from.IsZero() || !to.IsZero() {
+		rng, err := s.birthdayRange(from, to)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		filter.Birthdays = &rng
+	}
+
+	// Read the users in pages of the maximum size, stopping one user past
+	// the limit
+	var events []calendar.Event
+	for afterID := int32(0); ; {
+		batch := min(s.maxPageSize.Load(), int32(s.calendarLimit+1-len(events)))
+		users, err := s.repo.SearchUsers(ctx, filter, afterID, batch)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if len(events)+len(users) > s.calendarLimit {
+			return nil, time.Time{}, ErrCalendarTooLarge
+		}
+		for _, user := range users {
+			if user.UpdatedAt.After(modified) {
+				modified = user.UpdatedAt
+			}
+			events = append(events, calendar.Event{
+				UID:     fmt.Sprintf("user-%d-birthday@user-profile-api", user.ID),
+				Summary: user.Name + "'s birthday",
+				Date:    user.DOB,
+				RRule:   s.leapDay.RRule(user.DOB),
+			})
+		}
+		if len(users) < int(batch) {
+			// HTTP dates have a resolution of one second
+			return events, modified.UTC().Truncate(time.Second), nil
+		}
+		afterID = users[len(users)-1].ID
+	}
+}
+
+// inRange reports whether date lies between from and to, inclusive
+func inRange(date, from, to time.Time) bool {
+	return !date.Before(from) && !date.After(to)
+}
+
+// pageSize applies the default page size to a missing limit and caps it to
+// prevent abuse
+func (s *UserService) pageSize(limit int32) int32 {
+	if limit <= 0 {
+		limit = s.defaultPageSize.Load()
+	}
+	if maxPageSize := s.maxPageSize.Load(); limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit
+}
+
+// write runs fn in a transaction when events are recorded, and directly on
+// the repository otherwise
+func (s *UserService) write(ctx context.Context, fn func(repository.Repository) error, opts ...repository.TxOption) error {
+	if !s.events {
+		return fn(s.repo)
+	}
+	return s.repo.WithTx(ctx, fn, opts...)
+}
+
+// record appends an event describing the change from before to after
+func (s *UserService) record(ctx context.Context, tx repository.Repository, eventType string, before, after *repository.User) error {
+	if !s.events {
+		return nil
+	}
+
+	event := &events.Event{
+		Type: eventType,
+		Data: events.Change{
+			Before: toEventUser(before),
+			After:  toEventUser(after),
+		},
+	}
+	if after != nil {
+		event.UserID = after.ID
+	} else {
+		event.UserID = before.ID
+	}
+
+	return tx.RecordEvent(ctx, event)
+}
+
+// log returns the request-scoped logger
+func (s *UserService) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// checkTimezone rejects a time zone that is neither empty nor an IANA name
+func checkTimezone(timezone string) error {
+	if timezone == "" {
+		return nil
+	}
+	_, err := models.LoadTimezone(timezone)
+	return err
+}
+
+// checkDOB rejects a date of birth after today, as dated in the request's
+// time zone, then in the user's time zone named by timezone
+func (s *UserService) checkDOB(ctx context.Context, dob time.Time, timezone string) error {
+	today := s.today(ctx, timezone)
+	if dob.After(time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)) {
+		s.log(ctx).Debug("rejected future date of birth", zap.String("dob", models.FormatDate(dob)))
+		return ErrFutureDOB
+	}
+	return nil
+}
+
 // toCreateUserResponse converts a repository user to a create response DTO without age
 func (s *UserService) toCreateUserResponse(user *repository.User) *models.CreateUserResponse {
 	return &models.CreateUserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-		DOB:  models.FormatDate(user.DOB),
+		ID:       user.ID,
+		Name:     user.Name,
+		DOB:      models.FormatDate(user.DOB),
+		Timezone: user.Timezone,
 	}
 }
 
-// toUserResponse converts a repository user to a response DTO with calculated age
-func (s *UserService) toUserResponse(user *repository.User) *models.UserResponse {
-	return &models.UserResponse{
-		ID:   user.ID,
-		Name: user.Name,
-		DOB:  models.FormatDate(user.DOB),
-		Age:  models.CalculateAge(user.DOB),
+// toUserResponse converts a repository user to a response DTO with calculated
+// age and the optional computed fields in include, as of today in the
+// user's time zone
+func (s *UserService) toUserResponse(ctx context.Context, user *repository.User, include ...models.Include) *models.UserResponse {
+	today := s.today(ctx, user.Timezone)
+	resp := &models.UserResponse{
+		ID:       user.ID,
+		Name:     user.Name,
+		DOB:      models.FormatDate(user.DOB),
+		Age:      s.leapDay.Age(user.DOB, today),
+		Timezone: user.Timezone,
+	}
+
+	// Computed only when requested
+	for _, inc := range include {
+		switch inc {
+		case models.IncludeAgeMonths:
+			months := models.AgeInMonths(user.DOB, today)
+			resp.AgeMonths = &months
+		case models.IncludeAgeDays:
+			days := models.AgeInDays(user.DOB, today)
+			resp.AgeDays = &days
+		case models.IncludeNextBirthday:
+			next := models.FormatDate(s.leapDay.NextBirthday(user.DOB, today))
+			resp.NextBirthday = &next
+		case models.IncludeDaysUntilBirthday:
+			days := s.leapDay.DaysUntilBirthday(user.DOB, today)
+			resp.DaysUntilBirthday = &days
+		case models.IncludeAgeBracket:
+			bracket := models.AgeBracket(resp.Age)
+			resp.AgeBracket = &bracket
+		}
+	}
+	return resp
+}
+
+// toEventUser converts a repository user to an event snapshot
+func toEventUser(user *repository.User) *events.User {
+	if user == nil {
+		return nil
+	}
+	return &events.User{
+		ID:       user.ID,
+		Name:     user.Name,
+		DOB:      models.FormatDate(user.DOB),
+		Timezone: user.Timezone,
 	}
 }
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPgxTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	tracer := NewPgxTracer()
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "  update users SET name = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 3")})
+
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans; want 2", len(spans))
+	}
+
+	update := spans[0]
+	if update.Name() != "postgres UPDATE" || update.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span = %q of kind %v; want postgres UPDATE of kind client", update.Name(), update.SpanKind())
+	}
+	attrs := map[string]string{}
+	for _, kv := range update.Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["db.system"] != "postgresql" || attrs["db.statement"] != "  update users SET name = $1" || attrs["db.rows_affected"] != "3" {
+		t.Errorf("attributes = %v", attrs)
+	}
+	if update.Status().Code != codes.Unset {
+		t.Errorf("status = %v; want unset", update.Status())
+	}
+
+	if failed := spans[1]; failed.Status().Code != codes.Error || failed.Status().Description != "connection reset" {
+		t.Errorf("failed query status = %v; want the error", failed.Status())
+	}
+}
@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"user-profile-api/internal/events"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const (
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = 6 * time.Hour
+
+	// maxResponseBody is how much of each response is kept in the log
+	maxResponseBody = 1024
+)
+
+// dispatchMetrics is published under "webhooks" at /admin/metrics
+var dispatchMetrics = expvar.NewMap("webhooks")
+
+// DispatcherOptions configures a Dispatcher
+type DispatcherOptions struct {
+	// BatchSize is the number of deliveries sent concurrently per round
+	BatchSize int
+	// PollInterval is how often due retries are looked for
+	PollInterval time.Duration
+	// Timeout bounds each HTTP request
+	Timeout time.Duration
+	// MaxAttempts moves a delivery to the dead state after this many failures
+	MaxAttempts int
+	// DisableAfter disables a subscription after this many consecutive
+	// failed attempts; zero never disables
+	DisableAfter int
+	// Retention is how long finished deliveries are kept; zero keeps them
+	Retention time.Duration
+}
+
+// Dispatcher fans events out to matching subscriptions and sends the
+// resulting deliveries. It is an outbox sink: an event is acknowledged once
+// its deliveries are queued, and each delivery is then retried on its own.
+type Dispatcher struct {
+	pool   db
+	client *http.Client
+	opts   DispatcherOptions
+	logger *zap.Logger
+	wake   chan struct{}
+}
+
+// NewDispatcher creates a dispatcher storing deliveries through pool
+func NewDispatcher(pool *pgxpool.Pool, opts DispatcherOptions, logger *zap.Logger) *Dispatcher {
+	return &Dispatcher{
+		pool:   pool,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		logger: logger,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Publish queues a delivery of event for every active subscription that
+// wants it. Publishing the same event again queues nothing new.
+func (d *Dispatcher) Publish(ctx context.Context, event events.Event) error {
+	payload, err := event.Marshal()
+	if err != nil {
+		return err
+	}
+
+	result, err := d.pool.Exec(ctx, `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhook_subscriptions
+		WHERE active AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`, event.ID, event.Type, payload)
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	if result.RowsAffected() > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run sends due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	var lastCleanup time.Time
+	for ctx.Err() == nil {
+		n, err := d.sendBatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			d.logger.Warn("webhook dispatch failed", zap.Error(err))
+		}
+
+		if d.opts.Retention > 0 && time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+			d.cleanup(ctx)
+		}
+
+		if err == nil && n == d.opts.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+		case <-d.wake:
+		case <-time.After(d.opts.PollInterval):
+		}
+	}
+}
+
+// job is a claimed delivery ready to send
+type job struct {
+	deliveryID     int64
+	subscriptionID int64
+	eventID        int64
+	eventType      string
+	payload        []byte
+	attempts       int
+	url            string
+	secret         string
+}
+
+// result is the outcome of one attempt
+type result struct {
+	statusCode int
+	body       string
+	err        error
+	duration   time.Duration
+}
+
+func (r result) ok() bool {
+	return r.err == nil && r.statusCode >= 200 && r.statusCode <= 299
+}
+
+// sendBatch claims due deliveries and sends them concurrently. Claiming
+// pushes next_attempt_at past the request timeout, so other instances skip
+// them, and a crash mid-send only delays the retry.
+func (d *Dispatcher) sendBatch(ctx context.Context) (int, error) {
+	lease := d.opts.Timeout + 30*time.Second
+	rows, err := d.pool.Query(ctx, `UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT pending.id FROM webhook_deliveries pending
+			JOIN webhook_subscriptions sub ON sub.id = pending.subscription_id
+			WHERE pending.status = 'pending' AND pending.next_attempt_at <= now() AND sub.active
+			ORDER BY pending.next_attempt_at, pending.id
+			LIMIT $1
+			FOR UPDATE OF pending SKIP LOCKED)
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload::text, d.attempts, s.url, s.secret`,
+		d.opts.BatchSize, lease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	var jobs []job
+	for rows.Next() {
+		var j job
+		var payload string
+		if err := rows.Scan(&j.deliveryID, &j.subscriptionID, &j.eventID, &j.eventType, &payload, &j.attempts, &j.url, &j.secret); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		j.payload = []byte(payload)
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			res := d.send(ctx, j)
+			if err := d.record(context.WithoutCancel(ctx), j, res); err != nil {
+				d.logger.Error("failed to record webhook attempt", zap.Int64("delivery_id", j.deliveryID), zap.Error(err))
+			}
+		}(j)
+	}
+	wg.Wait()
+
+	return len(jobs), nil
+}
+
+// send makes one signed HTTP request for j
+func (d *Dispatcher) send(ctx context.Context, j job) result {
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.url, bytes.NewReader(j.payload))
+	if err != nil {
+		return result{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "user-profile-api-webhooks/1")
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(j.deliveryID, 10))
+	req.Header.Set(HeaderEvent, j.eventType)
+	req.Header.Set(HeaderEventID, strconv.FormatInt(j.eventID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(j.secret, start, j.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return result{err: err, duration: time.Since(start)}
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	return result{
+		statusCode: resp.StatusCode,
+		body:       string(body),
+		duration:   time.Since(start),
+	}
+}
+
+// record logs the attempt and moves the delivery and its subscription to
+// their next state
+func (d *Dispatcher) record(ctx context.Context, j job, res result) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	errMsg := ""
+	if res.err != nil {
+		errMsg = res.err.Error()
+	} else if !res.ok() {
+		errMsg = fmt.Sprintf("endpoint returned status %d", res.statusCode)
+	}
+
+	_, err = tx.Exec(ctx, `INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, response_body, duration_ms)
+		VALUES ($1, NULLIF($2, 0), NULLIF($3, ''), NULLIF($4, ''), $5)`,
+		j.deliveryID, res.statusCode, errMsg, res.body, res.duration.Milliseconds())
+	if err != nil {
+		return err
+	}
+
+	log := d.logger.With(
+		zap.Int64("webhook_id", j.subscriptionID),
+		zap.Int64("delivery_id", j.deliveryID),
+		zap.Int64("event_id", j.eventID),
+	)
+
+	if res.ok() {
+		_, err = tx.Exec(ctx, `UPDATE webhook_deliveries
+			SET status = 'succeeded', attempts = attempts + 1, last_status_code = $2, last_error = NULL,
+				delivered_at = now(), updated_at = now()
+			WHERE id = $1`, j.deliveryID, res.statusCode)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `UPDATE webhook_subscriptions SET consecutive_failures = 0
+			WHERE id = $1 AND consecutive_failures <> 0`, j.subscriptionID)
+		if err != nil {
+			return err
+		}
+		dispatchMetrics.Add("delivered", 1)
+		return tx.Commit(ctx)
+	}
+
+	var active bool
+	var failures int
+	err = tx.QueryRow(ctx, `SELECT active, consecutive_failures FROM webhook_subscriptions WHERE id = $1 FOR UPDATE`,
+		j.subscriptionID).Scan(&active, &failures)
+	if err != nil {
+		return err
+	}
+	next := d.afterFailure(j.attempts, failures, active)
+
+	_, err = tx.Exec(ctx, `UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, last_status_code = NULLIF($4, 0), last_error = $5,
+			next_attempt_at = now() + make_interval(secs => $6), updated_at = now()
+		WHERE id = $1`, j.deliveryID, next.status, next.attempts, res.statusCode, errMsg, next.delay.Seconds())
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE webhook_subscriptions SET
+			consecutive_failures = $2,
+			active = active AND NOT $3,
+			disabled_reason = CASE WHEN $3 THEN $4 ELSE disabled_reason END,
+			updated_at = now()
+		WHERE id = $1`, j.subscriptionID, next.failures, next.disable,
+		fmt.Sprintf("disabled after %d consecutive failures", next.failures))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	dispatchMetrics.Add("failures", 1)
+	if next.status == StatusDead {
+		dispatchMetrics.Add("dead", 1)
+		log.Warn("webhook delivery moved to dead letter", zap.Int("attempts", next.attempts), zap.String("error", errMsg))
+	} else {
+		log.Info("webhook delivery failed", zap.Int("attempts", next.attempts), zap.Duration("retry_in", next.delay), zap.String("error", errMsg))
+	}
+	if next.disable {
+		dispatchMetrics.Add("disabled", 1)
+		log.Warn("webhook disabled after repeated failures", zap.Int("consecutive_failures", next.failures))
+	}
+	return nil
+}
+
+// failure is where a failed attempt moves a delivery and its subscription
+type failure struct {
+	// attempts counts the delivery's attempts, including this one
+	attempts int
+	status   string
+	delay    time.Duration
+	// failures counts the subscription's consecutive failures, including
+	// this one
+	failures int
+	// disable is set when this failure disables the subscription
+	disable bool
+}
+
+// afterFailure returns the transition for a failed attempt of a delivery
+// that failed attempts times before, for a subscription with failures
+// consecutive failures before it
+func (d *Dispatcher) afterFailure(attempts, failures int, active bool) failure {
+	next := failure{
+		attempts: attempts + 1,
+		status:   StatusPending,
+		delay:    retryDelay(attempts + 1),
+		failures: failures + 1,
+	}
+	if next.attempts >= d.opts.MaxAttempts {
+		next.status = StatusDead
+	}
+	next.disable = active && d.opts.DisableAfter > 0 && next.failures >= d.opts.DisableAfter
+	return next
+}
+
+// cleanup deletes finished deliveries older than the retention period
+func (d *Dispatcher) cleanup(ctx context.Context) {
+	_, err := d.pool.Exec(ctx, `DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND updated_at < now() - make_interval(secs => $1)`, d.opts.Retention.Seconds())
+	if err != nil {
+		d.logger.Warn("failed to clean up webhook deliveries", zap.Error(err))
+	}
+}
+
+// retryDelay grows the delay exponentially with each failed attempt, up to
+// maxRetryDelay, and adds up to a fifth on top so that deliveries failing
+// together, such as during an endpoint outage, do not retry in lockstep
+func retryDelay(attempts int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay/5)+1))
+}
@@ -0,0 +1,299 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+func TestDispatcherSendSignsRequest(t *testing.T) {
+	var verifyErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = Verify("secret", r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+		if r.Header.Get(HeaderEvent) != "user.created" || r.Header.Get(HeaderDelivery) != "7" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, DispatcherOptions{Timeout: time.Second}, zap.NewNop())
+	res := d.send(context.Background(), job{
+		deliveryID: 7,
+		eventID:    3,
+		eventType:  "user.created",
+		payload:    []byte(`{"id":3}`),
+		url:        server.URL,
+		secret:     "secret",
+	})
+
+	if !res.ok() {
+		t.Fatalf("send() = status %d, error %v; want success", res.statusCode, res.err)
+	}
+	if verifyErr != nil {
+		t.Errorf("receiver could not verify signature: %v", verifyErr)
+	}
+}
+
+func TestDispatcherSendReportsFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil, DispatcherOptions{Timeout: time.Second}, zap.NewNop())
+	res := d.send(context.Background(), job{url: server.URL, payload: []byte(`{}`)})
+
+	if res.ok() || res.statusCode != http.StatusInternalServerError || res.body != "boom\n" {
+		t.Errorf("send() = %+v; want recorded 500 response", res)
+	}
+}
+
+func TestDispatcherAfterFailure(t *testing.T) {
+	d := NewDispatcher(nil, DispatcherOptions{MaxAttempts: 3, DisableAfter: 5}, zap.NewNop())
+
+	tests := []struct {
+		name         string
+		attempts     int
+		failures     int
+		active       bool
+		wantStatus   string
+		wantFailures int
+		wantDisable  bool
+	}{
+		{"first failure", 0, 0, true, StatusPending, 1, false},
+		{"dead letter", 2, 2, true, StatusDead, 3, false},
+		{"disables the subscription", 1, 4, true, StatusPending, 5, true},
+		{"already disabled", 1, 7, false, StatusPending, 8, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := d.afterFailure(tt.attempts, tt.failures, tt.active)
+			if next.attempts != tt.attempts+1 || next.status != tt.wantStatus {
+				t.Errorf("delivery = %d attempts, %s; want %d, %s", next.attempts, next.status, tt.attempts+1, tt.wantStatus)
+			}
+			if next.failures != tt.wantFailures || next.disable != tt.wantDisable {
+				t.Errorf("subscription = %d failures, disable %v; want %d, %v", next.failures, next.disable, tt.wantFailures, tt.wantDisable)
+			}
+		})
+	}
+
+	// Without a threshold the subscription is never disabled
+	d.opts.DisableAfter = 0
+	if next := d.afterFailure(0, 100, true); next.disable {
+		t.Error("afterFailure() disabled a subscription without disable_after")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, minRetryDelay},
+		{2, 2 * minRetryDelay},
+		{4, 8 * minRetryDelay},
+		{100, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := retryDelay(tt.attempts); got < tt.base || got > tt.base+tt.base/5 {
+				t.Fatalf("retryDelay(%d) = %v; want between %v and %v", tt.attempts, got, tt.base, tt.base+tt.base/5)
+			}
+		}
+	}
+}
+
+// fakeDB holds an active subscription and its deliveries in memory, and
+// runs the statements of the dispatcher and Redeliver against them
+type fakeDB struct {
+	mu         sync.Mutex
+	url        string
+	deliveries map[int64]*Delivery
+	attempts   int
+}
+
+func (db *fakeDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db}, nil
+}
+
+func (db *fakeDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(sql, "INSERT INTO webhook_delivery_attempts"):
+		db.attempts++
+		return pgconn.NewCommandTag("INSERT 0 1"), nil
+	case strings.Contains(sql, "SET status = 'succeeded'"):
+		delivery := db.deliveries[args[0].(int64)]
+		now := time.Now()
+		delivery.Status, delivery.LastStatusCode, delivery.LastError, delivery.DeliveredAt = StatusSucceeded, args[1].(int), "", &now
+		delivery.Attempts++
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	case strings.HasPrefix(sql, "UPDATE webhook_subscriptions SET consecutive_failures = 0"):
+		return pgconn.NewCommandTag("UPDATE 0"), nil
+	}
+	return pgconn.CommandTag{}, errors.New("unexpected statement: " + sql)
+}
+
+func (db *fakeDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if !strings.Contains(sql, "SET next_attempt_at = now() + make_interval") {
+		return nil, errors.New("unexpected query: " + sql)
+	}
+	// Claim the due deliveries by leasing them
+	now := time.Now()
+	var rows [][]any
+	for _, d := range db.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(time.Duration(args[1].(float64) * float64(time.Second)))
+			rows = append(rows, []any{d.ID, d.SubscriptionID, d.EventID, d.EventType, `{}`, d.Attempts, db.url, "secret"})
+		}
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (db *fakeDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(sql, "SELECT active FROM webhook_subscriptions"):
+		return &fakeRows{rows: [][]any{{true}}}
+	case strings.Contains(sql, "SET status = 'pending', attempts = 0"):
+		d, ok := db.deliveries[args[0].(int64)]
+		if !ok || (d.Status == StatusPending && d.NextAttemptAt.After(time.Now())) {
+			return &fakeRows{}
+		}
+		d.Status, d.Attempts, d.NextAttemptAt = StatusPending, 0, time.Now()
+		d.LastStatusCode, d.LastError, d.DeliveredAt = 0, "", nil
+		return &fakeRows{rows: [][]any{{d.ID, d.SubscriptionID, d.EventID, d.EventType, d.Status, d.Attempts,
+			d.LastStatusCode, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.CreatedAt}}}
+	case strings.HasPrefix(sql, "SELECT EXISTS"):
+		_, ok := db.deliveries[args[0].(int64)]
+		return &fakeRows{rows: [][]any{{ok}}}
+	}
+	return &fakeRows{err: errors.New("unexpected query: " + sql)}
+}
+
+// fakeTx runs statements directly on its fakeDB
+type fakeTx struct {
+	pgx.Tx
+	db *fakeDB
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tx.db.QueryRow(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+// fakeRows returns rows held in memory; without rows, Scan returns
+// pgx.ErrNoRows as a single row would
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+	err  error
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.err == nil && r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	if r.next == 0 {
+		r.next = 1
+	}
+	if r.next > len(r.rows) {
+		return pgx.ErrNoRows
+	}
+	for i, value := range r.rows[r.next-1] {
+		target := reflect.ValueOf(dest[i]).Elem()
+		target.Set(reflect.ValueOf(value).Convert(target.Type()))
+	}
+	return nil
+}
+
+func (r *fakeRows) Close() {}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func TestRedeliverWhileSending(t *testing.T) {
+	db := &fakeDB{deliveries: map[int64]*Delivery{
+		7: {ID: 7, SubscriptionID: 1, EventID: 3, EventType: "user.created", Status: StatusPending, Attempts: 1,
+			LastStatusCode: 500, LastError: "endpoint returned status 500", NextAttemptAt: time.Now().Add(-time.Second)},
+	}}
+	service := &Service{pool: db, logger: zap.NewNop()}
+
+	sends := 0
+	var redeliverErr error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sends++
+		// The delivery is claimed and being sent
+		_, redeliverErr = service.Redeliver(context.Background(), 1, 7)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	db.url = server.URL
+
+	d := NewDispatcher(nil, DispatcherOptions{BatchSize: 10, Timeout: time.Second, MaxAttempts: 3}, zap.NewNop())
+	d.pool = db
+
+	if n, err := d.sendBatch(context.Background()); n != 1 || err != nil {
+		t.Fatalf("sendBatch() = %d, %v; want 1 delivery sent", n, err)
+	}
+	if !errors.Is(redeliverErr, ErrInFlight) {
+		t.Errorf("Redeliver() during the send = %v; want %v", redeliverErr, ErrInFlight)
+	}
+	if n, err := d.sendBatch(context.Background()); n != 0 || err != nil || sends != 1 {
+		t.Fatalf("sendBatch() = %d, %v after %d sends; want the delivery sent once", n, err, sends)
+	}
+	if delivery := db.deliveries[7]; delivery.Status != StatusSucceeded || delivery.Attempts != 2 || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %+v; want succeeded after 2 attempts", delivery)
+	}
+
+	// Once delivered it can be sent again from scratch
+	delivery, err := service.Redeliver(context.Background(), 1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != StatusPending || delivery.Attempts != 0 || delivery.LastStatusCode != 0 || delivery.LastError != "" || delivery.DeliveredAt != nil {
+		t.Errorf("Redeliver() = %+v; want a pending delivery with its last outcome cleared", delivery)
+	}
+	if n, err := d.sendBatch(context.Background()); n != 1 || err != nil || sends != 2 {
+		t.Errorf("sendBatch() = %d, %v after %d sends; want the redelivery sent", n, err, sends)
+	}
+}
@@ -0,0 +1,356 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"user-profile-api/internal/events"
+	"user-profile-api/internal/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Errors returned by Service
+var (
+	ErrNotFound         = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrInvalid          = errors.New("invalid webhook")
+	ErrInactive         = errors.New("webhook is disabled")
+	ErrInFlight         = errors.New("delivery is waiting for its next attempt, which may be in progress")
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// Subscription is a partner endpoint receiving events
+type Subscription struct {
+	ID                  int64
+	URL                 string
+	Secret              string
+	Events              []string
+	Active              bool
+	DisabledReason      string
+	ConsecutiveFailures int
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// Delivery is one event sent, or to be sent, to one subscription
+type Delivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        int64
+	EventType      string
+	Status         string
+	Attempts       int
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+
+	// Log holds the individual attempts, oldest first; only filled by
+	// Service.GetDelivery
+	Log []Attempt
+}
+
+// Attempt is a single HTTP request made for a delivery
+type Attempt struct {
+	AttemptedAt  time.Time
+	StatusCode   int
+	Error        string
+	ResponseBody string
+	Duration     time.Duration
+}
+
+// db is the part of *pgxpool.Pool used for webhooks, so tests can replace it
+type db interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Service manages webhook subscriptions and their delivery logs
+type Service struct {
+	pool   db
+	logger *zap.Logger
+}
+
+// NewService creates a new webhook service
+func NewService(pool *pgxpool.Pool, logger *zap.Logger) *Service {
+	return &Service{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+const subscriptionColumns = `id, url, secret, event_types, active, COALESCE(disabled_reason, ''),
+	consecutive_failures, created_at, updated_at`
+
+// Create registers a subscription. An empty secret is replaced by a
+// generated one.
+func (s *Service) Create(ctx context.Context, rawURL string, eventTypes []string, secret string) (*Subscription, error) {
+	if err := validate(rawURL, eventTypes); err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		var err error
+		if secret, err = newSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+	}
+
+	query := `INSERT INTO webhook_subscriptions (url, secret, event_types) VALUES ($1, $2, $3)
+		RETURNING ` + subscriptionColumns
+	sub, err := scanSubscription(s.pool.QueryRow(ctx, query, rawURL, secret, normalizeEvents(eventTypes)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.log(ctx).Info("webhook created", zap.Int64("webhook_id", sub.ID), zap.String("url", sub.URL))
+	return sub, nil
+}
+
+// Get returns a subscription by ID
+func (s *Service) Get(ctx context.Context, id int64) (*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM webhook_subscriptions WHERE id = $1`
+	sub, err := scanSubscription(s.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return sub, nil
+}
+
+// List returns every subscription
+func (s *Service) List(ctx context.Context) ([]Subscription, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// Update replaces the URL and event filter of a subscription. A non-nil
+// active enables or disables it; re-enabling clears the failure count.
+func (s *Service) Update(ctx context.Context, id int64, rawURL string, eventTypes []string, active *bool) (*Subscription, error) {
+	if err := validate(rawURL, eventTypes); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE webhook_subscriptions SET
+			url = $2,
+			event_types = $3,
+			active = COALESCE($4, active),
+			consecutive_failures = CASE WHEN $4 THEN 0 ELSE consecutive_failures END,
+			disabled_reason = CASE WHEN $4 IS NULL THEN disabled_reason WHEN $4 THEN NULL ELSE 'disabled by request' END,
+			updated_at = now()
+		WHERE id = $1
+		RETURNING ` + subscriptionColumns
+	sub, err := scanSubscription(s.pool.QueryRow(ctx, query, id, rawURL, normalizeEvents(eventTypes), active))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	s.log(ctx).Info("webhook updated", zap.Int64("webhook_id", sub.ID), zap.Bool("active", sub.Active))
+	return sub, nil
+}
+
+// Delete removes a subscription along with its delivery log
+func (s *Service) Delete(ctx context.Context, id int64) error {
+	result, err := s.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	s.log(ctx).Info("webhook deleted", zap.Int64("webhook_id", id))
+	return nil
+}
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, status, attempts,
+	COALESCE(last_status_code, 0), COALESCE(last_error, ''), next_attempt_at, delivered_at, created_at`
+
+// ListDeliveries returns the delivery log of a subscription, newest first
+func (s *Service) ListDeliveries(ctx context.Context, id int64, status string, limit, offset int) ([]Delivery, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY id DESC
+		LIMIT $3 OFFSET $4`, id, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns a delivery of a subscription with its attempt log
+func (s *Service) GetDelivery(ctx context.Context, id, deliveryID int64) (*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2`
+	delivery, err := scanDelivery(s.pool.QueryRow(ctx, query, deliveryID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT attempted_at, COALESCE(status_code, 0), COALESCE(error, ''),
+			COALESCE(response_body, ''), duration_ms
+		FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery attempts: %w", err)
+	}
+	defer rows.Close()
+
+	delivery.Log = []Attempt{}
+	for rows.Next() {
+		var attempt Attempt
+		var durationMS int
+		if err := rows.Scan(&attempt.AttemptedAt, &attempt.StatusCode, &attempt.Error, &attempt.ResponseBody, &durationMS); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery attempt: %w", err)
+		}
+		attempt.Duration = time.Duration(durationMS) * time.Millisecond
+		delivery.Log = append(delivery.Log, attempt)
+	}
+	return delivery, rows.Err()
+}
+
+// Redeliver queues a delivery to be sent again right away with a fresh
+// retry budget. Deliveries of a disabled subscription are never sent, so
+// they cannot be redelivered until it is reactivated. A pending delivery
+// whose next attempt is in the future returns ErrInFlight: the dispatcher
+// claims a delivery by pushing its next attempt forward, so it may be
+// being sent, and resetting it would send it twice.
+func (s *Service) Redeliver(ctx context.Context, id, deliveryID int64) (*Delivery, error) {
+	var active bool
+	err := s.pool.QueryRow(ctx, `SELECT active FROM webhook_subscriptions WHERE id = $1`, id).Scan(&active)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver: %w", err)
+	}
+	if !active {
+		return nil, ErrInactive
+	}
+
+	query := `UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL,
+			last_status_code = NULL, last_error = NULL, updated_at = now()
+		WHERE id = $1 AND subscription_id = $2 AND NOT (status = 'pending' AND next_attempt_at > now())
+		RETURNING ` + deliveryColumns
+	delivery, err := scanDelivery(s.pool.QueryRow(ctx, query, deliveryID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Either there is no such delivery or it is in flight
+		var exists bool
+		err = s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND subscription_id = $2)`,
+			deliveryID, id).Scan(&exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redeliver: %w", err)
+		}
+		if exists {
+			return nil, ErrInFlight
+		}
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeliver: %w", err)
+	}
+
+	s.log(ctx).Info("webhook redelivery requested", zap.Int64("webhook_id", id), zap.Int64("delivery_id", deliveryID))
+	return delivery, nil
+}
+
+// log returns the request-scoped logger
+func (s *Service) log(ctx context.Context) *zap.Logger {
+	return logger.FromContext(ctx, s.logger)
+}
+
+// validate checks the endpoint URL and event filter
+func validate(rawURL string, eventTypes []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(events.Types, eventType) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalid, eventType)
+		}
+	}
+	return nil
+}
+
+// normalizeEvents returns a non-nil, duplicate-free event filter
+func normalizeEvents(eventTypes []string) []string {
+	normalized := []string{}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(normalized, eventType) {
+			normalized = append(normalized, eventType)
+		}
+	}
+	return normalized
+}
+
+func scanSubscription(row pgx.Row) (*Subscription, error) {
+	var sub Subscription
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.Events, &sub.Active, &sub.DisabledReason,
+		&sub.ConsecutiveFailures, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func scanDelivery(row pgx.Row) (*Delivery, error) {
+	var delivery Delivery
+	err := row.Scan(&delivery.ID, &delivery.SubscriptionID, &delivery.EventID, &delivery.EventType,
+		&delivery.Status, &delivery.Attempts, &delivery.LastStatusCode, &delivery.LastError,
+		&delivery.NextAttemptAt, &delivery.DeliveredAt, &delivery.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	signature := Sign("secret", now, body)
+
+	if signature[:13] != "t=1700000000," {
+		t.Errorf("Sign() = %q; want timestamp prefix", signature)
+	}
+	if err := Verify("secret", signature, body, 5*time.Minute, now.Add(time.Minute)); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	tests := map[string]struct {
+		secret string
+		body   string
+		now    time.Time
+	}{
+		"wrong secret": {"other", `{"id":1}`, now},
+		"tampered":     {"secret", `{"id":2}`, now},
+		"replayed":     {"secret", `{"id":1}`, now.Add(time.Hour)},
+	}
+	for name, tt := range tests {
+		if err := Verify(tt.secret, signature, []byte(tt.body), 5*time.Minute, tt.now); err == nil {
+			t.Errorf("%s: Verify() error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package client is a Go client for the User Profile API.
+//
+//	c, err := client.New("https://users.example.com", client.WithAuth(client.BearerToken(token)))
+//	user, err := c.Get(ctx, 42)
+//	if errors.Is(err, client.ErrNotFound) { ... }
+//
+// Idempotent calls are retried with exponential backoff on network errors
+// and 502, 503 and 504 responses; every call is retried on 429, which the
+// server returns before doing any work. All attempts of a call share one
+// X-Request-ID, taken from the context (see WithRequestID) or generated.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID to and from the server
+const RequestIDHeader = "X-Request-ID"
+
+// Client calls the User Profile API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Authenticator
+	userAgent  string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of a client with a 30s
+// timeout
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAuth authenticates every request with auth
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetries retries failed calls up to maxRetries times, waiting between
+// minBackoff and maxBackoff with exponential growth and jitter. Zero
+// maxRetries disables retries.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// New creates a client for the API at baseURL
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		userAgent:  "user-profile-api-go-client",
+		maxRetries: 3,
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 2 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type requestIDKey struct{}
+
+// WithRequestID makes calls with ctx send id as their X-Request-ID, so they
+// can be correlated with the caller's own logs
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// do sends a request, retrying as described in the package documentation,
+// and decodes a successful JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), body, requestID)
+		if err == nil && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+			return nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			err = responseError(resp, requestID)
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+		}
+
+		if attempt >= c.maxRetries || !retryable(method, err) || ctx.Err() != nil {
+			return err
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// send makes one attempt
+func (c *Client) send(ctx context.Context, method, url string, body []byte, requestID string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, requestID)
+
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// retryable reports whether a failed attempt may be repeated
+func retryable(method string, err error) bool {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		// Transport failure; the server may have processed the request
+		return idempotent(method)
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// idempotent reports whether repeating a call has the same effect as
+// making it once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff returns the wait before retry attempt+1: exponential growth from
+// minBackoff, capped at maxBackoff, with full jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	wait := c.minBackoff << attempt
+	if wait <= 0 || wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(wait)) + 1)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds
+func parseRetryAfter(value string) time.Duration {
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// responseError builds an *Error from an unsuccessful response
+func responseError(resp *http.Response, requestID string) error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(RequestIDHeader),
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = requestID
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		apiErr.Message, apiErr.Code = body.Error, body.Code
+	} else {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
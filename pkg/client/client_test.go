@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"user-profile-api/config"
+	"user-profile-api/internal/handler"
+	"user-profile-api/internal/health"
+	"user-profile-api/internal/repository"
+	"user-profile-api/internal/routes"
+	"user-profile-api/internal/service"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// memoryRepository keeps users in memory
+type memoryRepository struct {
+	repository.Repository
+	mu     sync.Mutex
+	nextID int32
+	users  map[int32]repository.User
+}
+
+func (r *memoryRepository) CreateUser(ctx context.Context, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	user := repository.User{ID: r.nextID, Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users[user.ID] = user
+	return &user, nil
+}
+
+func (r *memoryRepository) GetUserByID(ctx context.Context, id int32) (*repository.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryRepository) UpdateUser(ctx context.Context, id int32, name string, dob time.Time, timezone string, updatedAt time.Time) (*repository.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[id]; !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	user := repository.User{ID: id, Name: name, DOB: dob, Timezone: timezone, UpdatedAt: updatedAt}
+	r.users[id] = user
+	return &user, nil
+}
+
+func (r *memoryRepository) DeleteUser(ctx context.Context, id int32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[id]; !ok {
+		return repository.ErrUserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memoryRepository) ListUsers(ctx context.Context, limit, offset int32) ([]repository.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	users := []repository.User{}
+	for _, user := range r.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	if int(offset) >= len(users) {
+		return []repository.User{}, nil
+	}
+	users = users[offset:]
+	if len(users) > int(limit) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryRepository) WithTx(ctx context.Context, fn func(repository.Repository) error, opts ...repository.TxOption) error {
+	return fn(r)
+}
+
+// newTestServer serves the real routes on a local listener
+func newTestServer(t *testing.T) *Client {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Limits.MaxPageSize = 2
+	log := zap.NewNop()
+	users := service.NewUserService(&memoryRepository{users: map[int32]repository.User{}}, log,
+		service.WithPageSizes(cfg.Limits.DefaultPageSize, cfg.Limits.MaxPageSize),
+	)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	store := config.NewStore("", cfg, log)
+	routes.Setup(app, store,
+		handler.NewUserHandler(users, log),
+		handler.NewHealthHandler(health.NewChecker(time.Second, 0)),
+		handler.NewAdminHandler(zap.NewAtomicLevel(), store, log),
+		nil, nil, nil, log,
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	c, err := New("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientAgainstServer(t *testing.T) {
+	c := newTestServer(t)
+	ctx := context.Background()
+
+	alice, err := c.Create(ctx, UserInput{Name: "Alice", DOB: "1990-05-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Bob", "Carol"} {
+		if _, err := c.Create(ctx, UserInput{Name: name, DOB: "1985-01-01"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patched, err := c.Patch(ctx, alice.ID, UserPatch{Name: String("Alicia")})
+	if err != nil || patched.Name != "Alicia" || patched.DOB != "1990-05-15" {
+		t.Fatalf("Patch() = %+v, %v; want Alicia with the DOB unchanged", patched, err)
+	}
+
+	if _, err := c.Update(ctx, alice.ID, UserInput{Name: "Alice", DOB: "2999-01-01"}); !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Update() with future DOB error = %v; want ErrValidationFailed", err)
+	}
+
+	got, err := c.Get(ctx, alice.ID)
+	if err != nil || got.Name != "Alicia" || got.Age == 0 {
+		t.Errorf("Get() = %+v, %v; want Alicia with an age", got, err)
+	}
+
+	// The server caps pages at two users, so iterating takes two pages
+	var names []string
+	it := c.Iterate(ctx, 10)
+	for it.Next() {
+		names = append(names, it.User().Name)
+	}
+	if err := it.Err(); err != nil || len(names) != 3 {
+		t.Errorf("Iterate() = %v, %v; want 3 users", names, err)
+	}
+
+	if err := c.Delete(ctx, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Get(WithRequestID(ctx, "trace-me"), alice.ID)
+	var apiErr *Error
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) {
+		t.Fatalf("Get() after delete error = %v; want ErrNotFound", err)
+	}
+	if apiErr.Code != CodeNotFound || apiErr.RequestID != "trace-me" {
+		t.Errorf("error = %+v; want code %s and request ID trace-me", apiErr, CodeNotFound)
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	var calls atomic.Int32
+	var requestIDs sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs.Store(r.Header.Get(RequestIDHeader), true)
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"id":1,"name":"Alice","dob":"1990-05-15","age":35}`))
+	}))
+	defer server.Close()
+
+	c, err := New(server.URL, WithAuth(BearerToken("token")), WithRetries(3, time.Millisecond, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := c.Get(context.Background(), 1)
+	if err != nil || user.Name != "Alice" {
+		t.Fatalf("Get() = %+v, %v; want Alice after retries", user, err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("attempts = %d; want 3", got)
+	}
+	ids := 0
+	requestIDs.Range(func(any, any) bool { ids++; return true })
+	if ids != 1 {
+		t.Errorf("distinct request IDs = %d; want 1 shared by every attempt", ids)
+	}
+
+	// Creates are not retried on 503, since the user may have been created
+	calls.Store(0)
+	if _, err := c.Create(context.Background(), UserInput{Name: "Bob", DOB: "1990-01-01"}); !errors.Is(err, ErrInternal) {
+		t.Errorf("Create() error = %v; want ErrInternal", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("create attempts = %d; want 1", got)
+	}
+}
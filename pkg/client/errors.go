@@ -0,0 +1,73 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error codes reported by the server in error responses
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeValidationFailed = "validation_failed"
+	CodeNotFound         = "not_found"
+	CodeUnauthorized     = "unauthorized"
+	CodeRateLimited      = "rate_limited"
+	CodeInternal         = "internal_error"
+)
+
+// Errors matched by errors.Is against an *Error, one per error code
+var (
+	ErrInvalidRequest   = errors.New("invalid request")
+	ErrValidationFailed = errors.New("validation failed")
+	ErrNotFound         = errors.New("not found")
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrRateLimited      = errors.New("rate limited")
+	ErrInternal         = errors.New("internal server error")
+)
+
+// Error is an error response from the API
+type Error struct {
+	StatusCode int
+	// Code is one of the Code constants; it is empty for responses that did
+	// not come from the API itself, such as those of a proxy
+	Code    string
+	Message string
+	// RequestID identifies the call in the server's logs
+	RequestID string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("user profile API: %d %s (request %s)", e.StatusCode, e.Message, e.RequestID)
+}
+
+// Unwrap returns the sentinel error for the code, falling back to the
+// status code when there is none
+func (e *Error) Unwrap() error {
+	switch e.Code {
+	case CodeInvalidRequest:
+		return ErrInvalidRequest
+	case CodeValidationFailed:
+		return ErrValidationFailed
+	case CodeNotFound:
+		return ErrNotFound
+	case CodeUnauthorized:
+		return ErrUnauthorized
+	case CodeRateLimited:
+		return ErrRateLimited
+	case CodeInternal:
+		return ErrInternal
+	}
+
+	switch {
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrInternal
+	}
+	return nil
+}
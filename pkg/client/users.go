@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// User is a user profile
+type User struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	// DOB is the date of birth as YYYY-MM-DD
+	DOB string `json:"dob"`
+	Age int    `json:"age"`
+	// Timezone is the user's IANA time zone, empty when unset
+	Timezone string `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+}
+
+// usersPath is the collection of users in the API version the client speaks
+const usersPath = "/v2/users"
+
+// UserInput holds every field of a user, for Create and Update
+type UserInput struct {
+	Name string `json:"name"`
+	DOB  string `json:"dob"`
+	// Timezone is an IANA name like Europe/Paris; empty leaves it unset
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// UserPatch holds the fields to change in Patch; nil fields are left alone
+type UserPatch struct {
+	Name *string `json:"name,omitempty"`
+	DOB  *string `json:"dob,omitempty"`
+	// Timezone set to "" clears the user's time zone
+	Timezone *string `json:"timezone,omitempty"`
+}
+
+// String returns a pointer to s, for UserPatch fields
+func String(s string) *string {
+	return &s
+}
+
+// ListOptions selects a page of users. Zero values use the server's
+// defaults; the server also caps Limit.
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// Create creates a user. It is not retried on network errors, since the
+// user may have been created.
+func (c *Client) Create(ctx context.Context, in UserInput) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPost, usersPath, nil, in, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Get returns the user with the given ID
+func (c *Client) Get(ctx context.Context, id int32) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update replaces a user's name and date of birth
+func (c *Client) Update(ctx context.Context, id int32, in UserInput) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPut, userPath(id), nil, in, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Patch changes the fields set in patch
+func (c *Client) Patch(ctx context.Context, id int32, patch UserPatch) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodPatch, userPath(id), nil, patch, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Delete deletes a user
+func (c *Client) Delete(ctx context.Context, id int32) error {
+	return c.do(ctx, http.MethodDelete, userPath(id), nil, nil, nil)
+}
+
+// List returns one page of users ordered by ID
+func (c *Client) List(ctx context.Context, opts ListOptions) ([]User, error) {
+	page, err := c.listPage(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return page.Data, nil
+}
+
+// userPage is a page of users as the API returns it
+type userPage struct {
+	Data []User `json:"data"`
+	// NextOffset is nil on the last page
+	NextOffset *int `json:"next_offset"`
+}
+
+// listPage fetches one page of users
+func (c *Client) listPage(ctx context.Context, opts ListOptions) (*userPage, error) {
+	query := url.Values{}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var page userPage
+	if err := c.do(ctx, http.MethodGet, usersPath, query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Iterate walks every user ordered by ID, fetching pageSize at a time (zero
+// uses the server's default):
+//
+//	it := c.Iterate(ctx, 100)
+//	for it.Next() {
+//		fmt.Println(it.User().Name)
+//	}
+//	if err := it.Err(); err != nil { ... }
+func (c *Client) Iterate(ctx context.Context, pageSize int) *UserIterator {
+	return &UserIterator{client: c, ctx: ctx, pageSize: pageSize}
+}
+
+// UserIterator pages through users; see Client.Iterate
+type UserIterator struct {
+	client   *Client
+	ctx      context.Context
+	pageSize int
+	offset   int
+	page     []User
+	current  User
+	err      error
+	// last is set once the final page has been fetched
+	last bool
+}
+
+// Next advances to the next user, fetching the next page when needed. It
+// returns false when there are no more users or a request failed.
+func (it *UserIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 {
+		if it.last {
+			return false
+		}
+		page, err := it.client.listPage(it.ctx, ListOptions{Limit: it.pageSize, Offset: it.offset})
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if page.NextOffset == nil {
+			it.last = true
+		} else {
+			it.offset = *page.NextOffset
+		}
+		if it.page = page.Data; len(it.page) == 0 {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// User returns the current user
+func (it *UserIterator) User() User {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *UserIterator) Err() error {
+	return it.err
+}
+
+// userPath returns the path of a user
+func userPath(id int32) string {
+	return usersPath + "/" + strconv.Itoa(int(id))
+}